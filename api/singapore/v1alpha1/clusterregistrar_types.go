@@ -3,6 +3,7 @@
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -14,6 +15,165 @@ type ClusterRegistrarSpec struct {
 	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
 	// Important: Run "make generate" to regenerate code after modifying this file
 
+	// RegistrationPolicy restricts what users can request on their RegisteredClusters.
+	// +optional
+	RegistrationPolicy RegistrationPolicy `json:"registrationPolicy,omitempty"`
+
+	// Manager configures the deployment of the cluster-registration-operator manager.
+	// +optional
+	Manager ComponentSpec `json:"manager,omitempty"`
+
+	// Webhook configures the deployment of the admission webhook.
+	// +optional
+	Webhook ComponentSpec `json:"webhook,omitempty"`
+
+	// LogLevel is the verbosity of the manager and webhook logs.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	LogLevel int32 `json:"logLevel,omitempty"`
+
+	// LeaderElection enables the leader election of the manager replicas, it is enabled by default.
+	// +kubebuilder:default=true
+	// +optional
+	LeaderElection *bool `json:"leaderElection,omitempty"`
+
+	// WorkspaceSelector selects the namespaces handled as workspaces,
+	// by default the namespaces provided by the codeready-toolchain.
+	// +optional
+	WorkspaceSelector *metav1.LabelSelector `json:"workspaceSelector,omitempty"`
+
+	// CertificateProvider provides the serving certificate of the webhook. With SelfSigned, the installer
+	// generates and rotates a self-signed CA and the serving certificate, with ServiceCA they are provided
+	// by the OpenShift service CA operator.
+	// +kubebuilder:validation:Enum=SelfSigned;ServiceCA
+	// +kubebuilder:default=SelfSigned
+	// +optional
+	CertificateProvider CertificateProvider `json:"certificateProvider,omitempty"`
+
+	// DeletionPolicy is applied to the RegisteredClusters when the ClusterRegistrar is deleted.
+	// Block waits for the RegisteredClusters to be deleted before uninstalling, Detach deregisters them
+	// from the hubs and deletes the workspace ManagedClusterSets, Retain uninstalls and keeps them.
+	// +kubebuilder:validation:Enum=Retain;Detach;Block
+	// +kubebuilder:default=Block
+	// +optional
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// RemoveCRDs removes the CRDs when the ClusterRegistrar is deleted, they are kept by default.
+	// +optional
+	RemoveCRDs bool `json:"removeCRDs,omitempty"`
+
+	// Concurrency tunes the number of RegisteredClusters the manager reconciles in parallel
+	// and the rate of its requests to each hub.
+	// +optional
+	Concurrency ConcurrencySpec `json:"concurrency,omitempty"`
+}
+
+// ConcurrencySpec tunes the reconciliation of the RegisteredClusters and its load on the hubs.
+type ConcurrencySpec struct {
+	// MaxConcurrentReconciles is the number of RegisteredClusters reconciled in parallel, 1 by default.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxConcurrentReconciles int32 `json:"maxConcurrentReconciles,omitempty"`
+
+	// HubQPS is the number of requests per second the manager sends to each hub, 20 by default.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	HubQPS int32 `json:"hubQPS,omitempty"`
+
+	// HubBurst is the number of requests the manager can send at once to each hub, 50 by default.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	HubBurst int32 `json:"hubBurst,omitempty"`
+}
+
+// DeletionPolicy is applied to the RegisteredClusters when the ClusterRegistrar is deleted.
+type DeletionPolicy string
+
+const (
+	// DeletionPolicyRetain uninstalls and keeps the RegisteredClusters and their ManagedClusters on the hubs.
+	DeletionPolicyRetain DeletionPolicy = "Retain"
+	// DeletionPolicyDetach deletes the RegisteredClusters, their ManagedClusters and the workspace
+	// ManagedClusterSets on the hubs before uninstalling.
+	DeletionPolicyDetach DeletionPolicy = "Detach"
+	// DeletionPolicyBlock waits for the RegisteredClusters to be deleted before uninstalling.
+	DeletionPolicyBlock DeletionPolicy = "Block"
+)
+
+// CertificateProvider provides the serving certificate of the webhook.
+type CertificateProvider string
+
+const (
+	// CertificateProviderSelfSigned is the provider of the certificates generated by the installer.
+	CertificateProviderSelfSigned CertificateProvider = "SelfSigned"
+	// CertificateProviderServiceCA is the provider of the certificates generated by the OpenShift service CA operator.
+	CertificateProviderServiceCA CertificateProvider = "ServiceCA"
+)
+
+// ForceUninstallAnnotation allows the uninstallation when set to "true" on the ClusterRegistrar,
+// even if RegisteredClusters still exist.
+const ForceUninstallAnnotation string = "clusterregistrar.singapore.open-cluster-management.io/force-uninstall"
+
+// ComponentSpec defines how a component of the operator is deployed.
+type ComponentSpec struct {
+	// Image overrides the image of the component, by default the image of the installer is used.
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// Replicas is the number of replicas of the component, 1 by default.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	Replicas *int32 `json:"replicas,omitempty"`
+
+	// Resources overrides the compute resources of the component container.
+	// +optional
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// NodeSelector constrains the nodes the component pods are scheduled on.
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
+	// Tolerations overrides the tolerations of the component pods,
+	// by default the infra and dedicated nodes taints are tolerated.
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+}
+
+// RegistrationPolicy defines the allow-lists the RegisteredClusters are validated against.
+// An empty list allows nothing.
+type RegistrationPolicy struct {
+	// AllowedLabelPrefixes is the list of prefixes a RegisteredCluster label key must start with,
+	// for example "env" or "example.com/".
+	// +optional
+	AllowedLabelPrefixes []string `json:"allowedLabelPrefixes,omitempty"`
+
+	// AllowedAddOns is the list of ManagedClusterAddOns a RegisteredCluster can enable.
+	// +optional
+	AllowedAddOns []string `json:"allowedAddOns,omitempty"`
+
+	// AllowedAccessProfiles is the list of ClusterRoles a RegisteredCluster can use as access profile.
+	// +optional
+	AllowedAccessProfiles []string `json:"allowedAccessProfiles,omitempty"`
+
+	// DefaultAccessProfile is the access profile set on the RegisteredClusters created without one.
+	// +optional
+	DefaultAccessProfile string `json:"defaultAccessProfile,omitempty"`
+
+	// AdoptableManagedClusters is the list of the existing ManagedClusters of the hubs a RegisteredCluster can adopt.
+	// +optional
+	AdoptableManagedClusters []string `json:"adoptableManagedClusters,omitempty"`
+
+	// ApprovalRequired makes the RegisteredClusters created from now on wait for the approval of an approver
+	// before their cluster can join the hub.
+	// +optional
+	ApprovalRequired bool `json:"approvalRequired,omitempty"`
+
+	// Approvers is the list of the users allowed to approve the RegisteredClusters.
+	// +optional
+	Approvers []string `json:"approvers,omitempty"`
+
+	// ApproverGroups is the list of the groups whose users are allowed to approve the RegisteredClusters.
+	// +optional
+	ApproverGroups []string `json:"approverGroups,omitempty"`
 }
 
 // ClusterRegistrarStatus defines the observed state of ClusterRegistrar
@@ -24,11 +184,45 @@ type ClusterRegistrarStatus struct {
 	// Conditions contains the different condition statuses for this ClusterRegistrar.
 	// +optional
 	Conditions []metav1.Condition `json:"conditions"`
+
+	// InstalledVersion is the version of the installer which completed the installation.
+	// +optional
+	InstalledVersion string `json:"installedVersion,omitempty"`
+
+	// ObservedGeneration is the generation of the spec the conditions were computed for.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
 }
 
+const (
+	// ClusterRegistrarConditionCRDsInstalled is true when the CRDs are established.
+	ClusterRegistrarConditionCRDsInstalled string = "CRDsInstalled"
+	// ClusterRegistrarConditionManagerAvailable is true when the manager deployment is rolled out and available.
+	ClusterRegistrarConditionManagerAvailable string = "ManagerAvailable"
+	// ClusterRegistrarConditionWebhookAvailable is true when the webhook deployment is rolled out and available.
+	ClusterRegistrarConditionWebhookAvailable string = "WebhookAvailable"
+	// ClusterRegistrarConditionAPIServiceAvailable is true when the admission APIServices are available.
+	ClusterRegistrarConditionAPIServiceAvailable string = "APIServiceAvailable"
+	// ClusterRegistrarConditionReady is true when all the other conditions are true.
+	ClusterRegistrarConditionReady string = "Ready"
+	// ClusterRegistrarConditionUpgrading is true while a new version is being installed over the installed one.
+	ClusterRegistrarConditionUpgrading string = "Upgrading"
+	// ClusterRegistrarConditionUninstallBlocked is true when the deletion waits for the RegisteredClusters to be deleted.
+	ClusterRegistrarConditionUninstallBlocked string = "UninstallBlocked"
+	// ClusterRegistrarConditionDetaching is true while the RegisteredClusters are deregistered from the hubs
+	// before uninstalling with the Detach deletion policy.
+	ClusterRegistrarConditionDetaching string = "Detaching"
+	// ClusterRegistrarConditionHubPrerequisitesMet is true when the prerequisites of all the hubs
+	// configured by the HubConfigs are met, the HubConfig conditions detail the unmet ones.
+	ClusterRegistrarConditionHubPrerequisitesMet string = "HubPrerequisitesMet"
+)
+
 // +genclient
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:JSONPath=`.status.conditions[?(@.type=="Ready")].status`,name="Ready",type=string
+// +kubebuilder:printcolumn:JSONPath=`.status.installedVersion`,name="Version",type=string
+// +kubebuilder:printcolumn:JSONPath=`.metadata.creationTimestamp`,name="Age",type=date
 
 // ClusterRegistrar is the Schema for the clusterregistrars API
 type ClusterRegistrar struct {
@@ -52,3 +52,14 @@ type HubConfigList struct {
 	// +listType=set
 	Items []HubConfig `json:"items"`
 }
+
+const (
+	// HubConfigConditionMultiClusterEngineAvailable is true when the multicluster engine 2.0.0+
+	// or the advanced cluster management 2.5.0+ is installed on the hub.
+	HubConfigConditionMultiClusterEngineAvailable string = "MultiClusterEngineAvailable"
+	// HubConfigConditionManagedServiceAccountEnabled is true when the managed-service-account component
+	// of the multicluster engine is enabled.
+	HubConfigConditionManagedServiceAccountEnabled string = "ManagedServiceAccountEnabled"
+	// HubConfigConditionRequiredCRDsInstalled is true when the CRDs used by the manager are installed on the hub.
+	HubConfigConditionRequiredCRDsInstalled string = "RequiredCRDsInstalled"
+)
@@ -10,6 +10,7 @@ import (
 
 // +kubebuilder:printcolumn:JSONPath=`.status.conditions[?(@.type=="ManagedClusterJoined")].status`,name="Joined",type=string
 // +kubebuilder:printcolumn:JSONPath=`.status.conditions[?(@.type=="ManagedClusterConditionAvailable")].status`,name="Available",type=string
+// +kubebuilder:printcolumn:JSONPath=`.status.hubConfigName`,name="Hub",type=string
 // +kubebuilder:printcolumn:JSONPath=`.metadata.creationTimestamp`,name="Age",type=date
 
 // RegisteredClusterSpec defines the desired state of RegisteredCluster
@@ -17,6 +18,44 @@ type RegisteredClusterSpec struct {
 	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
 	// Important: Run "make generate" to regenerate code after modifying this file
 
+	// HubConfigName is the name of the HubConfig of the hub the cluster is registered on.
+	// When empty, the hub of the workspace is used. Changing it migrates the cluster to the new hub.
+	// +optional
+	HubConfigName string `json:"hubConfigName,omitempty"`
+
+	// ManagedClusterSet is the name of the ManagedClusterSet the cluster is added to on the hub.
+	// It defaults to the ManagedClusterSet of the workspace and can not be changed once set.
+	// +optional
+	ManagedClusterSet string `json:"managedClusterSet,omitempty"`
+
+	// Labels are added to the ManagedCluster on the hub. Keys must be allowed by the
+	// registration policy of the ClusterRegistrar.
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// AddOns is the list of additional ManagedClusterAddOns to enable on the cluster.
+	// Each addon must be allowed by the registration policy of the ClusterRegistrar.
+	// +optional
+	AddOns []string `json:"addOns,omitempty"`
+
+	// AccessProfile is the name of the ClusterRole bound to the AppStudio service account
+	// on the registered cluster. When empty, the service account is given full access.
+	// The profile must be allowed by the registration policy of the ClusterRegistrar.
+	// +optional
+	AccessProfile string `json:"accessProfile,omitempty"`
+
+	// ManagedClusterName is the name of an existing ManagedCluster of the hub to adopt instead of
+	// importing a new cluster. It must be allowed by the registration policy of the ClusterRegistrar
+	// and can not be changed once set.
+	// +optional
+	ManagedClusterName string `json:"managedClusterName,omitempty"`
+
+	// AutoImportSecretRef references a Secret of the namespace holding the credentials the hub imports
+	// the cluster with, instead of running the import command on the cluster. The Secret has the
+	// singapore.open-cluster-management.io/auto-import type and contains a `kubeconfig`, or a `server`
+	// and a `token`. It is deleted once the cluster joined the hub.
+	// +optional
+	AutoImportSecretRef *corev1.LocalObjectReference `json:"autoImportSecretRef,omitempty"`
 }
 
 // RegisteredClusterStatus defines the observed state of RegisteredCluster
@@ -30,6 +69,18 @@ type RegisteredClusterStatus struct {
 	//ClusterSecretRef is a reference to the secret containing the registered cluster kubeconfig.
 	ClusterSecretRef corev1.LocalObjectReference `json:"clusterSecretRef,omitempty"`
 
+	// HubConfigName is the name of the HubConfig of the hub the cluster is registered on.
+	// +optional
+	HubConfigName string `json:"hubConfigName,omitempty"`
+
+	// Migration is the progress of the migration of the cluster to the hub of the spec.
+	// +optional
+	Migration *RegisteredClusterMigration `json:"migration,omitempty"`
+
+	// Approval records the approval of the RegisteredCluster, when the registration policy requires one.
+	// +optional
+	Approval *RegisteredClusterApproval `json:"approval,omitempty"`
+
 	// Conditions contains the different condition statuses for this RegisteredCluster.
 	// +optional
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
@@ -56,6 +107,111 @@ type RegisteredClusterStatus struct {
 	ClusterClaims []clusterv1.ManagedClusterClaim `json:"clusterClaims,omitempty"`
 }
 
+// RegisteredClusterApproval records who approved the RegisteredCluster and when.
+type RegisteredClusterApproval struct {
+	// ApprovedBy is the name of the user who approved the RegisteredCluster.
+	ApprovedBy string `json:"approvedBy"`
+
+	// ApprovalTime is the time the approval was observed by the operator.
+	ApprovalTime metav1.Time `json:"approvalTime"`
+}
+
+const (
+	// ApprovalRequiredAnnotation is set to "true" by the webhook on the RegisteredClusters created while the
+	// registration policy requires an approval, their cluster can't join the hub until they are approved.
+	ApprovalRequiredAnnotation string = "registeredcluster.singapore.open-cluster-management.io/approval-required"
+	// ApprovedAnnotation is set to "true" by an approver of the registration policy to approve the RegisteredCluster.
+	ApprovedAnnotation string = "registeredcluster.singapore.open-cluster-management.io/approved"
+	// ApprovedByAnnotation records the user who approved the RegisteredCluster, it is set by the webhook.
+	ApprovedByAnnotation string = "registeredcluster.singapore.open-cluster-management.io/approved-by"
+	// MigrationSourceHubAnnotation records the hub the RegisteredCluster is migrated from, it is set by the webhook
+	// when the hub changes and removed by the operator once the cluster is registered on the new hub.
+	// The hub can't be changed again while it is set.
+	MigrationSourceHubAnnotation string = "registeredcluster.singapore.open-cluster-management.io/migration-source-hub"
+)
+
+// AutoImportSecretType is the type of the Secrets referenced by the autoImportSecretRef of the RegisteredClusters,
+// the operator doesn't copy or delete the Secrets of the other types.
+const AutoImportSecretType corev1.SecretType = "singapore.open-cluster-management.io/auto-import"
+
+const (
+	// RegisteredClusterConditionApproved is false while the RegisteredCluster waits for its approval.
+	RegisteredClusterConditionApproved string = "Approved"
+	// ApprovedReasonPendingApproval is the reason of the Approved condition while the approval is pending.
+	ApprovedReasonPendingApproval string = "PendingApproval"
+	// ApprovedReasonApproved is the reason of the Approved condition once the RegisteredCluster is approved.
+	ApprovedReasonApproved string = "Approved"
+)
+
+// RegisteredClusterMigration is the progress of the migration of a RegisteredCluster from a hub to another.
+type RegisteredClusterMigration struct {
+	// SourceHubConfigName is the name of the HubConfig of the hub the cluster is migrated from.
+	SourceHubConfigName string `json:"sourceHubConfigName"`
+
+	// TargetHubConfigName is the name of the HubConfig of the hub the cluster is migrated to.
+	TargetHubConfigName string `json:"targetHubConfigName"`
+
+	// Step is the current step of the migration.
+	Step MigrationStep `json:"step"`
+
+	// StartTime is the time the migration started.
+	StartTime metav1.Time `json:"startTime"`
+}
+
+// MigrationStep is a step of the migration of a RegisteredCluster to another hub.
+// +kubebuilder:validation:Enum=CreateManagedCluster;IssueImportCommand;WaitForJoin;MoveServiceAccount;DetachFromSourceHub
+type MigrationStep string
+
+const (
+	// MigrationStepCreateManagedCluster creates the ManagedCluster on the target hub.
+	MigrationStepCreateManagedCluster MigrationStep = "CreateManagedCluster"
+	// MigrationStepIssueImportCommand issues the import command of the target hub.
+	MigrationStepIssueImportCommand MigrationStep = "IssueImportCommand"
+	// MigrationStepWaitForJoin waits for the klusterlet to join the target hub, after the new import command is applied on the cluster.
+	MigrationStepWaitForJoin MigrationStep = "WaitForJoin"
+	// MigrationStepMoveServiceAccount creates the managed service account on the target hub and updates the kubeconfig secret with its token.
+	MigrationStepMoveServiceAccount MigrationStep = "MoveServiceAccount"
+	// MigrationStepDetachFromSourceHub deletes the ManagedCluster from the source hub.
+	MigrationStepDetachFromSourceHub MigrationStep = "DetachFromSourceHub"
+)
+
+const (
+	// RegisteredClusterConditionMigrating is true while the cluster is migrated to another hub, its reason is the current step.
+	RegisteredClusterConditionMigrating string = "Migrating"
+	// MigratingReasonCompleted is the reason of the Migrating condition once the migration is completed.
+	MigratingReasonCompleted string = "MigrationCompleted"
+)
+
+const (
+	// RegisteredClusterConditionSynced is true when the objects of the RegisteredCluster are synced on its hub,
+	// its reason classifies the error of the last synchronization otherwise.
+	RegisteredClusterConditionSynced string = "Synced"
+)
+
+// The reasons of the Synced condition of the RegisteredClusters.
+const (
+	// SyncedReasonSynced is set when the objects of the RegisteredCluster are synced.
+	SyncedReasonSynced string = "Synced"
+	// SyncedReasonHubUnreachable is set when the hub API server can't be reached, the synchronization is retried.
+	SyncedReasonHubUnreachable string = "HubUnreachable"
+	// SyncedReasonHubPermissionDenied is set when the hub kubeconfig is not allowed to manage the objects.
+	SyncedReasonHubPermissionDenied string = "HubPermissionDenied"
+	// SyncedReasonMCEComponentMissing is set when a kind of the multicluster engine is not installed on the hub.
+	SyncedReasonMCEComponentMissing string = "MCEComponentMissing"
+	// SyncedReasonImportSecretPending is set while the import secret is not generated on the hub.
+	SyncedReasonImportSecretPending string = "ImportSecretPending"
+	// SyncedReasonManifestWorkFailed is set when the ManifestWork of the service account is not applied on the cluster.
+	SyncedReasonManifestWorkFailed string = "ManifestWorkFailed"
+	// SyncedReasonTemplateRenderingFailed is set when a manifest of the RegisteredCluster can't be rendered.
+	SyncedReasonTemplateRenderingFailed string = "TemplateRenderingFailed"
+	// SyncedReasonAdoptionFailed is set when the ManagedCluster to adopt doesn't exist or is registered by another RegisteredCluster.
+	SyncedReasonAdoptionFailed string = "AdoptionFailed"
+	// SyncedReasonAutoImportSecretInvalid is set when the auto-import Secret doesn't exist or doesn't hold credentials.
+	SyncedReasonAutoImportSecretInvalid string = "AutoImportSecretInvalid"
+	// SyncedReasonSyncFailed is set on the other errors.
+	SyncedReasonSyncFailed string = "SyncFailed"
+)
+
 // +genclient
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
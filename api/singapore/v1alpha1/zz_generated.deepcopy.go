@@ -8,6 +8,7 @@
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	clusterv1 "open-cluster-management.io/api/cluster/v1"
@@ -18,7 +19,7 @@ func (in *ClusterRegistrar) DeepCopyInto(out *ClusterRegistrar) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
+	in.Spec.DeepCopyInto(&out.Spec)
 	in.Status.DeepCopyInto(&out.Status)
 }
 
@@ -75,6 +76,20 @@ func (in *ClusterRegistrarList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ClusterRegistrarSpec) DeepCopyInto(out *ClusterRegistrarSpec) {
 	*out = *in
+	in.RegistrationPolicy.DeepCopyInto(&out.RegistrationPolicy)
+	in.Manager.DeepCopyInto(&out.Manager)
+	in.Webhook.DeepCopyInto(&out.Webhook)
+	if in.LeaderElection != nil {
+		in, out := &in.LeaderElection, &out.LeaderElection
+		*out = new(bool)
+		**out = **in
+	}
+	if in.WorkspaceSelector != nil {
+		in, out := &in.WorkspaceSelector, &out.WorkspaceSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	out.Concurrency = in.Concurrency
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterRegistrarSpec.
@@ -109,6 +124,60 @@ func (in *ClusterRegistrarStatus) DeepCopy() *ClusterRegistrarStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ComponentSpec) DeepCopyInto(out *ComponentSpec) {
+	*out = *in
+	if in.Replicas != nil {
+		in, out := &in.Replicas, &out.Replicas
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = new(corev1.ResourceRequirements)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Tolerations != nil {
+		in, out := &in.Tolerations, &out.Tolerations
+		*out = make([]corev1.Toleration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ComponentSpec.
+func (in *ComponentSpec) DeepCopy() *ComponentSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ComponentSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConcurrencySpec) DeepCopyInto(out *ConcurrencySpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConcurrencySpec.
+func (in *ConcurrencySpec) DeepCopy() *ConcurrencySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ConcurrencySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *HubConfig) DeepCopyInto(out *HubConfig) {
 	*out = *in
@@ -211,7 +280,7 @@ func (in *RegisteredCluster) DeepCopyInto(out *RegisteredCluster) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
+	in.Spec.DeepCopyInto(&out.Spec)
 	in.Status.DeepCopyInto(&out.Status)
 }
 
@@ -233,6 +302,22 @@ func (in *RegisteredCluster) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RegisteredClusterApproval) DeepCopyInto(out *RegisteredClusterApproval) {
+	*out = *in
+	in.ApprovalTime.DeepCopyInto(&out.ApprovalTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RegisteredClusterApproval.
+func (in *RegisteredClusterApproval) DeepCopy() *RegisteredClusterApproval {
+	if in == nil {
+		return nil
+	}
+	out := new(RegisteredClusterApproval)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RegisteredClusterList) DeepCopyInto(out *RegisteredClusterList) {
 	*out = *in
@@ -265,9 +350,42 @@ func (in *RegisteredClusterList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RegisteredClusterMigration) DeepCopyInto(out *RegisteredClusterMigration) {
+	*out = *in
+	in.StartTime.DeepCopyInto(&out.StartTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RegisteredClusterMigration.
+func (in *RegisteredClusterMigration) DeepCopy() *RegisteredClusterMigration {
+	if in == nil {
+		return nil
+	}
+	out := new(RegisteredClusterMigration)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RegisteredClusterSpec) DeepCopyInto(out *RegisteredClusterSpec) {
 	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.AddOns != nil {
+		in, out := &in.AddOns, &out.AddOns
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AutoImportSecretRef != nil {
+		in, out := &in.AutoImportSecretRef, &out.AutoImportSecretRef
+		*out = new(corev1.LocalObjectReference)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RegisteredClusterSpec.
@@ -285,6 +403,16 @@ func (in *RegisteredClusterStatus) DeepCopyInto(out *RegisteredClusterStatus) {
 	*out = *in
 	out.ImportCommandRef = in.ImportCommandRef
 	out.ClusterSecretRef = in.ClusterSecretRef
+	if in.Migration != nil {
+		in, out := &in.Migration, &out.Migration
+		*out = new(RegisteredClusterMigration)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Approval != nil {
+		in, out := &in.Approval, &out.Approval
+		*out = new(RegisteredClusterApproval)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
 		*out = make([]v1.Condition, len(*in))
@@ -323,3 +451,48 @@ func (in *RegisteredClusterStatus) DeepCopy() *RegisteredClusterStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RegistrationPolicy) DeepCopyInto(out *RegistrationPolicy) {
+	*out = *in
+	if in.AllowedLabelPrefixes != nil {
+		in, out := &in.AllowedLabelPrefixes, &out.AllowedLabelPrefixes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AllowedAddOns != nil {
+		in, out := &in.AllowedAddOns, &out.AllowedAddOns
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AllowedAccessProfiles != nil {
+		in, out := &in.AllowedAccessProfiles, &out.AllowedAccessProfiles
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AdoptableManagedClusters != nil {
+		in, out := &in.AdoptableManagedClusters, &out.AdoptableManagedClusters
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Approvers != nil {
+		in, out := &in.Approvers, &out.Approvers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ApproverGroups != nil {
+		in, out := &in.ApproverGroups, &out.ApproverGroups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RegistrationPolicy.
+func (in *RegistrationPolicy) DeepCopy() *RegistrationPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(RegistrationPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -12,6 +12,7 @@ import (
 
 	singaporev1alpha1 "github.com/stolostron/cluster-registration-operator/api/singapore/v1alpha1"
 	"github.com/stolostron/cluster-registration-operator/controllers/installer"
+	"github.com/stolostron/cluster-registration-operator/pkg/helpers"
 
 	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
 
@@ -19,7 +20,6 @@ import (
 	"k8s.io/client-go/kubernetes"
 
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
-	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
 	"github.com/spf13/cobra"
 	// +kubebuilder:scaffold:imports
@@ -34,6 +34,7 @@ type installerOptions struct {
 	metricsAddr          string
 	probeAddr            string
 	enableLeaderElection bool
+	logging              helpers.LoggingOptions
 }
 
 func init() {
@@ -58,12 +59,19 @@ func NewInstaller() *cobra.Command {
 	cmd.Flags().BoolVar(&o.enableLeaderElection, "enable-leader-election", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	o.logging.AddFlags(cmd.Flags())
+	cmd.AddCommand(newRenderCommand())
 	return cmd
 }
 
 func (o *installerOptions) run() {
 
-	ctrl.SetLogger(zap.New(zap.UseDevMode(true)))
+	logger, err := o.logging.NewLogger()
+	if err != nil {
+		setupLog.Error(err, "invalid logging options")
+		os.Exit(1)
+	}
+	ctrl.SetLogger(logger)
 
 	setupLog.Info("Setup Installer")
 
@@ -74,6 +82,7 @@ func (o *installerOptions) run() {
 		HealthProbeBindAddress: o.probeAddr,
 		LeaderElection:         o.enableLeaderElection,
 		LeaderElectionID:       "installer.open-cluster-management.io",
+		NewCache:               installer.NewCache(),
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
@@ -89,6 +98,7 @@ func (o *installerOptions) run() {
 		APIExtensionClient: apiextensionsclient.NewForConfigOrDie(ctrl.GetConfigOrDie()),
 		Log:                ctrl.Log.WithName("controllers").WithName("Installer"),
 		Scheme:             mgr.GetScheme(),
+		Recorder:           mgr.GetEventRecorderFor("cluster-registration-installer"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Installer")
 		os.Exit(1)
@@ -0,0 +1,107 @@
+// Copyright Red Hat
+
+package installer
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/ghodss/yaml"
+	"github.com/spf13/cobra"
+
+	singaporev1alpha1 "github.com/stolostron/cluster-registration-operator/api/singapore/v1alpha1"
+	"github.com/stolostron/cluster-registration-operator/controllers/installer"
+)
+
+type renderOptions struct {
+	clusterRegistrarFile      string
+	image                     string
+	namespace                 string
+	caBundleFile              string
+	kubeAPIServerCABundleFile string
+	outputDir                 string
+}
+
+func newRenderCommand() *cobra.Command {
+	o := &renderOptions{}
+	cmd := &cobra.Command{
+		Use:   "render",
+		Short: "render the manifests installed for a ClusterRegistrar",
+		Long: "Render the CRDs and the manifests the installer applies for a ClusterRegistrar without connecting to a cluster, " +
+			"for GitOps-based installations and to review the changes before an upgrade.\n" +
+			"When the certificates are self-signed, the installer generates them in the cluster and they are not rendered: " +
+			"provide the CA bundles to inject them, and create the cluster-registration-webhook-service TLS secret " +
+			"holding the webhook serving certificate in the installation namespace yourself.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.run(cmd.OutOrStdout())
+		},
+	}
+	cmd.Flags().StringVarP(&o.clusterRegistrarFile, "filename", "f", "", "The ClusterRegistrar YAML file, the default spec is used if not set.")
+	cmd.Flags().StringVar(&o.image, "image", "", "The image of the components unless set in the ClusterRegistrar spec.")
+	cmd.Flags().StringVarP(&o.namespace, "namespace", "n", "cluster-reg-config", "The installation namespace.")
+	cmd.Flags().StringVar(&o.caBundleFile, "ca-bundle-file", "", "The PEM CA bundle of the webhook serving certificate, injected in the APIServices.")
+	cmd.Flags().StringVar(&o.kubeAPIServerCABundleFile, "kube-apiserver-ca-bundle-file", "",
+		"The PEM CA bundle of the kube-apiserver, injected in the webhook configurations.")
+	cmd.Flags().StringVarP(&o.outputDir, "output-dir", "o", "", "The directory the manifests are written to, they are printed to stdout if not set.")
+	return cmd
+}
+
+func (o *renderOptions) run(out io.Writer) error {
+	clusterRegistrar := &singaporev1alpha1.ClusterRegistrar{}
+	if len(o.clusterRegistrarFile) != 0 {
+		b, err := os.ReadFile(o.clusterRegistrarFile)
+		if err != nil {
+			return err
+		}
+		if err := yaml.UnmarshalStrict(b, clusterRegistrar); err != nil {
+			return fmt.Errorf("failed to read the ClusterRegistrar %s: %w", o.clusterRegistrarFile, err)
+		}
+	}
+	if len(o.image) == 0 &&
+		(len(clusterRegistrar.Spec.Manager.Image) == 0 || len(clusterRegistrar.Spec.Webhook.Image) == 0) {
+		return fmt.Errorf("--image must be set unless the images are set in the ClusterRegistrar spec")
+	}
+
+	options := installer.RenderOptions{
+		Image:     o.image,
+		Namespace: o.namespace,
+	}
+	var err error
+	if options.CABundle, err = readOptionalFile(o.caBundleFile); err != nil {
+		return err
+	}
+	if options.KubeAPIServerCABundle, err = readOptionalFile(o.kubeAPIServerCABundleFile); err != nil {
+		return err
+	}
+
+	manifests, err := installer.RenderManifests(clusterRegistrar, options)
+	if err != nil {
+		return err
+	}
+
+	if len(o.outputDir) == 0 {
+		for _, manifest := range manifests {
+			fmt.Fprintf(out, "---\n# Source: %s\n%s", manifest.File, manifest.Data)
+		}
+		return nil
+	}
+	for _, manifest := range manifests {
+		path := filepath.Join(o.outputDir, manifest.File)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, manifest.Data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readOptionalFile(file string) ([]byte, error) {
+	if len(file) == 0 {
+		return nil, nil
+	}
+	return os.ReadFile(file)
+}
@@ -3,7 +3,10 @@
 package manager
 
 import (
+	"context"
+	"net/http"
 	"os"
+	"time"
 
 	singaporev1alpha1 "github.com/stolostron/cluster-registration-operator/api/singapore/v1alpha1"
 	"github.com/stolostron/cluster-registration-operator/pkg/helpers"
@@ -17,7 +20,6 @@ import (
 
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
-	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
 	clusterreg "github.com/stolostron/cluster-registration-operator/controllers/cluster-registration"
 	"github.com/stolostron/cluster-registration-operator/controllers/workspace"
@@ -32,9 +34,17 @@ var (
 )
 
 type managerOptions struct {
-	metricsAddr          string
-	probeAddr            string
-	enableLeaderElection bool
+	metricsAddr              string
+	probeAddr                string
+	enableLeaderElection     bool
+	workspaceSelector        string
+	hubCacheSyncTimeout      time.Duration
+	hubCacheHeartbeatTimeout time.Duration
+	maxConcurrentReconciles  int
+	hubQPS                   float32
+	hubBurst                 int
+	tracing                  helpers.TracingOptions
+	logging                  helpers.LoggingOptions
 }
 
 func init() {
@@ -56,56 +66,135 @@ func NewManager() *cobra.Command {
 	}
 	cmd.Flags().StringVar(&o.metricsAddr, "metrics-addr", ":8080", "The address the metric endpoint binds to.")
 	cmd.Flags().StringVar(&o.probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
-	cmd.Flags().BoolVar(&o.enableLeaderElection, "enable-leader-election", false,
+	cmd.Flags().BoolVar(&o.enableLeaderElection, "enable-leader-election", true,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	cmd.Flags().DurationVar(&o.hubCacheSyncTimeout, "hub-cache-sync-timeout", 5*time.Minute,
+		"The time the caches of the hubs have to sync before the manager is restarted by its liveness probe.")
+	cmd.Flags().DurationVar(&o.hubCacheHeartbeatTimeout, "hub-cache-heartbeat-timeout", 5*time.Minute,
+		"The time without event from the synced cache of a hub before the manager is restarted by its liveness probe.")
+	cmd.Flags().StringVar(&o.workspaceSelector, "workspace-selector", helpers.DefaultWorkspaceSelector,
+		"The label selector identifying the workspace namespaces.")
+	cmd.Flags().IntVar(&o.maxConcurrentReconciles, "max-concurrent-reconciles", helpers.DefaultMaxConcurrentReconciles,
+		"The number of RegisteredClusters and workspaces reconciled in parallel.")
+	cmd.Flags().Float32Var(&o.hubQPS, "hub-qps", helpers.DefaultHubClientQPS,
+		"The number of requests per second sent to each hub.")
+	cmd.Flags().IntVar(&o.hubBurst, "hub-burst", helpers.DefaultHubClientBurst,
+		"The number of requests sent at once to each hub.")
+	cmd.Flags().StringVar(&o.tracing.Endpoint, "tracing-endpoint", "",
+		"The host:port of the OTLP gRPC collector the traces are exported to, the tracing is disabled when empty.")
+	cmd.Flags().BoolVar(&o.tracing.Insecure, "tracing-insecure", false,
+		"Connect to the OTLP collector without TLS.")
+	cmd.Flags().Float64Var(&o.tracing.SamplingRatio, "tracing-sampling-ratio", 1,
+		"The fraction of the reconciliations traced, between 0 and 1.")
+	o.logging.AddFlags(cmd.Flags())
 	return cmd
 }
 
 func (o *managerOptions) run() {
 
-	ctrl.SetLogger(zap.New(zap.UseDevMode(true)))
+	// The klog verbosity set by the installer is applied to the manager logs unless --log-level is set
+	logger, err := o.logging.NewLogger()
+	if err != nil {
+		setupLog.Error(err, "invalid logging options")
+		os.Exit(1)
+	}
+	ctrl.SetLogger(logger)
 
 	setupLog.Info("Setup Manager")
 
-	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
+	if err := helpers.SetWorkspaceSelector(o.workspaceSelector); err != nil {
+		setupLog.Error(err, "invalid workspace selector")
+		os.Exit(1)
+	}
+
+	if err := helpers.SetHubClientRateLimits(o.hubQPS, o.hubBurst); err != nil {
+		setupLog.Error(err, "invalid hub rate limits")
+		os.Exit(1)
+	}
+
+	shutdownTracing, err := helpers.SetupTracing(context.Background(), "cluster-registration-operator", o.tracing)
+	if err != nil {
+		setupLog.Error(err, "unable to set up the tracing")
+		os.Exit(1)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			setupLog.Error(err, "unable to flush the traces")
+		}
+	}()
+
+	// Trace the requests to the workspace cluster made by the reconciliations
+	restConfig := ctrl.GetConfigOrDie()
+	restConfig.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+		return helpers.TraceTransport(rt)
+	})
+
+	mgr, err := ctrl.NewManager(restConfig, ctrl.Options{
 		Scheme:                 scheme,
 		MetricsBindAddress:     o.metricsAddr,
 		Port:                   9443,
 		HealthProbeBindAddress: o.probeAddr,
 		LeaderElection:         o.enableLeaderElection,
 		LeaderElectionID:       "628f2987.cluster-registratiion.io",
+		// Release the lease on shutdown, so another replica takes over without waiting for it to expire
+		LeaderElectionReleaseOnCancel: true,
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
 		os.Exit(1)
 	}
 
+	setupLog.Info("Add RegisteredCluster reconciler")
+
+	// Record the latency and the errors of the requests to the hubs in the manager metrics
+	helpers.WrapHubTransport = clusterreg.InstrumentHubTransport
+	hubInstances, err := helpers.GetHubClusters(mgr)
+	if err != nil {
+		setupLog.Error(err, "unable to retreive the hubCluster", "controller", "Cluster Registration")
+		os.Exit(1)
+	}
+
+	kubeClient := kubernetes.NewForConfigOrDie(restConfig)
+	dynamicClient := dynamic.NewForConfigOrDie(restConfig)
+
 	// add healthz/readyz check handler
 	setupLog.Info("Add health check")
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to add healthz check handler ")
 		os.Exit(1)
 	}
+	hubCachesStuckChecker, err := helpers.HubCachesStuckChecker(context.TODO(), hubInstances, o.hubCacheSyncTimeout, o.hubCacheHeartbeatTimeout)
+	if err != nil {
+		setupLog.Error(err, "unable to watch the hub caches")
+		os.Exit(1)
+	}
+	if err := mgr.AddHealthzCheck("hub-caches", hubCachesStuckChecker); err != nil {
+		setupLog.Error(err, "unable to add hub caches healthz check handler ")
+		os.Exit(1)
+	}
 
 	setupLog.Info("Add ready check")
 	if err := mgr.AddReadyzCheck("readyz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to add readyz check handler ")
 		os.Exit(1)
 	}
-
-	setupLog.Info("Add RegisteredCluster reconciler")
-
-	hubInstances, err := helpers.GetHubClusters(mgr)
-	if err != nil {
-		setupLog.Error(err, "unable to retreive the hubCluster", "controller", "Cluster Registration")
+	if err := mgr.AddReadyzCheck("hub-caches", helpers.HubCachesSyncedChecker(hubInstances)); err != nil {
+		setupLog.Error(err, "unable to add hub caches readyz check handler ")
+		os.Exit(1)
+	}
+	if err := mgr.AddReadyzCheck("webhook", helpers.APIServiceAvailableChecker(dynamicClient, helpers.WebhookAPIServiceName)); err != nil {
+		setupLog.Error(err, "unable to add webhook readyz check handler ")
 		os.Exit(1)
 	}
 
-	kubeClient := kubernetes.NewForConfigOrDie(ctrl.GetConfigOrDie())
-	dynamicClient := dynamic.NewForConfigOrDie(ctrl.GetConfigOrDie())
-	apiExtensionClient := apiextensionsclient.NewForConfigOrDie(ctrl.GetConfigOrDie())
-	hubApplier := clusteradmapply.NewApplierBuilder().WithClient(kubeClient, apiExtensionClient, dynamicClient).Build()
+	apiExtensionClient := apiextensionsclient.NewForConfigOrDie(restConfig)
+	hubApplier := clusteradmapply.NewApplierBuilder().
+		WithClient(kubeClient, apiExtensionClient, dynamicClient).
+		WithCache(helpers.NewResourceCache()).
+		Build()
 	if err = (&clusterreg.RegisteredClusterReconciler{
 		Client:             mgr.GetClient(),
 		KubeClient:         kubeClient,
@@ -115,21 +204,31 @@ func (o *managerOptions) run() {
 		Log:                ctrl.Log.WithName("controllers").WithName("RegistredCluster"),
 		Scheme:             mgr.GetScheme(),
 		HubClusters:        hubInstances,
+		Recorder:           mgr.GetEventRecorderFor("cluster-registration-operator"),
+
+		MaxConcurrentReconciles: o.maxConcurrentReconciles,
 	}).SetupWithManager(mgr, scheme); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Cluster Registration")
 		os.Exit(1)
 	}
+	if err := clusterreg.RegisterRegistrationCollector(mgr.GetClient(), hubInstances, mgr.Elected()); err != nil {
+		setupLog.Error(err, "unable to register the RegisteredCluster metrics")
+		os.Exit(1)
+	}
 
 	setupLog.Info("Add workspace reconciler")
 
 	if err = (&workspace.WorkspaceReconciler{
 		Client:             mgr.GetClient(),
-		KubeClient:         kubernetes.NewForConfigOrDie(ctrl.GetConfigOrDie()),
-		DynamicClient:      dynamic.NewForConfigOrDie(ctrl.GetConfigOrDie()),
-		APIExtensionClient: apiextensionsclient.NewForConfigOrDie(ctrl.GetConfigOrDie()),
+		KubeClient:         kubernetes.NewForConfigOrDie(restConfig),
+		DynamicClient:      dynamic.NewForConfigOrDie(restConfig),
+		APIExtensionClient: apiextensionsclient.NewForConfigOrDie(restConfig),
 		Log:                ctrl.Log.WithName("controllers").WithName("Workspace"),
 		Scheme:             mgr.GetScheme(),
 		HubClusters:        hubInstances,
+		Recorder:           mgr.GetEventRecorderFor("cluster-registration-workspace"),
+
+		MaxConcurrentReconciles: o.maxConcurrentReconciles,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "workspace")
 		os.Exit(1)
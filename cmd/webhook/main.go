@@ -7,17 +7,28 @@ import (
 
 	admissionserver "github.com/openshift/generic-admission-server/pkg/cmd/server"
 	"github.com/spf13/cobra"
+	"github.com/stolostron/cluster-registration-operator/pkg/helpers"
 	"github.com/stolostron/cluster-registration-operator/webhook"
 	genericapiserver "k8s.io/apiserver/pkg/server"
 )
 
 func NewAdmissionHook() *cobra.Command {
-	o := admissionserver.NewAdmissionServerOptions(os.Stdout, os.Stderr, &webhook.RegisteredClusterAdmissionHook{})
+	var workspaceSelector string
+	hook := &webhook.RegisteredClusterAdmissionHook{}
+	o := admissionserver.NewAdmissionServerOptions(os.Stdout, os.Stderr,
+		hook,
+		&webhook.RegisteredClusterAdmissionHookV1Beta1{Hook: hook},
+		&webhook.HubConfigAdmissionHook{},
+		&webhook.ClusterRegistrarAdmissionHook{})
 
 	cmd := &cobra.Command{
 		Use:   "webhook",
 		Short: "Start Cluster Admission Server",
 		RunE: func(c *cobra.Command, args []string) error {
+			if err := helpers.SetWorkspaceSelector(workspaceSelector); err != nil {
+				return err
+			}
+
 			stopCh := genericapiserver.SetupSignalHandler()
 
 			if err := o.Complete(); err != nil {
@@ -34,6 +45,8 @@ func NewAdmissionHook() *cobra.Command {
 	}
 
 	o.RecommendedOptions.AddFlags(cmd.Flags())
+	cmd.Flags().StringVar(&workspaceSelector, "workspace-selector", helpers.DefaultWorkspaceSelector,
+		"The label selector identifying the workspace namespaces.")
 
 	return cmd
 }
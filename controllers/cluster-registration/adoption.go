@@ -0,0 +1,60 @@
+// Copyright Red Hat
+
+package registeredcluster
+
+import (
+	"context"
+	"fmt"
+
+	giterrors "github.com/pkg/errors"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	clusterapiv1 "open-cluster-management.io/api/cluster/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	singaporev1alpha1 "github.com/stolostron/cluster-registration-operator/api/singapore/v1alpha1"
+	"github.com/stolostron/cluster-registration-operator/pkg/helpers"
+)
+
+// isAdopted returns true when the RegisteredCluster adopts an existing ManagedCluster of the hub.
+func isAdopted(regCluster *singaporev1alpha1.RegisteredCluster) bool {
+	return len(regCluster.Spec.ManagedClusterName) != 0
+}
+
+// adoptManagedCluster labels the existing ManagedCluster of the spec as the ManagedCluster of the RegisteredCluster
+// and adds it to its ManagedClusterSet, the cluster already joined the hub and is not imported again.
+func (r *RegisteredClusterReconciler) adoptManagedCluster(regCluster *singaporev1alpha1.RegisteredCluster, hubCluster *helpers.HubInstance, mcsName string, ctx context.Context) error {
+	managedCluster := &clusterapiv1.ManagedCluster{}
+	if err := hubCluster.Client.Get(ctx, types.NamespacedName{Name: regCluster.Spec.ManagedClusterName}, managedCluster); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return newSyncError(singaporev1alpha1.SyncedReasonAdoptionFailed, false, err)
+		}
+		return giterrors.WithStack(err)
+	}
+	// The webhook checks it on creation, but the ManagedCluster could have been registered since
+	if name, ok := managedCluster.Labels[RegisteredClusterNamelabel]; ok {
+		return newSyncError(singaporev1alpha1.SyncedReasonAdoptionFailed, false,
+			fmt.Errorf("ManagedCluster %s is already registered by the RegisteredCluster %s/%s",
+				managedCluster.Name, managedCluster.Labels[RegisteredClusterNamespacelabel], name))
+	}
+
+	if set, ok := managedCluster.Labels[ManagedClusterSetlabel]; ok && set != mcsName {
+		return newSyncError(singaporev1alpha1.SyncedReasonAdoptionFailed, false,
+			fmt.Errorf("ManagedCluster %s belongs to the ManagedClusterSet %s", managedCluster.Name, set))
+	}
+
+	patch := client.MergeFrom(managedCluster.DeepCopy())
+	syncOwnedLabels(regCluster, managedCluster)
+	managedCluster.Labels[RegisteredClusterNamelabel] = regCluster.Name
+	managedCluster.Labels[RegisteredClusterNamespacelabel] = regCluster.Namespace
+	managedCluster.Labels[RegisteredClusterAdoptedlabel] = "true"
+	managedCluster.Labels[ManagedClusterSetlabel] = mcsName
+	if err := hubCluster.Client.Patch(ctx, managedCluster, patch); err != nil {
+		return giterrors.WithStack(err)
+	}
+	r.Recorder.Eventf(regCluster, corev1.EventTypeNormal, EventReasonManagedClusterAdopted,
+		"Adopted ManagedCluster %s on the hub %s", managedCluster.Name, hubCluster.HubConfig.Name)
+	return nil
+}
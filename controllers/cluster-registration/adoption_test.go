@@ -0,0 +1,62 @@
+// Copyright Red Hat
+
+package registeredcluster
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-logr/logr"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	clusterapiv1 "open-cluster-management.io/api/cluster/v1"
+
+	singaporev1alpha1 "github.com/stolostron/cluster-registration-operator/api/singapore/v1alpha1"
+)
+
+func TestAdoptManagedCluster(t *testing.T) {
+	regCluster := newPhaseRegisteredCluster("c", "ws", false, false, "")
+	regCluster.Spec.ManagedClusterName = "local-cluster"
+	regCluster.Spec.Labels = map[string]string{"env": "prod"}
+	hub := newMigrationHub(t, "hub-1",
+		&clusterapiv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "local-cluster", Labels: map[string]string{"vendor": "OpenShift"}}},
+		&clusterapiv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "registered", Labels: map[string]string{RegisteredClusterNamelabel: "other"}}},
+		&clusterapiv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "other-set", Labels: map[string]string{ManagedClusterSetlabel: "other-set"}}},
+	)
+	recorder := record.NewFakeRecorder(10)
+	r := &RegisteredClusterReconciler{Log: logr.Discard(), Recorder: recorder}
+
+	if !isAdopted(regCluster) {
+		t.Fatalf("RegisteredCluster with a ManagedCluster name expected to be adopted")
+	}
+	if err := r.adoptManagedCluster(regCluster, hub, "ws-set", context.TODO()); err != nil {
+		t.Fatalf("Failed to adopt the ManagedCluster: %s", err)
+	}
+	managedCluster := &clusterapiv1.ManagedCluster{}
+	if err := hub.Client.Get(context.TODO(), types.NamespacedName{Name: "local-cluster"}, managedCluster); err != nil {
+		t.Fatalf("Failed to get the ManagedCluster: %s", err)
+	}
+	for k, v := range map[string]string{
+		"vendor": "OpenShift", "env": "prod", RegisteredClusterNamelabel: "c", RegisteredClusterNamespacelabel: "ws",
+		RegisteredClusterAdoptedlabel: "true", ManagedClusterSetlabel: "ws-set",
+	} {
+		if managedCluster.Labels[k] != v {
+			t.Fatalf("Label %s expected to be %q, got %q", k, v, managedCluster.Labels[k])
+		}
+	}
+	if len(recorder.Events) != 1 {
+		t.Fatalf("Expected 1 event, got %d", len(recorder.Events))
+	}
+
+	for _, name := range []string{"missing", "registered", "other-set"} {
+		regCluster.Spec.ManagedClusterName = name
+		err := r.adoptManagedCluster(regCluster, hub, "ws-set", context.TODO())
+		syncErr := &SyncError{}
+		if !errors.As(err, &syncErr) || syncErr.Reason != singaporev1alpha1.SyncedReasonAdoptionFailed || syncErr.Transient {
+			t.Fatalf("Expected a permanent AdoptionFailed error adopting %s, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright Red Hat
+
+package registeredcluster
+
+import (
+	"context"
+	"fmt"
+
+	giterrors "github.com/pkg/errors"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterapiv1 "open-cluster-management.io/api/cluster/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	singaporev1alpha1 "github.com/stolostron/cluster-registration-operator/api/singapore/v1alpha1"
+	"github.com/stolostron/cluster-registration-operator/pkg/helpers"
+)
+
+// isPendingApproval returns true when the RegisteredCluster must be approved and is not approved yet.
+func isPendingApproval(regCluster *singaporev1alpha1.RegisteredCluster) bool {
+	return regCluster.Annotations[singaporev1alpha1.ApprovalRequiredAnnotation] == "true" &&
+		regCluster.Annotations[singaporev1alpha1.ApprovedAnnotation] != "true"
+}
+
+// syncApproval lets the cluster join the hub once the RegisteredCluster is approved, and records the approver.
+// It returns false while the approval is pending, the hub doesn't accept the cluster until then.
+func (r *RegisteredClusterReconciler) syncApproval(regCluster *singaporev1alpha1.RegisteredCluster, managedCluster *clusterapiv1.ManagedCluster, hubCluster *helpers.HubInstance, ctx context.Context) (bool, error) {
+	// The adopted clusters already joined the hub, they are not disconnected until approved
+	if regCluster.Annotations[singaporev1alpha1.ApprovalRequiredAnnotation] != "true" || isAdopted(regCluster) {
+		return true, nil
+	}
+
+	pending := isPendingApproval(regCluster)
+	// The approval can be revoked, the hub then denies the cluster again
+	if managedCluster.Spec.HubAcceptsClient == pending {
+		patch := client.MergeFrom(managedCluster.DeepCopy())
+		managedCluster.Spec.HubAcceptsClient = !pending
+		if err := hubCluster.Client.Patch(ctx, managedCluster, patch); err != nil {
+			return false, giterrors.WithStack(err)
+		}
+	}
+
+	if pending {
+		if condition := meta.FindStatusCondition(regCluster.Status.Conditions, singaporev1alpha1.RegisteredClusterConditionApproved); condition == nil ||
+			condition.Reason != singaporev1alpha1.ApprovedReasonPendingApproval {
+			r.Recorder.Event(regCluster, corev1.EventTypeNormal, EventReasonApprovalPending,
+				"The RegisteredCluster waits for the approval of an approver of the registration policy")
+		}
+		patch := client.MergeFrom(regCluster.DeepCopy())
+		regCluster.Status.Approval = nil
+		regCluster.Status.Conditions = helpers.MergeStatusConditions(regCluster.Status.Conditions, metav1.Condition{
+			Type:    singaporev1alpha1.RegisteredClusterConditionApproved,
+			Status:  metav1.ConditionFalse,
+			Reason:  singaporev1alpha1.ApprovedReasonPendingApproval,
+			Message: fmt.Sprintf("Set the %s annotation to \"true\" to approve the RegisteredCluster", singaporev1alpha1.ApprovedAnnotation),
+		})
+		return false, giterrors.WithStack(r.Client.Status().Patch(ctx, regCluster, patch))
+	}
+
+	if regCluster.Status.Approval != nil {
+		return true, nil
+	}
+	approvedBy := regCluster.Annotations[singaporev1alpha1.ApprovedByAnnotation]
+	patch := client.MergeFrom(regCluster.DeepCopy())
+	regCluster.Status.Approval = &singaporev1alpha1.RegisteredClusterApproval{
+		ApprovedBy:   approvedBy,
+		ApprovalTime: metav1.Now(),
+	}
+	regCluster.Status.Conditions = helpers.MergeStatusConditions(regCluster.Status.Conditions, metav1.Condition{
+		Type:    singaporev1alpha1.RegisteredClusterConditionApproved,
+		Status:  metav1.ConditionTrue,
+		Reason:  singaporev1alpha1.ApprovedReasonApproved,
+		Message: fmt.Sprintf("The RegisteredCluster is approved by %s", approvedBy),
+	})
+	if err := r.Client.Status().Patch(ctx, regCluster, patch); err != nil {
+		return false, giterrors.WithStack(err)
+	}
+	r.Recorder.Eventf(regCluster, corev1.EventTypeNormal, EventReasonClusterApproved,
+		"The RegisteredCluster is approved by %s", approvedBy)
+	return true, nil
+}
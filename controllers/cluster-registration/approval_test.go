@@ -0,0 +1,101 @@
+// Copyright Red Hat
+
+package registeredcluster
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	clusterapiv1 "open-cluster-management.io/api/cluster/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	singaporev1alpha1 "github.com/stolostron/cluster-registration-operator/api/singapore/v1alpha1"
+)
+
+func TestSyncApproval(t *testing.T) {
+	regCluster := newPhaseRegisteredCluster("c", "ws", false, false, "")
+	regCluster.Annotations = map[string]string{singaporev1alpha1.ApprovalRequiredAnnotation: "true"}
+	managedCluster := &clusterapiv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "registered-cluster-abcde"}}
+	hub := newMigrationHub(t, "hub-1", managedCluster)
+	recorder := record.NewFakeRecorder(10)
+	r := &RegisteredClusterReconciler{
+		Client:   fake.NewClientBuilder().WithScheme(newMigrationScheme(t)).WithObjects(regCluster).Build(),
+		Log:      logr.Discard(),
+		Recorder: recorder,
+	}
+
+	approved, err := r.syncApproval(regCluster, managedCluster, hub, context.TODO())
+	if err != nil || approved {
+		t.Fatalf("RegisteredCluster expected to be pending approval: %v, %v", approved, err)
+	}
+	condition := meta.FindStatusCondition(regCluster.Status.Conditions, singaporev1alpha1.RegisteredClusterConditionApproved)
+	if condition == nil || condition.Status != metav1.ConditionFalse || condition.Reason != singaporev1alpha1.ApprovedReasonPendingApproval {
+		t.Fatalf("Approved condition not as expected: %v", condition)
+	}
+	if phase := RegisteredClusterPhase(regCluster); phase != RegisteredClusterPhasePendingApproval {
+		t.Fatalf("Expected phase %s, got %s", RegisteredClusterPhasePendingApproval, phase)
+	}
+
+	regCluster.Annotations[singaporev1alpha1.ApprovedAnnotation] = "true"
+	regCluster.Annotations[singaporev1alpha1.ApprovedByAnnotation] = "admin"
+	approved, err = r.syncApproval(regCluster, managedCluster, hub, context.TODO())
+	if err != nil || !approved {
+		t.Fatalf("RegisteredCluster expected to be approved: %v, %v", approved, err)
+	}
+	updated := &singaporev1alpha1.RegisteredCluster{}
+	if err := r.Client.Get(context.TODO(), client.ObjectKeyFromObject(regCluster), updated); err != nil {
+		t.Fatalf("Failed to get RegisteredCluster: %s", err)
+	}
+	if updated.Status.Approval == nil || updated.Status.Approval.ApprovedBy != "admin" {
+		t.Fatalf("Approval not as expected: %v", updated.Status.Approval)
+	}
+	hubManagedCluster := &clusterapiv1.ManagedCluster{}
+	if err := hub.Client.Get(context.TODO(), client.ObjectKeyFromObject(managedCluster), hubManagedCluster); err != nil {
+		t.Fatalf("Failed to get ManagedCluster: %s", err)
+	}
+	if !hubManagedCluster.Spec.HubAcceptsClient {
+		t.Fatalf("ManagedCluster expected to be accepted by the hub once approved")
+	}
+	if len(recorder.Events) != 2 {
+		t.Fatalf("Expected 2 events, got %d", len(recorder.Events))
+	}
+}
+
+func TestSyncApprovalNotRequired(t *testing.T) {
+	regCluster := newPhaseRegisteredCluster("c", "ws", false, false, "")
+	r := &RegisteredClusterReconciler{Log: logr.Discard(), Recorder: record.NewFakeRecorder(10)}
+	if approved, err := r.syncApproval(regCluster, &clusterapiv1.ManagedCluster{}, newMigrationHub(t, "hub-1"), context.TODO()); err != nil || !approved {
+		t.Fatalf("RegisteredCluster expected to be approved without an approval requirement: %v, %v", approved, err)
+	}
+	if isPendingApproval(regCluster) {
+		t.Fatalf("RegisteredCluster not expected to be pending approval")
+	}
+}
+
+func TestSyncApprovalAdopted(t *testing.T) {
+	regCluster := newPhaseRegisteredCluster("c", "ws", true, true, "")
+	regCluster.Annotations = map[string]string{singaporev1alpha1.ApprovalRequiredAnnotation: "true"}
+	regCluster.Spec.ManagedClusterName = "local-cluster"
+	managedCluster := &clusterapiv1.ManagedCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "local-cluster"},
+		Spec:       clusterapiv1.ManagedClusterSpec{HubAcceptsClient: true},
+	}
+	hub := newMigrationHub(t, "hub-1", managedCluster)
+	r := &RegisteredClusterReconciler{Log: logr.Discard(), Recorder: record.NewFakeRecorder(10)}
+	if approved, err := r.syncApproval(regCluster, managedCluster, hub, context.TODO()); err != nil || !approved {
+		t.Fatalf("Adopted RegisteredCluster expected to be approved: %v, %v", approved, err)
+	}
+	hubManagedCluster := &clusterapiv1.ManagedCluster{}
+	if err := hub.Client.Get(context.TODO(), client.ObjectKeyFromObject(managedCluster), hubManagedCluster); err != nil {
+		t.Fatalf("Failed to get ManagedCluster: %s", err)
+	}
+	if !hubManagedCluster.Spec.HubAcceptsClient {
+		t.Fatalf("Adopted ManagedCluster expected to be still accepted by the hub")
+	}
+}
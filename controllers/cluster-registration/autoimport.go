@@ -0,0 +1,141 @@
+// Copyright Red Hat
+
+package registeredcluster
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	giterrors "github.com/pkg/errors"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clusterapiv1 "open-cluster-management.io/api/cluster/v1"
+
+	singaporev1alpha1 "github.com/stolostron/cluster-registration-operator/api/singapore/v1alpha1"
+	"github.com/stolostron/cluster-registration-operator/pkg/helpers"
+)
+
+const (
+	// autoImportSecretName is the name of the Secret of the ManagedCluster namespace the hub imports the cluster with.
+	autoImportSecretName string = "auto-import-secret"
+	// autoImportRetry is the number of times the hub retries to import the cluster.
+	autoImportRetry string = "5"
+	// managedClusterConditionImportSucceeded is set by the hub once the cluster is imported, the hub then deletes the auto-import secret.
+	managedClusterConditionImportSucceeded string = "ManagedClusterImportSucceeded"
+)
+
+// syncAutoImportSecret creates the auto-import secret of the hub with the credentials of the Secret referenced
+// by the RegisteredCluster, so the hub imports the cluster without running the import command.
+// The credentials are deleted from the hub and the workspace once the cluster joined the hub.
+func (r *RegisteredClusterReconciler) syncAutoImportSecret(regCluster *singaporev1alpha1.RegisteredCluster, managedCluster *clusterapiv1.ManagedCluster, hubCluster *helpers.HubInstance, ctx context.Context) error {
+	if meta.IsStatusConditionTrue(managedCluster.Status.Conditions, clusterapiv1.ManagedClusterConditionJoined) {
+		return r.deleteAutoImportCredentials(regCluster, managedCluster, hubCluster, ctx)
+	}
+	// The hub deletes the auto-import secret once the cluster is imported, it is not created again while the klusterlet joins
+	if meta.IsStatusConditionTrue(managedCluster.Status.Conditions, managedClusterConditionImportSucceeded) {
+		return nil
+	}
+
+	secret, err := r.KubeClient.CoreV1().Secrets(regCluster.Namespace).Get(ctx, regCluster.Spec.AutoImportSecretRef.Name, metav1.GetOptions{})
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			// The credentials are deleted once the cluster joined, the import command is used to import it again
+			if isJoined(regCluster) {
+				return nil
+			}
+			return newSyncError(singaporev1alpha1.SyncedReasonAutoImportSecretInvalid, false, err)
+		}
+		return giterrors.WithStack(err)
+	}
+	// Only the Secrets dedicated to the auto-import are copied to the hub, the webhook checks the user can read and delete it
+	if secret.Type != singaporev1alpha1.AutoImportSecretType {
+		return newSyncError(singaporev1alpha1.SyncedReasonAutoImportSecretInvalid, false,
+			fmt.Errorf("the auto-import secret %s must have the %s type", secret.Name, singaporev1alpha1.AutoImportSecretType))
+	}
+	data, err := getAutoImportSecretData(secret)
+	if err != nil {
+		return newSyncError(singaporev1alpha1.SyncedReasonAutoImportSecretInvalid, false, err)
+	}
+
+	autoImportSecret := &corev1.Secret{}
+	err = hubCluster.Client.Get(ctx, types.NamespacedName{Namespace: managedCluster.Name, Name: autoImportSecretName}, autoImportSecret)
+	switch {
+	case k8serrors.IsNotFound(err):
+		autoImportSecret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      autoImportSecretName,
+				Namespace: managedCluster.Name,
+				Labels: map[string]string{
+					RegisteredClusterNamelabel:      regCluster.Name,
+					RegisteredClusterNamespacelabel: regCluster.Namespace,
+				},
+			},
+			Type: corev1.SecretTypeOpaque,
+			Data: data,
+		}
+		if err := hubCluster.Client.Create(ctx, autoImportSecret); err != nil {
+			return giterrors.WithStack(err)
+		}
+		r.Recorder.Eventf(regCluster, corev1.EventTypeNormal, EventReasonAutoImportSecretCreated,
+			"The hub %s imports the cluster with the credentials of the secret %s", hubCluster.HubConfig.Name, secret.Name)
+	case err != nil:
+		return giterrors.WithStack(err)
+	case !reflect.DeepEqual(autoImportSecret.Data, data):
+		autoImportSecret.Data = data
+		if err := hubCluster.Client.Update(ctx, autoImportSecret); err != nil {
+			return giterrors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+// getAutoImportSecretData returns the data of the auto-import secret of the hub, with the kubeconfig
+// of the Secret or its server and token.
+func getAutoImportSecretData(secret *corev1.Secret) (map[string][]byte, error) {
+	data := map[string][]byte{"autoImportRetry": []byte(autoImportRetry)}
+	switch {
+	case len(secret.Data["kubeconfig"]) != 0:
+		data["kubeconfig"] = secret.Data["kubeconfig"]
+	case len(secret.Data["server"]) != 0 && len(secret.Data["token"]) != 0:
+		data["server"] = secret.Data["server"]
+		data["token"] = secret.Data["token"]
+	default:
+		return nil, fmt.Errorf("the auto-import secret %s must contain a kubeconfig, or a server and a token", secret.Name)
+	}
+	return data, nil
+}
+
+// deleteAutoImportCredentials deletes the auto-import secret of the hub and the Secret referenced by the RegisteredCluster.
+func (r *RegisteredClusterReconciler) deleteAutoImportCredentials(regCluster *singaporev1alpha1.RegisteredCluster, managedCluster *clusterapiv1.ManagedCluster, hubCluster *helpers.HubInstance, ctx context.Context) error {
+	autoImportSecret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: managedCluster.Name, Name: autoImportSecretName}}
+	if err := hubCluster.Client.Delete(ctx, autoImportSecret); err != nil && !k8serrors.IsNotFound(err) {
+		return giterrors.WithStack(err)
+	}
+
+	secret, err := r.KubeClient.CoreV1().Secrets(regCluster.Namespace).Get(ctx, regCluster.Spec.AutoImportSecretRef.Name, metav1.GetOptions{})
+	switch {
+	case k8serrors.IsNotFound(err):
+		return nil
+	case err != nil:
+		return giterrors.WithStack(err)
+	case secret.Type != singaporev1alpha1.AutoImportSecretType:
+		// The Secrets of the other types are not deleted, even if they are referenced
+		return nil
+	}
+	// The Secret is only deleted if it was not replaced since it was read
+	err = r.KubeClient.CoreV1().Secrets(regCluster.Namespace).Delete(ctx, secret.Name, metav1.DeleteOptions{Preconditions: metav1.NewUIDPreconditions(string(secret.UID))})
+	switch {
+	case k8serrors.IsNotFound(err):
+		return nil
+	case err != nil:
+		return giterrors.WithStack(err)
+	}
+	r.Recorder.Eventf(regCluster, corev1.EventTypeNormal, EventReasonAutoImportCredentialsDeleted,
+		"The cluster joined the hub, the credentials of the secret %s are deleted", regCluster.Spec.AutoImportSecretRef.Name)
+	return nil
+}
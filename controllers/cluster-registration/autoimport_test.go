@@ -0,0 +1,102 @@
+// Copyright Red Hat
+
+package registeredcluster
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-logr/logr"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+	clusterapiv1 "open-cluster-management.io/api/cluster/v1"
+
+	singaporev1alpha1 "github.com/stolostron/cluster-registration-operator/api/singapore/v1alpha1"
+)
+
+func TestSyncAutoImportSecret(t *testing.T) {
+	regCluster := newPhaseRegisteredCluster("c", "ws", false, false, "")
+	regCluster.Spec.AutoImportSecretRef = &corev1.LocalObjectReference{Name: "c-credentials"}
+	managedCluster := &clusterapiv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "registered-cluster-abcde"}}
+	hub := newMigrationHub(t, "hub-1")
+	kubeClient := kubefake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "c-credentials", Namespace: "ws"},
+		Type:       singaporev1alpha1.AutoImportSecretType,
+		Data:       map[string][]byte{"server": []byte("https://api.cluster:6443"), "token": []byte("abcdef")},
+	})
+	recorder := record.NewFakeRecorder(10)
+	r := &RegisteredClusterReconciler{KubeClient: kubeClient, Log: logr.Discard(), Recorder: recorder}
+
+	if err := r.syncAutoImportSecret(regCluster, managedCluster, hub, context.TODO()); err != nil {
+		t.Fatalf("Failed to sync the auto-import secret: %s", err)
+	}
+	autoImportSecret := &corev1.Secret{}
+	if err := hub.Client.Get(context.TODO(), types.NamespacedName{Namespace: managedCluster.Name, Name: autoImportSecretName}, autoImportSecret); err != nil {
+		t.Fatalf("Failed to get the auto-import secret: %s", err)
+	}
+	if string(autoImportSecret.Data["server"]) != "https://api.cluster:6443" || string(autoImportSecret.Data["token"]) != "abcdef" ||
+		string(autoImportSecret.Data["autoImportRetry"]) != autoImportRetry {
+		t.Fatalf("Auto-import secret not as expected: %v", autoImportSecret.Data)
+	}
+
+	// The credentials are deleted once the cluster joined the hub
+	managedCluster.Status.Conditions = []metav1.Condition{{Type: clusterapiv1.ManagedClusterConditionJoined, Status: metav1.ConditionTrue, Reason: "Joined"}}
+	if err := r.syncAutoImportSecret(regCluster, managedCluster, hub, context.TODO()); err != nil {
+		t.Fatalf("Failed to delete the credentials: %s", err)
+	}
+	if err := hub.Client.Get(context.TODO(), types.NamespacedName{Namespace: managedCluster.Name, Name: autoImportSecretName}, autoImportSecret); !k8serrors.IsNotFound(err) {
+		t.Fatalf("Auto-import secret expected to be deleted: %v", err)
+	}
+	if _, err := kubeClient.CoreV1().Secrets("ws").Get(context.TODO(), "c-credentials", metav1.GetOptions{}); !k8serrors.IsNotFound(err) {
+		t.Fatalf("Credentials expected to be deleted: %v", err)
+	}
+	if err := r.syncAutoImportSecret(regCluster, managedCluster, hub, context.TODO()); err != nil {
+		t.Fatalf("Failed to sync the auto-import secret once the credentials are deleted: %s", err)
+	}
+	if len(recorder.Events) != 2 {
+		t.Fatalf("Expected 2 events, got %d", len(recorder.Events))
+	}
+}
+
+func TestSyncAutoImportSecretInvalid(t *testing.T) {
+	regCluster := newPhaseRegisteredCluster("c", "ws", false, false, "")
+	managedCluster := &clusterapiv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "registered-cluster-abcde"}}
+	kubeClient := kubefake.NewSimpleClientset(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "c-token", Namespace: "ws"},
+			Type:       singaporev1alpha1.AutoImportSecretType,
+			Data:       map[string][]byte{"token": []byte("abcdef")},
+		},
+		// The kubeconfig secret of another RegisteredCluster is not an auto-import secret
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "other-cluster-secret", Namespace: "ws"},
+			Data:       map[string][]byte{"kubeconfig": []byte("apiVersion: v1")},
+		},
+	)
+	r := &RegisteredClusterReconciler{KubeClient: kubeClient, Log: logr.Discard(), Recorder: record.NewFakeRecorder(10)}
+
+	for _, name := range []string{"c-token", "other-cluster-secret", "missing"} {
+		regCluster.Spec.AutoImportSecretRef = &corev1.LocalObjectReference{Name: name}
+		err := r.syncAutoImportSecret(regCluster, managedCluster, newMigrationHub(t, "hub-1"), context.TODO())
+		syncErr := &SyncError{}
+		if !errors.As(err, &syncErr) || syncErr.Reason != singaporev1alpha1.SyncedReasonAutoImportSecretInvalid || syncErr.Transient {
+			t.Fatalf("Expected a permanent AutoImportSecretInvalid error for the secret %s, got %v", name, err)
+		}
+	}
+
+	// The Secrets of the other types are not deleted once the cluster joined
+	regCluster.Spec.AutoImportSecretRef = &corev1.LocalObjectReference{Name: "other-cluster-secret"}
+	managedCluster.Status.Conditions = []metav1.Condition{{Type: clusterapiv1.ManagedClusterConditionJoined, Status: metav1.ConditionTrue, Reason: "Joined"}}
+	if err := r.syncAutoImportSecret(regCluster, managedCluster, newMigrationHub(t, "hub-1"), context.TODO()); err != nil {
+		t.Fatalf("Failed to sync the auto-import secret: %s", err)
+	}
+	if _, err := kubeClient.CoreV1().Secrets("ws").Get(context.TODO(), "other-cluster-secret", metav1.GetOptions{}); err != nil {
+		t.Fatalf("Secret of another type expected to be kept: %v", err)
+	}
+}
@@ -4,7 +4,6 @@ package registeredcluster
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -12,12 +11,14 @@ import (
 	"github.com/ghodss/yaml"
 	"github.com/go-logr/logr"
 	giterrors "github.com/pkg/errors"
+	"golang.org/x/time/rate"
 
 	b64 "encoding/base64"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	clusteradmapply "open-cluster-management.io/clusteradm/pkg/helpers/apply"
 
 	// corev1 "k8s.io/api/core/v1"
@@ -30,26 +31,31 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
 	clusterapiv1 "open-cluster-management.io/api/cluster/v1"
 	manifestworkv1 "open-cluster-management.io/api/work/v1"
 	authv1alpha1 "open-cluster-management.io/managed-serviceaccount/api/v1alpha1"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/ratelimiter"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 )
 
 // +kubebuilder:rbac:groups="",resources={secrets},verbs=get;list;watch;create;update;patch
 // +kubebuilder:rbac:groups="singapore.open-cluster-management.io",resources={hubconfigs},verbs=get;list;watch
-// +kubebuilder:rbac:groups="singapore.open-cluster-management.io",resources={registeredclusters},verbs=get;list;watch;create;update;delete
+// +kubebuilder:rbac:groups="singapore.open-cluster-management.io",resources={registeredclusters},verbs=get;list;watch;create;update;patch;delete
 
 // +kubebuilder:rbac:groups="singapore.open-cluster-management.io",resources={registeredclusters/status},verbs=update;patch
 
 // +kubebuilder:rbac:groups="coordination.k8s.io",resources={leases},verbs=get;list;create;update;patch;delete;watch
+// +kubebuilder:rbac:groups="apiregistration.k8s.io",resources={apiservices},verbs=get
 // +kubebuilder:rbac:groups="";events.k8s.io,resources=events,verbs=create;update;patch
 
 const (
@@ -57,6 +63,8 @@ const (
 	RegisteredClusterNamespacelabel string = "registeredcluster.singapore.open-cluster-management.io/namespace"
 	ManagedClusterSetlabel          string = "cluster.open-cluster-management.io/clusterset"
 	ManagedServiceAccountName       string = "appstudio"
+	// RegisteredClusterAdoptedlabel is set on the ManagedClusters adopted by a RegisteredCluster, they are not deleted when detached.
+	RegisteredClusterAdoptedlabel string = "registeredcluster.singapore.open-cluster-management.io/adopted"
 )
 
 // RegisteredClusterReconciler reconciles a RegisteredCluster object
@@ -69,17 +77,31 @@ type RegisteredClusterReconciler struct {
 	Log                logr.Logger
 	Scheme             *runtime.Scheme
 	HubClusters        []helpers.HubInstance
+	Recorder           record.EventRecorder
+	// MaxConcurrentReconciles is the number of RegisteredClusters reconciled in parallel.
+	MaxConcurrentReconciles int
+}
+
+// newReconcileRateLimiter backs off the retries of each RegisteredCluster from 1 second to 5 minutes,
+// such as while its import secret is pending, and limits the retries of all of them to 10 per second.
+func newReconcileRateLimiter() ratelimiter.RateLimiter {
+	return workqueue.NewMaxOfRateLimiter(
+		workqueue.NewItemExponentialFailureRateLimiter(time.Second, 5*time.Minute),
+		&workqueue.BucketRateLimiter{Limiter: rate.NewLimiter(rate.Limit(10), 100)},
+	)
 }
 
 func (r *RegisteredClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	_ = context.Background()
-	logger := r.Log.WithValues("namespace", req.Namespace, "name", req.Name)
+	logger := r.Log.WithValues(helpers.LogKeyWorkspace, req.Namespace, helpers.LogKeyRegisteredCluster, req.Name)
 	logger.Info("Reconciling...")
 
+	ctx, span := startReconcileSpan(ctx, req.Namespace, req.Name)
+	defer span.End()
+
 	instance := &singaporev1alpha1.RegisteredCluster{}
 
 	if err := r.Client.Get(
-		context.TODO(),
+		ctx,
 		types.NamespacedName{Namespace: req.Namespace, Name: req.Name},
 		instance,
 	); err != nil {
@@ -93,54 +115,131 @@ func (r *RegisteredClusterReconciler) Reconcile(ctx context.Context, req ctrl.Re
 		return reconcile.Result{}, giterrors.WithStack(err)
 	}
 
-	hubCluster, err := helpers.GetHubCluster(req.Namespace, r.HubClusters)
+	hubCluster, err := r.getHubCluster(ctx, instance)
 	if err != nil {
 		logger.Error(err, "failed to get HubCluster for RegisteredCluster workspace")
-		return ctrl.Result{}, err
+		// The hubs are loaded when the manager starts
+		return r.handleSyncError(ctx, instance, syncOperationHub, newSyncError(singaporev1alpha1.SyncedReasonSyncFailed, false, err))
+	}
+	span.SetAttributes(helpers.TracingAttributeHub.String(hubCluster.HubConfig.Name))
+	logger = logger.WithValues(helpers.LogKeyHub, hubCluster.HubConfig.Name)
+
+	// The cluster is migrated when its hub changes
+	if isMigrating(instance, &hubCluster) {
+		return r.syncMigration(ctx, instance, &hubCluster)
+	}
+	if err := r.clearMigrationSource(ctx, instance); err != nil {
+		logger.Error(err, "failed to remove the migration source annotation")
+		return r.handleSyncError(ctx, instance, syncOperationStatus, err)
 	}
 
 	// create managecluster on creation of registeredcluster CR
-	if err := r.createManagedCluster(instance, &hubCluster, ctx); err != nil {
+	if err := traceStep(ctx, spanCreateManagedCluster, instance, &hubCluster, func(ctx context.Context) error {
+		return r.createManagedCluster(instance, &hubCluster, ctx)
+	}); err != nil {
 		logger.Error(err, "failed to create ManagedCluster")
-		return ctrl.Result{}, err
+		return r.handleSyncError(ctx, instance, syncOperationManagedCluster, err)
 	}
 
-	managedCluster, err := r.getManagedCluster(instance, &hubCluster)
+	managedCluster, err := r.getManagedCluster(instance, &hubCluster, ctx)
 	if err != nil {
 		logger.Error(err, "failed to get ManagedCluster")
-		return ctrl.Result{}, err
+		return r.handleSyncError(ctx, instance, syncOperationManagedCluster, err)
 	}
+	logger = logger.WithValues(helpers.LogKeyManagedCluster, managedCluster.Name)
 
-	// update status of registeredcluster - add import command
-	if err := r.updateImportCommand(instance, &managedCluster, &hubCluster, ctx); err != nil {
-		if k8serrors.IsNotFound(err) {
-			return reconcile.Result{Requeue: true, RequeueAfter: 1 * time.Second}, nil
+	// The cluster can't join the hub until the RegisteredCluster is approved, its annotations are watched
+	approved := false
+	if err := traceStep(ctx, spanSyncApproval, instance, &hubCluster, func(ctx context.Context) (err error) {
+		approved, err = r.syncApproval(instance, &managedCluster, &hubCluster, ctx)
+		return err
+	}); err != nil {
+		logger.Error(err, "failed to sync the approval")
+		return r.handleSyncError(ctx, instance, syncOperationApproval, err)
+	}
+	if !approved {
+		logger.Info("Waiting for the approval")
+		return ctrl.Result{}, nil
+	}
+
+	// update status of registeredcluster - add import command, the adopted clusters which joined the hub are not imported again
+	if !isAdopted(instance) || !meta.IsStatusConditionTrue(managedCluster.Status.Conditions, clusterapiv1.ManagedClusterConditionJoined) {
+		if err := traceStep(ctx, spanUpdateImportCommand, instance, &hubCluster, func(ctx context.Context) error {
+			return r.updateImportCommand(instance, &managedCluster, &hubCluster, ctx)
+		}); err != nil {
+			if classifySyncError(err).Reason != singaporev1alpha1.SyncedReasonImportSecretPending {
+				logger.Error(err, "failed to update import command")
+			}
+			return r.handleSyncError(ctx, instance, syncOperationImport, err)
+		}
+	}
+
+	// import the cluster with the credentials of the user, they are deleted once the cluster joined the hub
+	if instance.Spec.AutoImportSecretRef != nil {
+		if err := traceStep(ctx, spanSyncAutoImportSecret, instance, &hubCluster, func(ctx context.Context) error {
+			return r.syncAutoImportSecret(instance, &managedCluster, &hubCluster, ctx)
+		}); err != nil {
+			logger.Error(err, "failed to sync the auto-import secret")
+			return r.handleSyncError(ctx, instance, syncOperationAutoImport, err)
 		}
-		logger.Error(err, "failed to update import command")
-		return ctrl.Result{}, err
 	}
 
 	// sync ManagedClusterAddOn, ManagedServiceAccount, ...
-	if err := r.syncManagedServiceAccount(instance, &managedCluster, &hubCluster, ctx); err != nil {
+	if err := traceStep(ctx, spanSyncManagedServiceAccount, instance, &hubCluster, func(ctx context.Context) error {
+		return r.syncManagedServiceAccount(instance, &managedCluster, &hubCluster, ctx)
+	}); err != nil {
 		logger.Error(err, "failed to sync managedclusteraddon")
-		return ctrl.Result{}, err
+		return r.handleSyncError(ctx, instance, syncOperationManagedServiceAccount, err)
+	}
+
+	// sync the additional ManagedClusterAddOns requested by the user
+	if err := traceStep(ctx, spanSyncManagedClusterAddOns, instance, &hubCluster, func(ctx context.Context) error {
+		return r.syncManagedClusterAddOns(instance, &managedCluster, &hubCluster, ctx)
+	}); err != nil {
+		logger.Error(err, "failed to sync additional managedclusteraddons")
+		return r.handleSyncError(ctx, instance, syncOperationAddOns, err)
 	}
 
 	// update status of registeredcluster
-	if err := r.updateRegisteredClusterStatus(instance, &managedCluster, ctx); err != nil {
+	if err := traceStep(ctx, spanUpdateStatus, instance, &hubCluster, func(ctx context.Context) error {
+		return r.updateRegisteredClusterStatus(instance, &managedCluster, &hubCluster, ctx)
+	}); err != nil {
 		logger.Error(err, "failed to update registered cluster status")
-		return ctrl.Result{}, err
+		return r.handleSyncError(ctx, instance, syncOperationStatus, err)
 	}
 
 	return ctrl.Result{}, nil
 }
 
-func (r *RegisteredClusterReconciler) updateRegisteredClusterStatus(regCluster *singaporev1alpha1.RegisteredCluster, managedCluster *clusterapiv1.ManagedCluster, ctx context.Context) error {
+// getHubCluster returns the hub selected in the RegisteredCluster spec or the hub of its workspace
+func (r *RegisteredClusterReconciler) getHubCluster(ctx context.Context, regCluster *singaporev1alpha1.RegisteredCluster) (helpers.HubInstance, error) {
+	if len(regCluster.Spec.HubConfigName) != 0 {
+		return helpers.GetHubClusterByName(regCluster.Spec.HubConfigName, r.HubClusters)
+	}
+	namespace := &corev1.Namespace{}
+	if err := r.Client.Get(ctx, types.NamespacedName{Name: regCluster.Namespace}, namespace); err != nil {
+		return helpers.HubInstance{}, giterrors.WithStack(err)
+	}
+	return helpers.GetWorkspaceHubCluster(namespace, r.HubClusters)
+}
+
+func (r *RegisteredClusterReconciler) updateRegisteredClusterStatus(regCluster *singaporev1alpha1.RegisteredCluster, managedCluster *clusterapiv1.ManagedCluster, hubCluster *helpers.HubInstance, ctx context.Context) error {
 
+	joined := isJoined(regCluster)
 	patch := client.MergeFrom(regCluster.DeepCopy())
+	// During a migration, the target hub is recorded once the cluster is detached from the source hub
+	if !isMigrating(regCluster, hubCluster) {
+		regCluster.Status.HubConfigName = hubCluster.HubConfig.Name
+	}
 	if managedCluster.Status.Conditions != nil {
 		regCluster.Status.Conditions = helpers.MergeStatusConditions(regCluster.Status.Conditions, managedCluster.Status.Conditions...)
 	}
+	regCluster.Status.Conditions = helpers.MergeStatusConditions(regCluster.Status.Conditions, metav1.Condition{
+		Type:    singaporev1alpha1.RegisteredClusterConditionSynced,
+		Status:  metav1.ConditionTrue,
+		Reason:  singaporev1alpha1.SyncedReasonSynced,
+		Message: fmt.Sprintf("The RegisteredCluster is synced with the ManagedCluster %s", managedCluster.Name),
+	})
 	if managedCluster.Status.Allocatable != nil {
 		allocatable := managedCluster.Status.Allocatable
 		regCluster.Status.Allocatable = allocatable
@@ -160,14 +259,25 @@ func (r *RegisteredClusterReconciler) updateRegisteredClusterStatus(regCluster *
 	if err := r.Client.Status().Patch(ctx, regCluster, patch); err != nil {
 		return err
 	}
+	if !joined && isJoined(regCluster) {
+		timeToJoinedSeconds.Observe(time.Since(regCluster.CreationTimestamp.Time).Seconds())
+		r.Recorder.Eventf(regCluster, corev1.EventTypeNormal, EventReasonClusterJoined,
+			"The cluster joined the hub as ManagedCluster %s", managedCluster.Name)
+	}
 
 	return nil
 }
 
-func (r *RegisteredClusterReconciler) getManagedCluster(regCluster *singaporev1alpha1.RegisteredCluster, hubCluster *helpers.HubInstance) (clusterapiv1.ManagedCluster, error) {
+// isJoined returns true when the cluster of the RegisteredCluster has joined its hub.
+func isJoined(regCluster *singaporev1alpha1.RegisteredCluster) bool {
+	status, ok := helpers.GetConditionStatus(regCluster.Status.Conditions, clusterapiv1.ManagedClusterConditionJoined)
+	return ok && status == metav1.ConditionTrue
+}
+
+func (r *RegisteredClusterReconciler) getManagedCluster(regCluster *singaporev1alpha1.RegisteredCluster, hubCluster *helpers.HubInstance, ctx context.Context) (clusterapiv1.ManagedCluster, error) {
 	managedClusterList := &clusterapiv1.ManagedClusterList{}
 	managedCluster := clusterapiv1.ManagedCluster{}
-	if err := hubCluster.Client.List(context.Background(), managedClusterList, client.MatchingLabels{RegisteredClusterNamelabel: regCluster.Name, RegisteredClusterNamespacelabel: regCluster.Namespace}); err != nil {
+	if err := hubCluster.Client.List(ctx, managedClusterList, client.MatchingLabels{RegisteredClusterNamelabel: regCluster.Name, RegisteredClusterNamespacelabel: regCluster.Namespace}); err != nil {
 		// Error reading the object - requeue the request.
 		return managedCluster, err
 	}
@@ -184,7 +294,8 @@ func (r *RegisteredClusterReconciler) updateImportCommand(regCluster *singaporev
 	importSecret := &corev1.Secret{}
 	if err := hubCluster.Cluster.GetAPIReader().Get(ctx, types.NamespacedName{Namespace: managedCluster.Name, Name: managedCluster.Name + "-import"}, importSecret); err != nil {
 		if k8serrors.IsNotFound(err) {
-			return err
+			// The import secret is generated by the hub after the ManagedCluster creation
+			return newSyncError(singaporev1alpha1.SyncedReasonImportSecretPending, true, err)
 		}
 		return giterrors.WithStack(err)
 	}
@@ -213,11 +324,12 @@ func (r *RegisteredClusterReconciler) updateImportCommand(regCluster *singaporev
 		ImportCommand: importCommand,
 	}
 
-	_, err = r.HubApplier.ApplyDirectly(readerDeploy, values, false, "", files...)
+	err = helpers.ApplyDirectly(ctx, r.KubeClient, &r.HubApplier, readerDeploy, values, files...)
 	if err != nil {
-		return giterrors.WithStack(err)
+		return giterrors.WithStack(classifyApplyError(err))
 	}
 
+	importCommandReady := len(regCluster.Status.ImportCommandRef.Name) != 0
 	patch := client.MergeFrom(regCluster.DeepCopy())
 	regCluster.Status.ImportCommandRef = corev1.LocalObjectReference{
 		Name: regCluster.Name + "-import",
@@ -225,12 +337,17 @@ func (r *RegisteredClusterReconciler) updateImportCommand(regCluster *singaporev
 	if err := r.Client.Status().Patch(ctx, regCluster, patch); err != nil {
 		return err
 	}
+	if !importCommandReady {
+		r.Recorder.Eventf(regCluster, corev1.EventTypeNormal, EventReasonImportCommandReady,
+			"The import command is available in the ConfigMap %s", regCluster.Status.ImportCommandRef.Name)
+	}
 
 	return nil
 }
 
 func (r *RegisteredClusterReconciler) syncManagedServiceAccount(regCluster *singaporev1alpha1.RegisteredCluster, managedCluster *clusterapiv1.ManagedCluster, hubCluster *helpers.HubInstance, ctx context.Context) error {
-	logger := r.Log.WithName("syncManagedServiceAccount").WithValues("namespace", regCluster.Namespace, "name", regCluster.Name, "managed cluster name", managedCluster.Name)
+	logger := r.Log.WithName("syncManagedServiceAccount").WithValues(helpers.LogKeyWorkspace, regCluster.Namespace, helpers.LogKeyRegisteredCluster, regCluster.Name,
+		helpers.LogKeyHub, hubCluster.HubConfig.Name, helpers.LogKeyManagedCluster, managedCluster.Name)
 
 	readerDeploy := resources.GetScenarioResourcesReader()
 
@@ -246,6 +363,7 @@ func (r *RegisteredClusterReconciler) syncManagedServiceAccount(regCluster *sing
 		RegisteredClusterNamespaceLabel string
 		RegisteredClusterName           string
 		RegisteredClusterNamespace      string
+		AccessProfile                   string
 	}{
 		ServiceAccountName:              ManagedServiceAccountName,
 		Namespace:                       managedCluster.Name,
@@ -253,21 +371,22 @@ func (r *RegisteredClusterReconciler) syncManagedServiceAccount(regCluster *sing
 		RegisteredClusterNamespaceLabel: RegisteredClusterNamespacelabel,
 		RegisteredClusterName:           regCluster.Name,
 		RegisteredClusterNamespace:      regCluster.Namespace,
+		AccessProfile:                   regCluster.Spec.AccessProfile,
 	}
 
 	logger.V(1).Info("applying managedclusteraddon and managedserviceaccount")
 
-	_, err := hubCluster.HubApplier.ApplyCustomResources(readerDeploy, values, false, "", files...)
+	err := helpers.ApplyResources(ctx, hubCluster.Client, &hubCluster.HubApplier, readerDeploy, values, files...)
 	if err != nil {
-		return giterrors.WithStack(err)
+		return giterrors.WithStack(classifyApplyError(err))
 	}
 
 	// If cluster has joined, sync the ManifestWork to create the roles and bindings for the service account
-	if status, ok := helpers.GetConditionStatus(regCluster.Status.Conditions, clusterapiv1.ManagedClusterConditionJoined); ok && status == metav1.ConditionTrue {
+	if isJoined(regCluster) {
 		msa := &authv1alpha1.ManagedServiceAccount{}
 
 		if err := hubCluster.Client.Get(
-			context.TODO(),
+			ctx,
 			types.NamespacedName{Namespace: managedCluster.Name, Name: ManagedServiceAccountName},
 			msa,
 		); err != nil {
@@ -283,9 +402,9 @@ func (r *RegisteredClusterReconciler) syncManagedServiceAccount(regCluster *sing
 		files = []string{
 			"cluster-registration/service_account_roles.yaml",
 		}
-		_, err := applier.ApplyCustomResources(readerDeploy, values, false, "", files...)
+		err := helpers.ApplyResources(ctx, hubCluster.Client, &applier, readerDeploy, values, files...)
 		if err != nil {
-			return giterrors.WithStack(err)
+			return giterrors.WithStack(classifyApplyError(err))
 		}
 
 		work := &manifestworkv1.ManifestWork{}
@@ -295,24 +414,66 @@ func (r *RegisteredClusterReconciler) syncManagedServiceAccount(regCluster *sing
 			return err
 		}
 
+		// The ManifestWork status is watched, a failed ManifestWork is retried when it changes
+		if applied := meta.FindStatusCondition(work.Status.Conditions, string(manifestworkv1.ManifestApplied)); applied != nil && applied.Status == metav1.ConditionFalse {
+			return newSyncError(singaporev1alpha1.SyncedReasonManifestWorkFailed, false,
+				fmt.Errorf("ManifestWork %s/%s not applied: %s", work.Namespace, work.Name, applied.Message))
+		}
 		if status, ok := helpers.GetConditionStatus(work.Status.Conditions, string(manifestworkv1.ManifestApplied)); ok && status == metav1.ConditionTrue {
 			logger.V(1).Info("manifestwork applied. preparing secret...")
-			err := r.syncManagedClusterKubeconfig(regCluster, managedCluster, hubCluster, ctx)
+			err := traceStep(ctx, spanSyncManagedClusterKubeconfig, regCluster, hubCluster, func(ctx context.Context) error {
+				return r.syncManagedClusterKubeconfig(regCluster, managedCluster, hubCluster, ctx)
+			})
 			if err != nil {
-				return giterrors.WithStack(err)
+				return &operationError{operation: syncOperationKubeconfig, err: giterrors.WithStack(err)}
 			}
 		}
 	}
 	return nil
 }
 
+func (r *RegisteredClusterReconciler) syncManagedClusterAddOns(regCluster *singaporev1alpha1.RegisteredCluster, managedCluster *clusterapiv1.ManagedCluster, hubCluster *helpers.HubInstance, ctx context.Context) error {
+	logger := r.Log.WithName("syncManagedClusterAddOns").WithValues(helpers.LogKeyWorkspace, regCluster.Namespace, helpers.LogKeyRegisteredCluster, regCluster.Name,
+		helpers.LogKeyHub, hubCluster.HubConfig.Name, helpers.LogKeyManagedCluster, managedCluster.Name)
+
+	if err := r.syncOwnedManagedClusterAddOns(regCluster, managedCluster, hubCluster, ctx); err != nil {
+		return err
+	}
+
+	readerDeploy := resources.GetScenarioResourcesReader()
+
+	files := []string{
+		"cluster-registration/additional_managed_cluster_addon.yaml",
+	}
+
+	for _, addOn := range regCluster.Spec.AddOns {
+		values := struct {
+			AddOnName string
+			Namespace string
+		}{
+			AddOnName: addOn,
+			Namespace: managedCluster.Name,
+		}
+
+		logger.V(1).Info("applying managedclusteraddon", "addon", addOn)
+
+		err := helpers.ApplyResources(ctx, hubCluster.Client, &hubCluster.HubApplier, readerDeploy, values, files...)
+		if err != nil {
+			return giterrors.WithStack(classifyApplyError(err))
+		}
+	}
+	return nil
+}
+
 func (r *RegisteredClusterReconciler) syncManagedClusterKubeconfig(regCluster *singaporev1alpha1.RegisteredCluster, managedCluster *clusterapiv1.ManagedCluster, hubCluster *helpers.HubInstance, ctx context.Context) error {
-	logger := r.Log.WithName("syncManagedClusterKubeconfig").WithValues("namespace", regCluster.Namespace, "name", regCluster.Name, "managed cluster name", managedCluster.Name)
-	// Retrieve the API URL
-	apiUrl := managedCluster.Spec.ManagedClusterClientConfigs[0].URL
-	if managedCluster.Spec.ManagedClusterClientConfigs == nil || len(managedCluster.Spec.ManagedClusterClientConfigs) == 0 {
-		return errors.New("ManagedClusterClientConfigs not configured as expected")
+	logger := r.Log.WithName("syncManagedClusterKubeconfig").WithValues(helpers.LogKeyWorkspace, regCluster.Namespace, helpers.LogKeyRegisteredCluster, regCluster.Name,
+		helpers.LogKeyHub, hubCluster.HubConfig.Name, helpers.LogKeyManagedCluster, managedCluster.Name)
+	// Retrieve the API URL, the ManagedCluster is watched so the kubeconfig is synced once the klusterlet sets it
+	if len(managedCluster.Spec.ManagedClusterClientConfigs) == 0 {
+		return newSyncError(singaporev1alpha1.SyncedReasonSyncFailed, false,
+			fmt.Errorf("ManagedCluster %s has no ManagedClusterClientConfigs", managedCluster.Name))
 	}
+	apiUrl := managedCluster.Spec.ManagedClusterClientConfigs[0].URL
 
 	// Retrieve the secret containing the managedserviceaccount token
 	token := &corev1.Secret{}
@@ -334,6 +495,11 @@ func (r *RegisteredClusterReconciler) syncManagedClusterKubeconfig(regCluster *s
 	}
 
 	secretName := fmt.Sprintf("%s-cluster-secret", regCluster.Name)
+	// The token is rotated when the kubeconfig secret doesn't hold it yet
+	rotated := false
+	if existing, err := r.KubeClient.CoreV1().Secrets(regCluster.Namespace).Get(ctx, secretName, metav1.GetOptions{}); err == nil {
+		rotated = !strings.Contains(string(existing.Data["kubeconfig"]), "token: "+string(token.Data["token"]))
+	}
 	values := struct {
 		ApiURL      string
 		Token       string
@@ -350,13 +516,14 @@ func (r *RegisteredClusterReconciler) syncManagedClusterKubeconfig(regCluster *s
 		Namespace:   regCluster.Namespace,
 	}
 
-	_, err = applier.ApplyCustomResources(readerDeploy, values, false, "", files...)
+	err = helpers.ApplyDirectly(ctx, r.KubeClient, &applier, readerDeploy, values, files...)
 	if err != nil {
-		return giterrors.WithStack(err)
+		return giterrors.WithStack(classifyApplyError(err))
 	}
 	logger.V(1).Info("cluster kubeconfig synced")
 
 	// Patch the RegisteredCluster status with reference to the kubeconfig secret
+	ready := len(regCluster.Status.ClusterSecretRef.Name) != 0
 	patch := client.MergeFrom(regCluster.DeepCopy())
 	regCluster.Status.ClusterSecretRef = corev1.LocalObjectReference{
 		Name: secretName,
@@ -364,6 +531,15 @@ func (r *RegisteredClusterReconciler) syncManagedClusterKubeconfig(regCluster *s
 	if err := r.Client.Status().Patch(ctx, regCluster, patch); err != nil {
 		return err
 	}
+	switch {
+	case !ready:
+		timeToKubeconfigReadySeconds.Observe(time.Since(regCluster.CreationTimestamp.Time).Seconds())
+		r.Recorder.Eventf(regCluster, corev1.EventTypeNormal, EventReasonKubeconfigIssued,
+			"The kubeconfig of the cluster is available in the secret %s", secretName)
+	case rotated:
+		r.Recorder.Eventf(regCluster, corev1.EventTypeNormal, EventReasonKubeconfigRotated,
+			"The token of the kubeconfig in the secret %s is rotated", secretName)
+	}
 
 	return nil
 }
@@ -372,12 +548,28 @@ func (r *RegisteredClusterReconciler) createManagedCluster(regCluster *singapore
 
 	// check if managedcluster is already exists
 	managedClusterList := &clusterapiv1.ManagedClusterList{}
-	if err := hubCluster.Client.List(context.Background(), managedClusterList, client.MatchingLabels{RegisteredClusterNamelabel: regCluster.Name, RegisteredClusterNamespacelabel: regCluster.Namespace}); err != nil {
+	if err := hubCluster.Client.List(ctx, managedClusterList, client.MatchingLabels{RegisteredClusterNamelabel: regCluster.Name, RegisteredClusterNamespacelabel: regCluster.Namespace}); err != nil {
 		// Error reading the object - requeue the request.
 		return err
 	}
 
-	mcsName := helpers.ManagedClusterSetNameForWorkspace(regCluster.Namespace)
+	mcsName := regCluster.Spec.ManagedClusterSet
+	if len(mcsName) == 0 {
+		mcsName = helpers.ManagedClusterSetNameForWorkspace(regCluster.Namespace)
+	}
+
+	// The workspace controller only creates the ManagedClusterSet on the hub of the workspace,
+	// the cluster may be registered on or migrated to another hub
+	if len(managedClusterList.Items) < 1 {
+		if err := r.ensureManagedClusterSet(mcsName, hubCluster, ctx); err != nil {
+			return err
+		}
+	}
+
+	// The existing ManagedCluster is adopted instead of created, except on the target hub of a migration
+	if len(managedClusterList.Items) < 1 && len(regCluster.Spec.ManagedClusterName) != 0 && !isMigrating(regCluster, hubCluster) {
+		return r.adoptManagedCluster(regCluster, hubCluster, mcsName, ctx)
+	}
 
 	if len(managedClusterList.Items) < 1 {
 		managedCluster := &clusterapiv1.ManagedCluster{
@@ -387,24 +579,50 @@ func (r *RegisteredClusterReconciler) createManagedCluster(regCluster *singapore
 			},
 			ObjectMeta: metav1.ObjectMeta{
 				GenerateName: "registered-cluster-",
-				Labels: map[string]string{
-					RegisteredClusterNamelabel:      regCluster.Name,
-					RegisteredClusterNamespacelabel: regCluster.Namespace,
-					ManagedClusterSetlabel:          mcsName,
-				},
 			},
 			Spec: clusterapiv1.ManagedClusterSpec{
-				HubAcceptsClient: true,
+				HubAcceptsClient: !isPendingApproval(regCluster),
 			},
 		}
+		syncOwnedLabels(regCluster, managedCluster)
+		managedCluster.Labels[RegisteredClusterNamelabel] = regCluster.Name
+		managedCluster.Labels[RegisteredClusterNamespacelabel] = regCluster.Namespace
+		managedCluster.Labels[ManagedClusterSetlabel] = mcsName
+
+		if err := hubCluster.Client.Create(ctx, managedCluster, &client.CreateOptions{}); err != nil {
+			return err
+		}
+		r.Recorder.Eventf(regCluster, corev1.EventTypeNormal, EventReasonManagedClusterCreated,
+			"Created ManagedCluster %s on the hub %s", managedCluster.Name, hubCluster.HubConfig.Name)
+		return nil
+	}
 
-		if err := hubCluster.Cluster.GetClient().Create(context.TODO(), managedCluster, &client.CreateOptions{}); err != nil {
+	// Propagate the labels added to or removed from the RegisteredCluster after the ManagedCluster creation
+	managedCluster := &managedClusterList.Items[0]
+	patch := client.MergeFrom(managedCluster.DeepCopy())
+	if syncOwnedLabels(regCluster, managedCluster) {
+		if err := hubCluster.Client.Patch(ctx, managedCluster, patch); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+// ensureManagedClusterSet creates the ManagedClusterSet the ManagedCluster is added to when it is missing on the hub.
+func (r *RegisteredClusterReconciler) ensureManagedClusterSet(mcsName string, hubCluster *helpers.HubInstance, ctx context.Context) error {
+	values := struct {
+		Name string
+	}{
+		Name: mcsName,
+	}
+	err := helpers.ApplyResources(ctx, hubCluster.Client, &hubCluster.HubApplier, resources.GetScenarioResourcesReader(), values,
+		"workspace/managed_cluster_set.yaml")
+	if err != nil {
+		return giterrors.WithStack(classifyApplyError(err))
+	}
+	return nil
+}
+
 func registeredClusterPredicate() predicate.Predicate {
 	return predicate.Predicate(predicate.Funcs{
 		GenericFunc: func(e event.GenericEvent) bool { return false },
@@ -425,7 +643,7 @@ func registeredClusterPredicate() predicate.Predicate {
 
 func managedClusterPredicate() predicate.Predicate {
 	f := func(obj client.Object) bool {
-		log := ctrl.Log.WithName("controllers").WithName("RegisteredCluster").WithName("managedClusterPredicate").WithValues("namespace", obj.GetNamespace(), "name", obj.GetName())
+		log := ctrl.Log.WithName("controllers").WithName("RegisteredCluster").WithName("managedClusterPredicate").WithValues(helpers.LogKeyManagedCluster, obj.GetName())
 		if _, ok := obj.GetLabels()[RegisteredClusterNamelabel]; ok {
 			if _, ok := obj.GetLabels()[RegisteredClusterNamespacelabel]; ok {
 				log.V(1).Info("process managedcluster")
@@ -462,7 +680,7 @@ func managedClusterPredicate() predicate.Predicate {
 
 func manifestWorkPredicate() predicate.Predicate {
 	f := func(obj client.Object) bool {
-		log := ctrl.Log.WithName("controllers").WithName("RegisteredCluster").WithName("manifestWorkPredicate").WithValues("namespace", obj.GetNamespace(), "name", obj.GetName())
+		log := ctrl.Log.WithName("controllers").WithName("RegisteredCluster").WithName("manifestWorkPredicate").WithValues(helpers.LogKeyManagedCluster, obj.GetNamespace(), "manifestWork", obj.GetName())
 		if _, ok := obj.GetLabels()[RegisteredClusterNamelabel]; ok {
 			if _, ok := obj.GetLabels()[RegisteredClusterNamespacelabel]; ok {
 				log.V(1).Info("process manifestwork")
@@ -503,10 +721,10 @@ func (r *RegisteredClusterReconciler) SetupWithManager(mgr ctrl.Manager, scheme
 
 	for _, hubCluster := range r.HubClusters {
 
-		r.Log.Info("add watchers for ", "hubConfig.Name", hubCluster.HubConfig.Name)
+		r.Log.Info("add watchers for hub", helpers.LogKeyHub, hubCluster.HubConfig.Name)
 		controllerBuilder.Watches(source.NewKindWithCache(&clusterapiv1.ManagedCluster{}, hubCluster.Cluster.GetCache()), handler.EnqueueRequestsFromMapFunc(func(o client.Object) []reconcile.Request {
 			managedCluster := o.(*clusterapiv1.ManagedCluster)
-			r.Log.Info("Processing ManagedCluster event", "name", managedCluster.Name)
+			r.Log.V(1).Info("Processing ManagedCluster event", helpers.LogKeyManagedCluster, managedCluster.Name)
 
 			req := make([]reconcile.Request, 0)
 			req = append(req, reconcile.Request{
@@ -519,7 +737,7 @@ func (r *RegisteredClusterReconciler) SetupWithManager(mgr ctrl.Manager, scheme
 		}), builder.WithPredicates(managedClusterPredicate()))
 		controllerBuilder.Watches(source.NewKindWithCache(&manifestworkv1.ManifestWork{}, hubCluster.Cluster.GetCache()), handler.EnqueueRequestsFromMapFunc(func(o client.Object) []reconcile.Request {
 			manifestWork := o.(*manifestworkv1.ManifestWork)
-			r.Log.Info("Processing ManifestWork event", "name", manifestWork.Name, "namespace", manifestWork.Namespace)
+			r.Log.V(1).Info("Processing ManifestWork event", helpers.LogKeyManagedCluster, manifestWork.Namespace, "manifestWork", manifestWork.Name)
 
 			req := make([]reconcile.Request, 0)
 			req = append(req, reconcile.Request{
@@ -533,5 +751,9 @@ func (r *RegisteredClusterReconciler) SetupWithManager(mgr ctrl.Manager, scheme
 	}
 
 	return controllerBuilder.
+		WithOptions(controller.Options{
+			MaxConcurrentReconciles: r.MaxConcurrentReconciles,
+			RateLimiter:             newReconcileRateLimiter(),
+		}).
 		Complete(r)
 }
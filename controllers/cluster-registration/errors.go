@@ -0,0 +1,114 @@
+// Copyright Red Hat
+
+package registeredcluster
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	singaporev1alpha1 "github.com/stolostron/cluster-registration-operator/api/singapore/v1alpha1"
+	"github.com/stolostron/cluster-registration-operator/pkg/helpers"
+)
+
+// permanentErrorRequeuePeriod is the period the synchronization is retried at after a permanent error,
+// for the fixes which don't trigger a reconciliation such as the permissions on the hub.
+const permanentErrorRequeuePeriod = 10 * time.Minute
+
+// SyncError is an error of the synchronization of a RegisteredCluster classified by the reason
+// of its Synced condition. The transient errors are retried with backoff, the permanent ones
+// are retried when the objects change.
+type SyncError struct {
+	Reason    string
+	Transient bool
+	Err       error
+}
+
+func (e *SyncError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *SyncError) Unwrap() error {
+	return e.Err
+}
+
+func newSyncError(reason string, transient bool, err error) *SyncError {
+	return &SyncError{Reason: reason, Transient: transient, Err: err}
+}
+
+// classifySyncError returns the SyncError of the error, deduced from the API or network error
+// when it is not classified yet.
+func classifySyncError(err error) *SyncError {
+	var syncErr *SyncError
+	if errors.As(err, &syncErr) {
+		return syncErr
+	}
+	var netErr net.Error
+	switch {
+	case k8serrors.IsForbidden(err) || k8serrors.IsUnauthorized(err):
+		return newSyncError(singaporev1alpha1.SyncedReasonHubPermissionDenied, false, err)
+	case isNoMatchError(err):
+		return newSyncError(singaporev1alpha1.SyncedReasonMCEComponentMissing, false, err)
+	case errors.As(err, &netErr),
+		k8serrors.IsTimeout(err),
+		k8serrors.IsServerTimeout(err),
+		k8serrors.IsTooManyRequests(err),
+		k8serrors.IsServiceUnavailable(err),
+		k8serrors.IsInternalError(err),
+		k8serrors.IsUnexpectedServerError(err):
+		return newSyncError(singaporev1alpha1.SyncedReasonHubUnreachable, true, err)
+	}
+	return newSyncError(singaporev1alpha1.SyncedReasonSyncFailed, true, err)
+}
+
+// isNoMatchError returns true when the kind of the error is not served, meta.IsNoMatchError doesn't unwrap the error.
+func isNoMatchError(err error) bool {
+	var kindErr *meta.NoKindMatchError
+	var resourceErr *meta.NoResourceMatchError
+	return errors.As(err, &kindErr) || errors.As(err, &resourceErr)
+}
+
+// classifyApplyError classifies the errors of the applier, those which are not returned
+// by the API server are rendering errors of the templates.
+func classifyApplyError(err error) error {
+	var status k8serrors.APIStatus
+	var netErr net.Error
+	if errors.As(err, &status) || errors.As(err, &netErr) || isNoMatchError(err) {
+		return err
+	}
+	return newSyncError(singaporev1alpha1.SyncedReasonTemplateRenderingFailed, false, err)
+}
+
+// handleSyncError sets the Synced condition of the RegisteredCluster from the error of the operation
+// and returns the result requeuing the transient errors with backoff.
+func (r *RegisteredClusterReconciler) handleSyncError(ctx context.Context, regCluster *singaporev1alpha1.RegisteredCluster, operation string, err error) (ctrl.Result, error) {
+	syncErr := classifySyncError(err)
+	// The import secret is expected to be pending after the ManagedCluster creation
+	if syncErr.Reason != singaporev1alpha1.SyncedReasonImportSecretPending {
+		r.syncFailed(regCluster, operation, err)
+	}
+
+	patch := client.MergeFrom(regCluster.DeepCopy())
+	regCluster.Status.Conditions = helpers.MergeStatusConditions(regCluster.Status.Conditions, metav1.Condition{
+		Type:    singaporev1alpha1.RegisteredClusterConditionSynced,
+		Status:  metav1.ConditionFalse,
+		Reason:  syncErr.Reason,
+		Message: err.Error(),
+	})
+	if err := r.Client.Status().Patch(ctx, regCluster, patch); err != nil {
+		r.Log.Error(err, "failed to update the Synced condition",
+			helpers.LogKeyWorkspace, regCluster.Namespace, helpers.LogKeyRegisteredCluster, regCluster.Name)
+	}
+
+	if syncErr.Transient {
+		return ctrl.Result{Requeue: true}, nil
+	}
+	return ctrl.Result{RequeueAfter: permanentErrorRequeuePeriod}, nil
+}
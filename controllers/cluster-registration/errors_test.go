@@ -0,0 +1,107 @@
+// Copyright Red Hat
+
+package registeredcluster
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/url"
+	"testing"
+
+	"github.com/go-logr/logr"
+	giterrors "github.com/pkg/errors"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/record"
+	clusterapiv1 "open-cluster-management.io/api/cluster/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	singaporev1alpha1 "github.com/stolostron/cluster-registration-operator/api/singapore/v1alpha1"
+)
+
+func TestClassifySyncError(t *testing.T) {
+	gr := schema.GroupResource{Group: "cluster.open-cluster-management.io", Resource: "managedclusters"}
+	tests := []struct {
+		err       error
+		reason    string
+		transient bool
+	}{
+		{k8serrors.NewForbidden(gr, "c", errors.New("denied")), singaporev1alpha1.SyncedReasonHubPermissionDenied, false},
+		{k8serrors.NewUnauthorized("expired token"), singaporev1alpha1.SyncedReasonHubPermissionDenied, false},
+		{&meta.NoKindMatchError{GroupKind: schema.GroupKind{Kind: "ManagedServiceAccount"}}, singaporev1alpha1.SyncedReasonMCEComponentMissing, false},
+		{&url.Error{Op: "Get", URL: "https://hub:6443", Err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}},
+			singaporev1alpha1.SyncedReasonHubUnreachable, true},
+		{k8serrors.NewServiceUnavailable("unavailable"), singaporev1alpha1.SyncedReasonHubUnreachable, true},
+		{k8serrors.NewConflict(gr, "c", errors.New("conflict")), singaporev1alpha1.SyncedReasonSyncFailed, true},
+		{giterrors.WithStack(newSyncError(singaporev1alpha1.SyncedReasonManifestWorkFailed, false, errors.New("not applied"))),
+			singaporev1alpha1.SyncedReasonManifestWorkFailed, false},
+	}
+	for i, test := range tests {
+		syncErr := classifySyncError(giterrors.WithStack(test.err))
+		if syncErr.Reason != test.reason || syncErr.Transient != test.transient {
+			t.Fatalf("Test %d: expected %s/%v, got %s/%v", i, test.reason, test.transient, syncErr.Reason, syncErr.Transient)
+		}
+	}
+}
+
+func TestClassifyApplyError(t *testing.T) {
+	err := classifyApplyError(errors.New(`template: managed_service_account.yaml:12: unexpected "}"`))
+	if reason := classifySyncError(err).Reason; reason != singaporev1alpha1.SyncedReasonTemplateRenderingFailed {
+		t.Fatalf("Expected reason %s, got %s", singaporev1alpha1.SyncedReasonTemplateRenderingFailed, reason)
+	}
+	err = classifyApplyError(k8serrors.NewForbidden(schema.GroupResource{Resource: "configmaps"}, "c-import", errors.New("denied")))
+	if reason := classifySyncError(err).Reason; reason != singaporev1alpha1.SyncedReasonHubPermissionDenied {
+		t.Fatalf("Expected reason %s, got %s", singaporev1alpha1.SyncedReasonHubPermissionDenied, reason)
+	}
+}
+
+func TestHandleSyncError(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := singaporev1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed to add the scheme: %s", err)
+	}
+	regCluster := newPhaseRegisteredCluster("c", "ws", false, false, "")
+	r := &RegisteredClusterReconciler{
+		Client:   fake.NewClientBuilder().WithScheme(scheme).WithObjects(regCluster).Build(),
+		Log:      logr.Discard(),
+		Recorder: record.NewFakeRecorder(10),
+	}
+
+	result, err := r.handleSyncError(context.TODO(), regCluster, syncOperationImport,
+		newSyncError(singaporev1alpha1.SyncedReasonImportSecretPending, true, errors.New("import secret not found")))
+	if err != nil || !result.Requeue {
+		t.Fatalf("Transient error not requeued with backoff: %v, %s", result, err)
+	}
+
+	result, err = r.handleSyncError(context.TODO(), regCluster, syncOperationManagedServiceAccount,
+		k8serrors.NewForbidden(schema.GroupResource{Resource: "managedserviceaccounts"}, "appstudio", errors.New("denied")))
+	if err != nil || result.Requeue || result.RequeueAfter != permanentErrorRequeuePeriod {
+		t.Fatalf("Permanent error not requeued after %s: %v, %s", permanentErrorRequeuePeriod, result, err)
+	}
+
+	updated := &singaporev1alpha1.RegisteredCluster{}
+	if err := r.Client.Get(context.TODO(), client.ObjectKeyFromObject(regCluster), updated); err != nil {
+		t.Fatalf("Failed to get RegisteredCluster: %s", err)
+	}
+	condition := meta.FindStatusCondition(updated.Status.Conditions, singaporev1alpha1.RegisteredClusterConditionSynced)
+	if condition == nil || condition.Status != metav1.ConditionFalse || condition.Reason != singaporev1alpha1.SyncedReasonHubPermissionDenied {
+		t.Fatalf("Synced condition not as expected: %v", condition)
+	}
+}
+
+func TestSyncManagedClusterKubeconfigNoClientConfigs(t *testing.T) {
+	regCluster := newPhaseRegisteredCluster("c", "ws", false, false, "")
+	managedCluster := &clusterapiv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "registered-cluster-abcde"}}
+	r := &RegisteredClusterReconciler{Log: logr.Discard()}
+
+	err := r.syncManagedClusterKubeconfig(regCluster, managedCluster, newMigrationHub(t, "hub-1"), context.TODO())
+	if syncErr := classifySyncError(err); err == nil || syncErr.Reason != singaporev1alpha1.SyncedReasonSyncFailed || syncErr.Transient {
+		t.Fatalf("Permanent error expected without ManagedClusterClientConfigs: %v", err)
+	}
+}
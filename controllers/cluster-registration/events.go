@@ -0,0 +1,51 @@
+// Copyright Red Hat
+
+package registeredcluster
+
+import (
+	corev1 "k8s.io/api/core/v1"
+
+	singaporev1alpha1 "github.com/stolostron/cluster-registration-operator/api/singapore/v1alpha1"
+)
+
+// The reasons of the events recorded on the RegisteredClusters.
+const (
+	EventReasonManagedClusterCreated string = "ManagedClusterCreated"
+	EventReasonManagedClusterAdopted string = "ManagedClusterAdopted"
+	EventReasonImportCommandReady    string = "ImportCommandReady"
+	EventReasonClusterJoined         string = "ClusterJoined"
+	EventReasonKubeconfigIssued      string = "KubeconfigIssued"
+	EventReasonKubeconfigRotated     string = "KubeconfigRotated"
+	EventReasonApprovalPending       string = "ApprovalPending"
+	EventReasonClusterApproved       string = "ClusterApproved"
+
+	EventReasonManagedClusterAddOnDisabled string = "ManagedClusterAddOnDisabled"
+
+	EventReasonAutoImportSecretCreated      string = "AutoImportSecretCreated"
+	EventReasonAutoImportCredentialsDeleted string = "AutoImportCredentialsDeleted"
+
+	EventReasonMigrationStarted            string = "MigrationStarted"
+	EventReasonMigrationImportCommandReady string = "MigrationImportCommandReady"
+	EventReasonManagedClusterDetached      string = "ManagedClusterDetached"
+	EventReasonMigrationCompleted          string = "MigrationCompleted"
+)
+
+// syncFailedEventReasons are the reasons of the warning events recorded when an operation of the reconciliation fails.
+var syncFailedEventReasons = map[string]string{
+	syncOperationHub:                   "HubNotFound",
+	syncOperationManagedCluster:        "ManagedClusterSyncFailed",
+	syncOperationImport:                "ImportCommandSyncFailed",
+	syncOperationManagedServiceAccount: "ManagedServiceAccountSyncFailed",
+	syncOperationKubeconfig:            "KubeconfigSyncFailed",
+	syncOperationAddOns:                "AddOnsSyncFailed",
+	syncOperationStatus:                "StatusUpdateFailed",
+	syncOperationDetach:                "DetachFromSourceHubFailed",
+	syncOperationApproval:              "ApprovalSyncFailed",
+	syncOperationAutoImport:            "AutoImportSyncFailed",
+}
+
+// syncFailed counts the error of the operation and records it as a warning event on the RegisteredCluster.
+func (r *RegisteredClusterReconciler) syncFailed(regCluster *singaporev1alpha1.RegisteredCluster, operation string, err error) {
+	recordSyncError(operation, err)
+	r.Recorder.Event(regCluster, corev1.EventTypeWarning, syncFailedEventReasons[syncErrorOperation(operation, err)], err.Error())
+}
@@ -0,0 +1,24 @@
+// Copyright Red Hat
+
+package registeredcluster
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"k8s.io/client-go/tools/record"
+)
+
+func TestSyncFailed(t *testing.T) {
+	recorder := record.NewFakeRecorder(10)
+	r := &RegisteredClusterReconciler{Recorder: recorder}
+	regCluster := newPhaseRegisteredCluster("c", "ws", true, true, "")
+
+	r.syncFailed(regCluster, syncOperationManagedServiceAccount,
+		&operationError{operation: syncOperationKubeconfig, err: errors.New("no client config")})
+	event := <-recorder.Events
+	if !strings.HasPrefix(event, "Warning KubeconfigSyncFailed ") {
+		t.Fatalf("Event not as expected: %s", event)
+	}
+}
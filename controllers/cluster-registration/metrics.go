@@ -0,0 +1,248 @@
+// Copyright Red Hat
+
+package registeredcluster
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clusterapiv1 "open-cluster-management.io/api/cluster/v1"
+	authv1alpha1 "open-cluster-management.io/managed-serviceaccount/api/v1alpha1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	singaporev1alpha1 "github.com/stolostron/cluster-registration-operator/api/singapore/v1alpha1"
+	"github.com/stolostron/cluster-registration-operator/pkg/helpers"
+)
+
+const metricsNamespace string = "cluster_registration"
+
+// The phases of a RegisteredCluster reported by the registered clusters metric.
+const (
+	RegisteredClusterPhasePendingApproval string = "PendingApproval"
+	RegisteredClusterPhasePending         string = "Pending"
+	RegisteredClusterPhaseJoined          string = "Joined"
+	RegisteredClusterPhaseAvailable       string = "Available"
+	RegisteredClusterPhaseReady           string = "Ready"
+)
+
+// The operations of the reconciliation reported by the sync errors metric.
+const (
+	syncOperationHub                   string = "hub"
+	syncOperationManagedCluster        string = "managed_cluster"
+	syncOperationImport                string = "import"
+	syncOperationManagedServiceAccount string = "managed_service_account"
+	syncOperationKubeconfig            string = "kubeconfig"
+	syncOperationAddOns                string = "addons"
+	syncOperationStatus                string = "status"
+	syncOperationDetach                string = "detach"
+	syncOperationApproval              string = "approval"
+	syncOperationAutoImport            string = "auto_import"
+)
+
+var (
+	// registrationDurationBuckets go from 10s to about 1h25m.
+	registrationDurationBuckets = prometheus.ExponentialBuckets(10, 2, 10)
+
+	timeToJoinedSeconds = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "time_to_joined_seconds",
+		Help:      "Time from the creation of a RegisteredCluster to its ManagedClusterJoined condition.",
+		Buckets:   registrationDurationBuckets,
+	})
+	timeToKubeconfigReadySeconds = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "time_to_kubeconfig_ready_seconds",
+		Help:      "Time from the creation of a RegisteredCluster to the creation of its kubeconfig secret.",
+		Buckets:   registrationDurationBuckets,
+	})
+	syncErrorsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "sync_errors_total",
+		Help:      "Number of errors of the RegisteredCluster reconciliation by operation and reason.",
+	}, []string{"operation", "reason"})
+	hubRequestDurationSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "hub_request_duration_seconds",
+		Help:      "Latency of the requests to the hubs by hub and HTTP method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"hub", "method"})
+	hubRequestErrorsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "hub_request_errors_total",
+		Help:      "Number of failed requests to the hubs by hub, HTTP method and status code.",
+	}, []string{"hub", "method", "code"})
+
+	registeredClustersDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "", "registered_clusters"),
+		"Number of RegisteredClusters by phase and workspace.",
+		[]string{"phase", "workspace"}, nil)
+	tokenAgeSecondsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "", "token_age_seconds"),
+		"Time since the last rotation of the ManagedServiceAccount token of a RegisteredCluster.",
+		[]string{"hub", "registered_cluster", "workspace"}, nil)
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		timeToJoinedSeconds,
+		timeToKubeconfigReadySeconds,
+		syncErrorsTotal,
+		hubRequestDurationSeconds,
+		hubRequestErrorsTotal,
+	)
+}
+
+// operationError is an error of an operation nested in another one of the reconciliation,
+// it is counted with its own operation by the sync errors metric.
+type operationError struct {
+	operation string
+	err       error
+}
+
+func (e *operationError) Error() string {
+	return e.err.Error()
+}
+
+func (e *operationError) Unwrap() error {
+	return e.err
+}
+
+// syncErrorOperation returns the operation of the nested operation error, if any, or the given operation.
+func syncErrorOperation(operation string, err error) string {
+	var opErr *operationError
+	if errors.As(err, &opErr) {
+		return opErr.operation
+	}
+	return operation
+}
+
+// recordSyncError counts the error of the operation with the reason of the API error.
+func recordSyncError(operation string, err error) {
+	operation = syncErrorOperation(operation, err)
+	reason := string(k8serrors.ReasonForError(err))
+	if len(reason) == 0 {
+		reason = "Unknown"
+	}
+	syncErrorsTotal.WithLabelValues(operation, reason).Inc()
+}
+
+// RegisteredClusterPhase returns the phase of the RegisteredCluster deduced from its status.
+func RegisteredClusterPhase(regCluster *singaporev1alpha1.RegisteredCluster) string {
+	if status, ok := helpers.GetConditionStatus(regCluster.Status.Conditions, singaporev1alpha1.RegisteredClusterConditionApproved); ok && status != metav1.ConditionTrue {
+		return RegisteredClusterPhasePendingApproval
+	}
+	if status, ok := helpers.GetConditionStatus(regCluster.Status.Conditions, clusterapiv1.ManagedClusterConditionJoined); !ok || status != metav1.ConditionTrue {
+		return RegisteredClusterPhasePending
+	}
+	if status, ok := helpers.GetConditionStatus(regCluster.Status.Conditions, clusterapiv1.ManagedClusterConditionAvailable); !ok || status != metav1.ConditionTrue {
+		return RegisteredClusterPhaseJoined
+	}
+	if len(regCluster.Status.ClusterSecretRef.Name) == 0 {
+		return RegisteredClusterPhaseAvailable
+	}
+	return RegisteredClusterPhaseReady
+}
+
+// InstrumentHubTransport records the latency and the errors of the requests to the hub of the HubConfig.
+func InstrumentHubTransport(hubConfigName string, rt http.RoundTripper) http.RoundTripper {
+	return &hubRoundTripper{hub: hubConfigName, delegate: rt}
+}
+
+type hubRoundTripper struct {
+	hub      string
+	delegate http.RoundTripper
+}
+
+func (rt *hubRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := rt.delegate.RoundTrip(req)
+	hubRequestDurationSeconds.WithLabelValues(rt.hub, req.Method).Observe(time.Since(start).Seconds())
+	switch {
+	case err != nil:
+		hubRequestErrorsTotal.WithLabelValues(rt.hub, req.Method, "<error>").Inc()
+	case resp.StatusCode >= http.StatusBadRequest:
+		hubRequestErrorsTotal.WithLabelValues(rt.hub, req.Method, strconv.Itoa(resp.StatusCode)).Inc()
+	}
+	return resp, err
+}
+
+// registrationCollector computes the metrics of the RegisteredClusters from the caches on each scrape.
+// The metrics are only reported by the leader, so they are not summed across the replicas.
+type registrationCollector struct {
+	client      client.Reader
+	hubClusters []helpers.HubInstance
+	// elected is closed once the replica is elected leader.
+	elected <-chan struct{}
+	now     func() time.Time
+}
+
+func newRegistrationCollector(c client.Reader, hubClusters []helpers.HubInstance, elected <-chan struct{}) *registrationCollector {
+	return &registrationCollector{client: c, hubClusters: hubClusters, elected: elected, now: time.Now}
+}
+
+// RegisterRegistrationCollector registers the metrics of the RegisteredClusters and their tokens in the manager metrics,
+// they are computed from the caches of the client and the hubs once the replica is elected leader.
+func RegisterRegistrationCollector(c client.Reader, hubClusters []helpers.HubInstance, elected <-chan struct{}) error {
+	return metrics.Registry.Register(newRegistrationCollector(c, hubClusters, elected))
+}
+
+func (c *registrationCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- registeredClustersDesc
+	ch <- tokenAgeSecondsDesc
+}
+
+func (c *registrationCollector) Collect(ch chan<- prometheus.Metric) {
+	select {
+	case <-c.elected:
+	default:
+		return
+	}
+	logger := ctrl.Log.WithName("controllers").WithName("RegisteredCluster").WithName("registrationCollector")
+
+	regClusters := &singaporev1alpha1.RegisteredClusterList{}
+	if err := c.client.List(context.TODO(), regClusters); err != nil {
+		logger.Error(err, "failed to list RegisteredClusters")
+	} else {
+		counts := map[[2]string]int{}
+		for i := range regClusters.Items {
+			regCluster := &regClusters.Items[i]
+			counts[[2]string{RegisteredClusterPhase(regCluster), regCluster.Namespace}]++
+		}
+		for key, count := range counts {
+			ch <- prometheus.MustNewConstMetric(registeredClustersDesc, prometheus.GaugeValue, float64(count), key[0], key[1])
+		}
+	}
+
+	for _, hubCluster := range c.hubClusters {
+		managedClusters := &clusterapiv1.ManagedClusterList{}
+		if err := hubCluster.Client.List(context.TODO(), managedClusters, client.HasLabels{RegisteredClusterNamelabel}); err != nil {
+			logger.Error(err, "failed to list ManagedClusters", helpers.LogKeyHub, hubCluster.HubConfig.Name)
+			continue
+		}
+		for _, managedCluster := range managedClusters.Items {
+			msa := &authv1alpha1.ManagedServiceAccount{}
+			if err := hubCluster.Client.Get(context.TODO(),
+				types.NamespacedName{Namespace: managedCluster.Name, Name: ManagedServiceAccountName}, msa); err != nil {
+				continue
+			}
+			if msa.Status.TokenSecretRef == nil || msa.Status.TokenSecretRef.LastRefreshTimestamp.IsZero() {
+				continue
+			}
+			age := c.now().Sub(msa.Status.TokenSecretRef.LastRefreshTimestamp.Time).Seconds()
+			ch <- prometheus.MustNewConstMetric(tokenAgeSecondsDesc, prometheus.GaugeValue, age,
+				hubCluster.HubConfig.Name,
+				managedCluster.Labels[RegisteredClusterNamelabel],
+				managedCluster.Labels[RegisteredClusterNamespacelabel])
+		}
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright Red Hat
+
+package registeredcluster
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	clusterapiv1 "open-cluster-management.io/api/cluster/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	singaporev1alpha1 "github.com/stolostron/cluster-registration-operator/api/singapore/v1alpha1"
+)
+
+func newPhaseRegisteredCluster(name, namespace string, joined, available bool, secret string) *singaporev1alpha1.RegisteredCluster {
+	conditionStatus := func(b bool) metav1.ConditionStatus {
+		if b {
+			return metav1.ConditionTrue
+		}
+		return metav1.ConditionFalse
+	}
+	return &singaporev1alpha1.RegisteredCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Status: singaporev1alpha1.RegisteredClusterStatus{
+			Conditions: []metav1.Condition{
+				{Type: clusterapiv1.ManagedClusterConditionJoined, Status: conditionStatus(joined)},
+				{Type: clusterapiv1.ManagedClusterConditionAvailable, Status: conditionStatus(available)},
+			},
+			ClusterSecretRef: corev1.LocalObjectReference{Name: secret},
+		},
+	}
+}
+
+func TestRegisteredClusterPhase(t *testing.T) {
+	tests := []struct {
+		regCluster *singaporev1alpha1.RegisteredCluster
+		phase      string
+	}{
+		{&singaporev1alpha1.RegisteredCluster{}, RegisteredClusterPhasePending},
+		{newPhaseRegisteredCluster("c", "ws", false, false, ""), RegisteredClusterPhasePending},
+		{newPhaseRegisteredCluster("c", "ws", true, false, ""), RegisteredClusterPhaseJoined},
+		{newPhaseRegisteredCluster("c", "ws", true, true, ""), RegisteredClusterPhaseAvailable},
+		{newPhaseRegisteredCluster("c", "ws", true, true, "c-cluster-secret"), RegisteredClusterPhaseReady},
+	}
+	for i, test := range tests {
+		if phase := RegisteredClusterPhase(test.regCluster); phase != test.phase {
+			t.Fatalf("Test %d: expected phase %s, got %s", i, test.phase, phase)
+		}
+	}
+}
+
+func TestRecordSyncError(t *testing.T) {
+	notFound := k8serrors.NewNotFound(schema.GroupResource{Resource: "secrets"}, "c-import")
+	before := testutil.ToFloat64(syncErrorsTotal.WithLabelValues(syncOperationImport, string(metav1.StatusReasonNotFound)))
+	recordSyncError(syncOperationImport, notFound)
+	if after := testutil.ToFloat64(syncErrorsTotal.WithLabelValues(syncOperationImport, string(metav1.StatusReasonNotFound))); after != before+1 {
+		t.Fatalf("Expected the import NotFound errors to be %v, got %v", before+1, after)
+	}
+
+	before = testutil.ToFloat64(syncErrorsTotal.WithLabelValues(syncOperationKubeconfig, "Unknown"))
+	recordSyncError(syncOperationManagedServiceAccount, &operationError{operation: syncOperationKubeconfig, err: errors.New("no client config")})
+	if after := testutil.ToFloat64(syncErrorsTotal.WithLabelValues(syncOperationKubeconfig, "Unknown")); after != before+1 {
+		t.Fatalf("Expected the kubeconfig Unknown errors to be %v, got %v", before+1, after)
+	}
+}
+
+func TestRegistrationCollector(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := singaporev1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed to add the scheme: %s", err)
+	}
+	client := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		newPhaseRegisteredCluster("c1", "ws-1", true, true, "c1-cluster-secret"),
+		newPhaseRegisteredCluster("c2", "ws-1", true, true, "c2-cluster-secret"),
+		newPhaseRegisteredCluster("c3", "ws-2", false, false, ""),
+	).Build()
+
+	expected := `
+# HELP cluster_registration_registered_clusters Number of RegisteredClusters by phase and workspace.
+# TYPE cluster_registration_registered_clusters gauge
+cluster_registration_registered_clusters{phase="Pending",workspace="ws-2"} 1
+cluster_registration_registered_clusters{phase="Ready",workspace="ws-1"} 2
+`
+	elected := make(chan struct{})
+	collector := newRegistrationCollector(client, nil, elected)
+	// The replicas which are not the leader report nothing
+	if count := testutil.CollectAndCount(collector); count != 0 {
+		t.Fatalf("Expected no metric before the election, got %d", count)
+	}
+
+	close(elected)
+	if err := testutil.CollectAndCompare(collector, strings.NewReader(expected),
+		"cluster_registration_registered_clusters"); err != nil {
+		t.Fatalf("Unexpected metrics: %s", err)
+	}
+}
@@ -0,0 +1,283 @@
+// Copyright Red Hat
+
+package registeredcluster
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	giterrors "github.com/pkg/errors"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clusterapiv1 "open-cluster-management.io/api/cluster/v1"
+	manifestworkv1 "open-cluster-management.io/api/work/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	singaporev1alpha1 "github.com/stolostron/cluster-registration-operator/api/singapore/v1alpha1"
+	"github.com/stolostron/cluster-registration-operator/pkg/helpers"
+)
+
+// migrationPollPeriod is the period the migration checks the progress of the steps which are not watched,
+// such as the deletion of the ManagedCluster on the source hub.
+const migrationPollPeriod = 10 * time.Second
+
+// isMigrating returns true when the hub of the RegisteredCluster is not the hub it is registered on.
+func isMigrating(regCluster *singaporev1alpha1.RegisteredCluster, hubCluster *helpers.HubInstance) bool {
+	return len(regCluster.Status.HubConfigName) != 0 && regCluster.Status.HubConfigName != hubCluster.HubConfig.Name
+}
+
+// syncMigration migrates the RegisteredCluster from the hub of its status to the target hub. The ManagedCluster
+// is created on the target hub and the import command is updated, once the klusterlet joined the target hub
+// the managed service account and the kubeconfig secret are moved, then the ManagedCluster is deleted
+// from the source hub. The steps are tracked in the migration status and the Migrating condition.
+func (r *RegisteredClusterReconciler) syncMigration(ctx context.Context, regCluster *singaporev1alpha1.RegisteredCluster, target *helpers.HubInstance) (ctrl.Result, error) {
+	logger := r.Log.WithValues(helpers.LogKeyWorkspace, regCluster.Namespace, helpers.LogKeyRegisteredCluster, regCluster.Name,
+		helpers.LogKeyHub, target.HubConfig.Name, "sourceHub", regCluster.Status.HubConfigName)
+
+	if regCluster.Status.Migration == nil || regCluster.Status.Migration.TargetHubConfigName != target.HubConfig.Name {
+		if err := r.setMigrationStep(ctx, regCluster, target, singaporev1alpha1.MigrationStepCreateManagedCluster); err != nil {
+			return r.handleSyncError(ctx, regCluster, syncOperationStatus, err)
+		}
+		logger.Info("Migrating to the hub")
+		r.Recorder.Eventf(regCluster, corev1.EventTypeNormal, EventReasonMigrationStarted,
+			"Migrating the cluster from the hub %s to the hub %s", regCluster.Status.HubConfigName, target.HubConfig.Name)
+	}
+
+	if err := traceStep(ctx, spanCreateManagedCluster, regCluster, target, func(ctx context.Context) error {
+		return r.createManagedCluster(regCluster, target, ctx)
+	}); err != nil {
+		logger.Error(err, "failed to create ManagedCluster on the target hub")
+		return r.handleSyncError(ctx, regCluster, syncOperationManagedCluster, err)
+	}
+
+	managedCluster, err := r.getManagedCluster(regCluster, target, ctx)
+	if err != nil {
+		logger.Error(err, "failed to get ManagedCluster on the target hub")
+		return r.handleSyncError(ctx, regCluster, syncOperationManagedCluster, err)
+	}
+	logger = logger.WithValues(helpers.LogKeyManagedCluster, managedCluster.Name)
+
+	approved := false
+	if err := traceStep(ctx, spanSyncApproval, regCluster, target, func(ctx context.Context) (err error) {
+		approved, err = r.syncApproval(regCluster, &managedCluster, target, ctx)
+		return err
+	}); err != nil {
+		logger.Error(err, "failed to sync the approval on the target hub")
+		return r.handleSyncError(ctx, regCluster, syncOperationApproval, err)
+	}
+	if !approved {
+		return ctrl.Result{}, nil
+	}
+
+	if err := r.setMigrationStep(ctx, regCluster, target, singaporev1alpha1.MigrationStepIssueImportCommand); err != nil {
+		return r.handleSyncError(ctx, regCluster, syncOperationStatus, err)
+	}
+	if err := traceStep(ctx, spanUpdateImportCommand, regCluster, target, func(ctx context.Context) error {
+		return r.updateImportCommand(regCluster, &managedCluster, target, ctx)
+	}); err != nil {
+		if classifySyncError(err).Reason != singaporev1alpha1.SyncedReasonImportSecretPending {
+			logger.Error(err, "failed to update import command of the target hub")
+		}
+		return r.handleSyncError(ctx, regCluster, syncOperationImport, err)
+	}
+	// The credentials are deleted once the cluster joined the source hub, the target hub uses them if they are provided again
+	if regCluster.Spec.AutoImportSecretRef != nil {
+		if err := traceStep(ctx, spanSyncAutoImportSecret, regCluster, target, func(ctx context.Context) error {
+			return r.syncAutoImportSecret(regCluster, &managedCluster, target, ctx)
+		}); err != nil {
+			logger.Error(err, "failed to sync the auto-import secret of the target hub")
+			return r.handleSyncError(ctx, regCluster, syncOperationAutoImport, err)
+		}
+	}
+
+	// The ManagedCluster of the target hub is watched, the migration resumes when the klusterlet joins it
+	if !meta.IsStatusConditionTrue(managedCluster.Status.Conditions, clusterapiv1.ManagedClusterConditionJoined) {
+		if regCluster.Status.Migration.Step != singaporev1alpha1.MigrationStepWaitForJoin {
+			r.Recorder.Eventf(regCluster, corev1.EventTypeNormal, EventReasonMigrationImportCommandReady,
+				"Apply the import command of the ConfigMap %s on the cluster to join the hub %s",
+				regCluster.Status.ImportCommandRef.Name, target.HubConfig.Name)
+		}
+		if err := r.setMigrationStep(ctx, regCluster, target, singaporev1alpha1.MigrationStepWaitForJoin); err != nil {
+			return r.handleSyncError(ctx, regCluster, syncOperationStatus, err)
+		}
+		return ctrl.Result{}, nil
+	}
+
+	// The conditions of the ManagedCluster of the target hub are merged before moving the service account
+	if err := traceStep(ctx, spanUpdateStatus, regCluster, target, func(ctx context.Context) error {
+		return r.updateRegisteredClusterStatus(regCluster, &managedCluster, target, ctx)
+	}); err != nil {
+		logger.Error(err, "failed to update registered cluster status")
+		return r.handleSyncError(ctx, regCluster, syncOperationStatus, err)
+	}
+
+	if err := r.setMigrationStep(ctx, regCluster, target, singaporev1alpha1.MigrationStepMoveServiceAccount); err != nil {
+		return r.handleSyncError(ctx, regCluster, syncOperationStatus, err)
+	}
+	if err := traceStep(ctx, spanSyncManagedServiceAccount, regCluster, target, func(ctx context.Context) error {
+		return r.syncManagedServiceAccount(regCluster, &managedCluster, target, ctx)
+	}); err != nil {
+		logger.Error(err, "failed to sync managedclusteraddon on the target hub")
+		return r.handleSyncError(ctx, regCluster, syncOperationManagedServiceAccount, err)
+	}
+	if err := traceStep(ctx, spanSyncManagedClusterAddOns, regCluster, target, func(ctx context.Context) error {
+		return r.syncManagedClusterAddOns(regCluster, &managedCluster, target, ctx)
+	}); err != nil {
+		logger.Error(err, "failed to sync additional managedclusteraddons on the target hub")
+		return r.handleSyncError(ctx, regCluster, syncOperationAddOns, err)
+	}
+	// The kubeconfig secret is updated with the token of the target hub once the ManifestWork is applied
+	moved, err := isServiceAccountMoved(ctx, &managedCluster, target)
+	if err != nil {
+		return r.handleSyncError(ctx, regCluster, syncOperationManagedServiceAccount, err)
+	}
+	if !moved {
+		return ctrl.Result{RequeueAfter: migrationPollPeriod}, nil
+	}
+
+	if err := r.setMigrationStep(ctx, regCluster, target, singaporev1alpha1.MigrationStepDetachFromSourceHub); err != nil {
+		return r.handleSyncError(ctx, regCluster, syncOperationStatus, err)
+	}
+	detached := true
+	// The source hub is not detached when it is not configured anymore
+	if source, err := helpers.GetHubClusterByName(regCluster.Status.HubConfigName, r.HubClusters); err == nil {
+		if err := traceStep(ctx, spanDetachFromSourceHub, regCluster, &source, func(ctx context.Context) error {
+			detached, err = r.detachFromSourceHub(ctx, regCluster, &source)
+			return err
+		}); err != nil {
+			logger.Error(err, "failed to delete ManagedCluster on the source hub")
+			return r.handleSyncError(ctx, regCluster, syncOperationDetach, err)
+		}
+	} else {
+		logger.Info("Source hub not found, the ManagedCluster is not deleted from it")
+	}
+	if !detached {
+		return ctrl.Result{RequeueAfter: migrationPollPeriod}, nil
+	}
+
+	if err := r.completeMigration(ctx, regCluster, target); err != nil {
+		return r.handleSyncError(ctx, regCluster, syncOperationStatus, err)
+	}
+	logger.Info("Migrated to the hub")
+	// The RegisteredCluster is synced with the target hub from now on
+	return ctrl.Result{Requeue: true}, nil
+}
+
+// setMigrationStep sets the step of the migration to the target hub in the status of the RegisteredCluster.
+func (r *RegisteredClusterReconciler) setMigrationStep(ctx context.Context, regCluster *singaporev1alpha1.RegisteredCluster, target *helpers.HubInstance, step singaporev1alpha1.MigrationStep) error {
+	migration := regCluster.Status.Migration
+	if migration != nil && migration.TargetHubConfigName == target.HubConfig.Name && migration.Step == step {
+		return nil
+	}
+	patch := client.MergeFrom(regCluster.DeepCopy())
+	if migration == nil || migration.TargetHubConfigName != target.HubConfig.Name {
+		regCluster.Status.Migration = &singaporev1alpha1.RegisteredClusterMigration{
+			SourceHubConfigName: regCluster.Status.HubConfigName,
+			TargetHubConfigName: target.HubConfig.Name,
+			StartTime:           metav1.Now(),
+		}
+	}
+	regCluster.Status.Migration.Step = step
+	regCluster.Status.Conditions = helpers.MergeStatusConditions(regCluster.Status.Conditions, metav1.Condition{
+		Type:    singaporev1alpha1.RegisteredClusterConditionMigrating,
+		Status:  metav1.ConditionTrue,
+		Reason:  string(step),
+		Message: fmt.Sprintf("Migrating from the hub %s to the hub %s", regCluster.Status.HubConfigName, target.HubConfig.Name),
+	})
+	return giterrors.WithStack(r.Client.Status().Patch(ctx, regCluster, patch))
+}
+
+// completeMigration records the target hub as the hub of the RegisteredCluster.
+func (r *RegisteredClusterReconciler) completeMigration(ctx context.Context, regCluster *singaporev1alpha1.RegisteredCluster, target *helpers.HubInstance) error {
+	source := regCluster.Status.HubConfigName
+	patch := client.MergeFrom(regCluster.DeepCopy())
+	regCluster.Status.HubConfigName = target.HubConfig.Name
+	regCluster.Status.Migration = nil
+	regCluster.Status.Conditions = helpers.MergeStatusConditions(regCluster.Status.Conditions, metav1.Condition{
+		Type:    singaporev1alpha1.RegisteredClusterConditionMigrating,
+		Status:  metav1.ConditionFalse,
+		Reason:  singaporev1alpha1.MigratingReasonCompleted,
+		Message: fmt.Sprintf("Migrated from the hub %s to the hub %s", source, target.HubConfig.Name),
+	})
+	if err := r.Client.Status().Patch(ctx, regCluster, patch); err != nil {
+		return giterrors.WithStack(err)
+	}
+	r.Recorder.Eventf(regCluster, corev1.EventTypeNormal, EventReasonMigrationCompleted,
+		"Migrated the cluster from the hub %s to the hub %s", source, target.HubConfig.Name)
+	return nil
+}
+
+// clearMigrationSource removes the migration source annotation set by the webhook when the hub changed,
+// once the cluster is registered on the hub of its spec the hub can be changed again.
+func (r *RegisteredClusterReconciler) clearMigrationSource(ctx context.Context, regCluster *singaporev1alpha1.RegisteredCluster) error {
+	if _, ok := regCluster.Annotations[singaporev1alpha1.MigrationSourceHubAnnotation]; !ok {
+		return nil
+	}
+	patch := client.MergeFrom(regCluster.DeepCopy())
+	delete(regCluster.Annotations, singaporev1alpha1.MigrationSourceHubAnnotation)
+	if err := r.Client.Patch(ctx, regCluster, patch); err != nil {
+		return giterrors.WithStack(err)
+	}
+	return nil
+}
+
+// isServiceAccountMoved returns true when the ManifestWork of the managed service account is applied
+// on the target hub, the kubeconfig secret then holds the token of the target hub.
+func isServiceAccountMoved(ctx context.Context, managedCluster *clusterapiv1.ManagedCluster, target *helpers.HubInstance) (bool, error) {
+	work := &manifestworkv1.ManifestWork{}
+	if err := target.Client.Get(ctx, types.NamespacedName{Name: ManagedServiceAccountName, Namespace: managedCluster.Name}, work); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, giterrors.WithStack(err)
+	}
+	return meta.IsStatusConditionTrue(work.Status.Conditions, string(manifestworkv1.ManifestApplied)), nil
+}
+
+// detachFromSourceHub deletes the ManagedClusters of the RegisteredCluster from the source hub
+// and returns true once they are deleted. The adopted ManagedClusters existed before the RegisteredCluster,
+// they are released instead of deleted.
+func (r *RegisteredClusterReconciler) detachFromSourceHub(ctx context.Context, regCluster *singaporev1alpha1.RegisteredCluster, source *helpers.HubInstance) (bool, error) {
+	managedClusterList := &clusterapiv1.ManagedClusterList{}
+	if err := source.Client.List(ctx, managedClusterList, client.MatchingLabels{RegisteredClusterNamelabel: regCluster.Name, RegisteredClusterNamespacelabel: regCluster.Namespace}); err != nil {
+		return false, giterrors.WithStack(err)
+	}
+	for i := range managedClusterList.Items {
+		managedCluster := &managedClusterList.Items[i]
+		if managedCluster.DeletionTimestamp != nil {
+			continue
+		}
+		if managedCluster.Labels[RegisteredClusterAdoptedlabel] == "true" {
+			if err := releaseManagedCluster(ctx, managedCluster, source); err != nil {
+				return false, err
+			}
+			r.Recorder.Eventf(regCluster, corev1.EventTypeNormal, EventReasonManagedClusterDetached,
+				"Released the adopted ManagedCluster %s on the hub %s", managedCluster.Name, source.HubConfig.Name)
+			continue
+		}
+		if err := source.Client.Delete(ctx, managedCluster); err != nil && !k8serrors.IsNotFound(err) {
+			return false, giterrors.WithStack(err)
+		}
+		r.Recorder.Eventf(regCluster, corev1.EventTypeNormal, EventReasonManagedClusterDetached,
+			"Deleted ManagedCluster %s from the hub %s", managedCluster.Name, source.HubConfig.Name)
+	}
+	return len(managedClusterList.Items) == 0, nil
+}
+
+// releaseManagedCluster removes the labels of the RegisteredCluster from an adopted ManagedCluster, which stays on the hub.
+func releaseManagedCluster(ctx context.Context, managedCluster *clusterapiv1.ManagedCluster, hubCluster *helpers.HubInstance) error {
+	patch := client.MergeFrom(managedCluster.DeepCopy())
+	for _, label := range []string{RegisteredClusterNamelabel, RegisteredClusterNamespacelabel, RegisteredClusterAdoptedlabel, ManagedClusterSetlabel} {
+		delete(managedCluster.Labels, label)
+	}
+	if err := hubCluster.Client.Patch(ctx, managedCluster, patch); err != nil && !k8serrors.IsNotFound(err) {
+		return giterrors.WithStack(err)
+	}
+	return nil
+}
@@ -0,0 +1,230 @@
+// Copyright Red Hat
+
+package registeredcluster
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	clusterapiv1 "open-cluster-management.io/api/cluster/v1"
+	clusterapiv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
+	manifestworkv1 "open-cluster-management.io/api/work/v1"
+	clusteradmapply "open-cluster-management.io/clusteradm/pkg/helpers/apply"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	singaporev1alpha1 "github.com/stolostron/cluster-registration-operator/api/singapore/v1alpha1"
+	"github.com/stolostron/cluster-registration-operator/pkg/helpers"
+)
+
+func newMigrationScheme(t *testing.T) *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	for _, addToScheme := range []func(*runtime.Scheme) error{
+		singaporev1alpha1.AddToScheme, clusterapiv1.AddToScheme, manifestworkv1.AddToScheme, corev1.AddToScheme,
+	} {
+		if err := addToScheme(scheme); err != nil {
+			t.Fatalf("Failed to add the scheme: %s", err)
+		}
+	}
+	return scheme
+}
+
+func newMigrationHub(t *testing.T, name string, objects ...client.Object) *helpers.HubInstance {
+	return &helpers.HubInstance{
+		HubConfig: &singaporev1alpha1.HubConfig{ObjectMeta: metav1.ObjectMeta{Name: name}},
+		Client:    fake.NewClientBuilder().WithScheme(newMigrationScheme(t)).WithObjects(objects...).Build(),
+	}
+}
+
+func TestIsMigrating(t *testing.T) {
+	hub := &helpers.HubInstance{HubConfig: &singaporev1alpha1.HubConfig{ObjectMeta: metav1.ObjectMeta{Name: "hub-2"}}}
+	regCluster := newPhaseRegisteredCluster("c", "ws", true, true, "")
+	if isMigrating(regCluster, hub) {
+		t.Fatalf("RegisteredCluster not registered yet expected not to be migrating")
+	}
+	regCluster.Status.HubConfigName = "hub-2"
+	if isMigrating(regCluster, hub) {
+		t.Fatalf("RegisteredCluster registered on its hub expected not to be migrating")
+	}
+	regCluster.Status.HubConfigName = "hub-1"
+	if !isMigrating(regCluster, hub) {
+		t.Fatalf("RegisteredCluster registered on another hub expected to be migrating")
+	}
+}
+
+func TestMigrationStatus(t *testing.T) {
+	regCluster := newPhaseRegisteredCluster("c", "ws", true, true, "")
+	regCluster.Status.HubConfigName = "hub-1"
+	recorder := record.NewFakeRecorder(10)
+	r := &RegisteredClusterReconciler{
+		Client:   fake.NewClientBuilder().WithScheme(newMigrationScheme(t)).WithObjects(regCluster).Build(),
+		Log:      logr.Discard(),
+		Recorder: recorder,
+	}
+	target := newMigrationHub(t, "hub-2")
+
+	if err := r.setMigrationStep(context.TODO(), regCluster, target, singaporev1alpha1.MigrationStepCreateManagedCluster); err != nil {
+		t.Fatalf("Failed to set the migration step: %s", err)
+	}
+	startTime := regCluster.Status.Migration.StartTime
+	if err := r.setMigrationStep(context.TODO(), regCluster, target, singaporev1alpha1.MigrationStepWaitForJoin); err != nil {
+		t.Fatalf("Failed to set the migration step: %s", err)
+	}
+	updated := &singaporev1alpha1.RegisteredCluster{}
+	if err := r.Client.Get(context.TODO(), client.ObjectKeyFromObject(regCluster), updated); err != nil {
+		t.Fatalf("Failed to get RegisteredCluster: %s", err)
+	}
+	migration := updated.Status.Migration
+	if migration == nil || migration.SourceHubConfigName != "hub-1" || migration.TargetHubConfigName != "hub-2" ||
+		migration.Step != singaporev1alpha1.MigrationStepWaitForJoin || !migration.StartTime.Equal(&startTime) {
+		t.Fatalf("Migration status not as expected: %v", migration)
+	}
+	condition := meta.FindStatusCondition(updated.Status.Conditions, singaporev1alpha1.RegisteredClusterConditionMigrating)
+	if condition == nil || condition.Status != metav1.ConditionTrue || condition.Reason != string(singaporev1alpha1.MigrationStepWaitForJoin) {
+		t.Fatalf("Migrating condition not as expected: %v", condition)
+	}
+
+	if err := r.completeMigration(context.TODO(), updated, target); err != nil {
+		t.Fatalf("Failed to complete the migration: %s", err)
+	}
+	if err := r.Client.Get(context.TODO(), client.ObjectKeyFromObject(regCluster), updated); err != nil {
+		t.Fatalf("Failed to get RegisteredCluster: %s", err)
+	}
+	if updated.Status.HubConfigName != "hub-2" || updated.Status.Migration != nil {
+		t.Fatalf("Status not as expected after the migration: %s, %v", updated.Status.HubConfigName, updated.Status.Migration)
+	}
+	condition = meta.FindStatusCondition(updated.Status.Conditions, singaporev1alpha1.RegisteredClusterConditionMigrating)
+	if condition == nil || condition.Status != metav1.ConditionFalse || condition.Reason != singaporev1alpha1.MigratingReasonCompleted {
+		t.Fatalf("Migrating condition not as expected: %v", condition)
+	}
+	if len(recorder.Events) != 1 {
+		t.Fatalf("Expected 1 event, got %d", len(recorder.Events))
+	}
+}
+
+func TestDetachFromSourceHub(t *testing.T) {
+	regCluster := newPhaseRegisteredCluster("c", "ws", true, true, "")
+	labels := map[string]string{RegisteredClusterNamelabel: "c", RegisteredClusterNamespacelabel: "ws"}
+	source := newMigrationHub(t, "hub-1",
+		&clusterapiv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "registered-cluster-abcde", Labels: labels}},
+		&clusterapiv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "other", Labels: map[string]string{RegisteredClusterNamelabel: "other"}}},
+	)
+	r := &RegisteredClusterReconciler{Log: logr.Discard(), Recorder: record.NewFakeRecorder(10)}
+
+	detached, err := r.detachFromSourceHub(context.TODO(), regCluster, source)
+	if err != nil || detached {
+		t.Fatalf("Expected the ManagedCluster to be deleted: %v, %v", detached, err)
+	}
+	detached, err = r.detachFromSourceHub(context.TODO(), regCluster, source)
+	if err != nil || !detached {
+		t.Fatalf("Expected the cluster to be detached: %v, %v", detached, err)
+	}
+	managedClusters := &clusterapiv1.ManagedClusterList{}
+	if err := source.Client.List(context.TODO(), managedClusters); err != nil {
+		t.Fatalf("Failed to list the ManagedClusters: %s", err)
+	}
+	if len(managedClusters.Items) != 1 || managedClusters.Items[0].Name != "other" {
+		t.Fatalf("Only the ManagedCluster of the RegisteredCluster expected to be deleted: %v", managedClusters.Items)
+	}
+}
+
+func TestDetachAdoptedFromSourceHub(t *testing.T) {
+	regCluster := newPhaseRegisteredCluster("c", "ws", true, true, "")
+	regCluster.Spec.ManagedClusterName = "local-cluster"
+	source := newMigrationHub(t, "hub-1",
+		&clusterapiv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "local-cluster", Labels: map[string]string{
+			RegisteredClusterNamelabel: "c", RegisteredClusterNamespacelabel: "ws", RegisteredClusterAdoptedlabel: "true",
+			ManagedClusterSetlabel: "ws-set", "vendor": "OpenShift",
+		}}},
+	)
+	r := &RegisteredClusterReconciler{Log: logr.Discard(), Recorder: record.NewFakeRecorder(10)}
+
+	detached, err := r.detachFromSourceHub(context.TODO(), regCluster, source)
+	if err != nil || detached {
+		t.Fatalf("Expected the ManagedCluster to be released: %v, %v", detached, err)
+	}
+	detached, err = r.detachFromSourceHub(context.TODO(), regCluster, source)
+	if err != nil || !detached {
+		t.Fatalf("Expected the cluster to be detached: %v, %v", detached, err)
+	}
+	managedCluster := &clusterapiv1.ManagedCluster{}
+	if err := source.Client.Get(context.TODO(), types.NamespacedName{Name: "local-cluster"}, managedCluster); err != nil {
+		t.Fatalf("The adopted ManagedCluster expected to be kept: %s", err)
+	}
+	if len(managedCluster.Labels) != 1 || managedCluster.Labels["vendor"] != "OpenShift" {
+		t.Fatalf("Only the labels of the RegisteredCluster expected to be removed: %v", managedCluster.Labels)
+	}
+}
+
+func TestEnsureManagedClusterSetOnTargetHub(t *testing.T) {
+	scheme := newMigrationScheme(t)
+	if err := clusterapiv1beta1.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed to add the scheme: %s", err)
+	}
+	target := &helpers.HubInstance{
+		HubConfig:  &singaporev1alpha1.HubConfig{ObjectMeta: metav1.ObjectMeta{Name: "hub-2"}},
+		Client:     fake.NewClientBuilder().WithScheme(scheme).Build(),
+		HubApplier: clusteradmapply.NewApplierBuilder().Build(),
+	}
+	r := &RegisteredClusterReconciler{Log: logr.Discard(), Recorder: record.NewFakeRecorder(10)}
+
+	for i := 0; i < 2; i++ {
+		if err := r.ensureManagedClusterSet("ws-set", target, context.TODO()); err != nil {
+			t.Fatalf("Failed to ensure the ManagedClusterSet: %s", err)
+		}
+	}
+	if err := target.Client.Get(context.TODO(), types.NamespacedName{Name: "ws-set"}, &clusterapiv1beta1.ManagedClusterSet{}); err != nil {
+		t.Fatalf("ManagedClusterSet expected to be created on the target hub: %s", err)
+	}
+}
+
+func TestIsServiceAccountMoved(t *testing.T) {
+	managedCluster := &clusterapiv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "registered-cluster-abcde"}}
+	work := &manifestworkv1.ManifestWork{ObjectMeta: metav1.ObjectMeta{Name: ManagedServiceAccountName, Namespace: managedCluster.Name}}
+	target := newMigrationHub(t, "hub-2")
+
+	if moved, err := isServiceAccountMoved(context.TODO(), managedCluster, target); err != nil || moved {
+		t.Fatalf("Service account not expected to be moved before the ManifestWork creation: %v, %v", moved, err)
+	}
+	if err := target.Client.Create(context.TODO(), work); err != nil {
+		t.Fatalf("Failed to create the ManifestWork: %s", err)
+	}
+	if moved, err := isServiceAccountMoved(context.TODO(), managedCluster, target); err != nil || moved {
+		t.Fatalf("Service account not expected to be moved before the ManifestWork is applied: %v, %v", moved, err)
+	}
+	work.Status.Conditions = []metav1.Condition{{Type: string(manifestworkv1.ManifestApplied), Status: metav1.ConditionTrue, Reason: "AppliedManifestWorkComplete"}}
+	if err := target.Client.Status().Update(context.TODO(), work); err != nil {
+		t.Fatalf("Failed to update the ManifestWork: %s", err)
+	}
+	if moved, err := isServiceAccountMoved(context.TODO(), managedCluster, target); err != nil || !moved {
+		t.Fatalf("Service account expected to be moved once the ManifestWork is applied: %v, %v", moved, err)
+	}
+}
+
+func TestClearMigrationSource(t *testing.T) {
+	regCluster := newPhaseRegisteredCluster("c", "ws", true, true, "")
+	regCluster.Annotations = map[string]string{singaporev1alpha1.MigrationSourceHubAnnotation: "hub-1"}
+	r := &RegisteredClusterReconciler{
+		Client: fake.NewClientBuilder().WithScheme(newMigrationScheme(t)).WithObjects(regCluster).Build(),
+		Log:    logr.Discard(),
+	}
+
+	if err := r.clearMigrationSource(context.TODO(), regCluster); err != nil {
+		t.Fatalf("Failed to clear the migration source: %s", err)
+	}
+	updated := &singaporev1alpha1.RegisteredCluster{}
+	if err := r.Client.Get(context.TODO(), client.ObjectKeyFromObject(regCluster), updated); err != nil {
+		t.Fatalf("Failed to get RegisteredCluster: %s", err)
+	}
+	if _, ok := updated.Annotations[singaporev1alpha1.MigrationSourceHubAnnotation]; ok {
+		t.Fatalf("Migration source annotation expected to be removed: %v", updated.Annotations)
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright Red Hat
+
+package registeredcluster
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	giterrors "github.com/pkg/errors"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	addonv1alpha1 "open-cluster-management.io/api/addon/v1alpha1"
+	clusterapiv1 "open-cluster-management.io/api/cluster/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	singaporev1alpha1 "github.com/stolostron/cluster-registration-operator/api/singapore/v1alpha1"
+	"github.com/stolostron/cluster-registration-operator/pkg/helpers"
+)
+
+const (
+	// ManagedClusterLabelsAnnotation lists the keys of the labels of the RegisteredCluster spec set on the ManagedCluster,
+	// the labels removed from the spec are removed from the ManagedCluster.
+	ManagedClusterLabelsAnnotation string = "registeredcluster.singapore.open-cluster-management.io/labels"
+	// ManagedClusterAddOnsAnnotation lists the additional ManagedClusterAddOns of the RegisteredCluster spec enabled on the
+	// ManagedCluster, the addons removed from the spec are deleted.
+	ManagedClusterAddOnsAnnotation string = "registeredcluster.singapore.open-cluster-management.io/addons"
+)
+
+// reservedLabels are set by the operator on the ManagedCluster and never removed as stale labels of the spec.
+var reservedLabels = map[string]bool{
+	RegisteredClusterNamelabel:      true,
+	RegisteredClusterNamespacelabel: true,
+	RegisteredClusterAdoptedlabel:   true,
+	ManagedClusterSetlabel:          true,
+}
+
+// getOwnedNames returns the names listed in the annotation of the object.
+func getOwnedNames(obj metav1.Object, annotation string) []string {
+	value := obj.GetAnnotations()[annotation]
+	if len(value) == 0 {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
+
+// setOwnedNames lists the names in the annotation of the object, the annotation is removed when there are none.
+// It returns true when the annotation changed.
+func setOwnedNames(obj metav1.Object, annotation string, names []string) bool {
+	sorted := append([]string{}, names...)
+	sort.Strings(sorted)
+	value := strings.Join(sorted, ",")
+
+	annotations := obj.GetAnnotations()
+	if current, ok := annotations[annotation]; (ok && current == value) || (!ok && len(value) == 0) {
+		return false
+	}
+	if len(value) == 0 {
+		delete(annotations, annotation)
+	} else {
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[annotation] = value
+	}
+	obj.SetAnnotations(annotations)
+	return true
+}
+
+// syncOwnedLabels sets the labels of the RegisteredCluster spec on the ManagedCluster and removes the ones previously set
+// and since removed from the spec. The other labels of the ManagedCluster are left untouched.
+// It returns true when the ManagedCluster changed.
+func syncOwnedLabels(regCluster *singaporev1alpha1.RegisteredCluster, managedCluster *clusterapiv1.ManagedCluster) bool {
+	if managedCluster.Labels == nil {
+		managedCluster.Labels = map[string]string{}
+	}
+	updated := false
+	for _, k := range getOwnedNames(managedCluster, ManagedClusterLabelsAnnotation) {
+		if _, ok := regCluster.Spec.Labels[k]; ok || reservedLabels[k] {
+			continue
+		}
+		if _, ok := managedCluster.Labels[k]; ok {
+			delete(managedCluster.Labels, k)
+			updated = true
+		}
+	}
+	keys := make([]string, 0, len(regCluster.Spec.Labels))
+	for k, v := range regCluster.Spec.Labels {
+		keys = append(keys, k)
+		if managedCluster.Labels[k] != v {
+			managedCluster.Labels[k] = v
+			updated = true
+		}
+	}
+	if setOwnedNames(managedCluster, ManagedClusterLabelsAnnotation, keys) {
+		updated = true
+	}
+	return updated
+}
+
+// syncOwnedManagedClusterAddOns deletes the additional ManagedClusterAddOns removed from the RegisteredCluster spec
+// and records the ones of the spec on the ManagedCluster before they are enabled.
+func (r *RegisteredClusterReconciler) syncOwnedManagedClusterAddOns(regCluster *singaporev1alpha1.RegisteredCluster, managedCluster *clusterapiv1.ManagedCluster, hubCluster *helpers.HubInstance, ctx context.Context) error {
+	logger := r.Log.WithName("syncOwnedManagedClusterAddOns").WithValues(helpers.LogKeyWorkspace, regCluster.Namespace, helpers.LogKeyRegisteredCluster, regCluster.Name,
+		helpers.LogKeyHub, hubCluster.HubConfig.Name, helpers.LogKeyManagedCluster, managedCluster.Name)
+
+	enabled := map[string]bool{}
+	for _, addOn := range regCluster.Spec.AddOns {
+		enabled[addOn] = true
+	}
+	for _, addOn := range getOwnedNames(managedCluster, ManagedClusterAddOnsAnnotation) {
+		if enabled[addOn] {
+			continue
+		}
+		logger.V(1).Info("deleting managedclusteraddon", "addon", addOn)
+		managedClusterAddOn := &addonv1alpha1.ManagedClusterAddOn{ObjectMeta: metav1.ObjectMeta{Namespace: managedCluster.Name, Name: addOn}}
+		if err := hubCluster.Client.Delete(ctx, managedClusterAddOn); err != nil {
+			if k8serrors.IsNotFound(err) {
+				continue
+			}
+			return giterrors.WithStack(err)
+		}
+		r.Recorder.Eventf(regCluster, corev1.EventTypeNormal, EventReasonManagedClusterAddOnDisabled,
+			"The addon %s removed from the spec is disabled on the hub %s", addOn, hubCluster.HubConfig.Name)
+	}
+
+	patch := client.MergeFrom(managedCluster.DeepCopy())
+	if !setOwnedNames(managedCluster, ManagedClusterAddOnsAnnotation, regCluster.Spec.AddOns) {
+		return nil
+	}
+	return giterrors.WithStack(hubCluster.Client.Patch(ctx, managedCluster, patch))
+}
@@ -0,0 +1,152 @@
+// Copyright Red Hat
+
+package registeredcluster
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	addonv1alpha1 "open-cluster-management.io/api/addon/v1alpha1"
+	clusterapiv1 "open-cluster-management.io/api/cluster/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	singaporev1alpha1 "github.com/stolostron/cluster-registration-operator/api/singapore/v1alpha1"
+	"github.com/stolostron/cluster-registration-operator/pkg/helpers"
+)
+
+func TestSyncOwnedLabels(t *testing.T) {
+	regCluster := newPhaseRegisteredCluster("c", "ws", false, false, "")
+	regCluster.Spec.Labels = map[string]string{"env": "prod", "team": "a"}
+	managedCluster := &clusterapiv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "mc", Labels: map[string]string{"vendor": "OpenShift"}}}
+
+	if !syncOwnedLabels(regCluster, managedCluster) {
+		t.Fatalf("ManagedCluster expected to be updated")
+	}
+	if managedCluster.Annotations[ManagedClusterLabelsAnnotation] != "env,team" {
+		t.Fatalf("Owned labels not as expected: %q", managedCluster.Annotations[ManagedClusterLabelsAnnotation])
+	}
+	if syncOwnedLabels(regCluster, managedCluster) {
+		t.Fatalf("ManagedCluster in sync expected not to be updated")
+	}
+
+	// The labels removed from the spec are removed, the labels set by others are kept
+	regCluster.Spec.Labels = map[string]string{"env": "dev"}
+	managedCluster.Labels[RegisteredClusterNamelabel] = "c"
+	managedCluster.Annotations[ManagedClusterLabelsAnnotation] = "env,team," + RegisteredClusterNamelabel
+	if !syncOwnedLabels(regCluster, managedCluster) {
+		t.Fatalf("ManagedCluster expected to be updated")
+	}
+	for k, v := range map[string]string{"vendor": "OpenShift", "env": "dev", RegisteredClusterNamelabel: "c"} {
+		if managedCluster.Labels[k] != v {
+			t.Fatalf("Label %s expected to be %q, got %q", k, v, managedCluster.Labels[k])
+		}
+	}
+	if _, ok := managedCluster.Labels["team"]; ok {
+		t.Fatalf("Label removed from the spec expected to be removed: %v", managedCluster.Labels)
+	}
+	if managedCluster.Annotations[ManagedClusterLabelsAnnotation] != "env" {
+		t.Fatalf("Owned labels not as expected: %q", managedCluster.Annotations[ManagedClusterLabelsAnnotation])
+	}
+
+	regCluster.Spec.Labels = nil
+	if !syncOwnedLabels(regCluster, managedCluster) {
+		t.Fatalf("ManagedCluster expected to be updated")
+	}
+	if _, ok := managedCluster.Labels["env"]; ok {
+		t.Fatalf("Label removed from the spec expected to be removed: %v", managedCluster.Labels)
+	}
+	if _, ok := managedCluster.Annotations[ManagedClusterLabelsAnnotation]; ok {
+		t.Fatalf("Owned labels annotation expected to be removed")
+	}
+}
+
+func TestCreateManagedClusterRemovesStaleLabels(t *testing.T) {
+	regCluster := newPhaseRegisteredCluster("c", "ws", false, false, "")
+	regCluster.Spec.Labels = map[string]string{"env": "prod"}
+	hub := newMigrationHub(t, "hub-1", &clusterapiv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{
+		Name: "mc",
+		Labels: map[string]string{
+			RegisteredClusterNamelabel: "c", RegisteredClusterNamespacelabel: "ws", "team": "a", "vendor": "OpenShift",
+		},
+		Annotations: map[string]string{ManagedClusterLabelsAnnotation: "team"},
+	}})
+	r := &RegisteredClusterReconciler{Log: logr.Discard(), Recorder: record.NewFakeRecorder(10)}
+
+	if err := r.createManagedCluster(regCluster, hub, context.TODO()); err != nil {
+		t.Fatalf("Failed to sync the ManagedCluster: %s", err)
+	}
+	managedCluster := &clusterapiv1.ManagedCluster{}
+	if err := hub.Client.Get(context.TODO(), types.NamespacedName{Name: "mc"}, managedCluster); err != nil {
+		t.Fatalf("Failed to get the ManagedCluster: %s", err)
+	}
+	if _, ok := managedCluster.Labels["team"]; ok || managedCluster.Labels["env"] != "prod" || managedCluster.Labels["vendor"] != "OpenShift" {
+		t.Fatalf("Labels not as expected: %v", managedCluster.Labels)
+	}
+	if managedCluster.Annotations[ManagedClusterLabelsAnnotation] != "env" {
+		t.Fatalf("Owned labels not as expected: %q", managedCluster.Annotations[ManagedClusterLabelsAnnotation])
+	}
+}
+
+func TestSyncOwnedManagedClusterAddOns(t *testing.T) {
+	scheme := newMigrationScheme(t)
+	if err := addonv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed to add the scheme: %s", err)
+	}
+	managedCluster := &clusterapiv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{
+		Name:        "mc",
+		Annotations: map[string]string{ManagedClusterAddOnsAnnotation: "application-manager,policy-controller"},
+	}}
+	hub := &helpers.HubInstance{
+		HubConfig: &singaporev1alpha1.HubConfig{ObjectMeta: metav1.ObjectMeta{Name: "hub-1"}},
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(managedCluster,
+			&addonv1alpha1.ManagedClusterAddOn{ObjectMeta: metav1.ObjectMeta{Namespace: "mc", Name: "application-manager"}},
+			&addonv1alpha1.ManagedClusterAddOn{ObjectMeta: metav1.ObjectMeta{Namespace: "mc", Name: "policy-controller"}},
+			&addonv1alpha1.ManagedClusterAddOn{ObjectMeta: metav1.ObjectMeta{Namespace: "mc", Name: "managed-serviceaccount"}},
+		).Build(),
+	}
+	regCluster := newPhaseRegisteredCluster("c", "ws", false, false, "")
+	regCluster.Spec.AddOns = []string{"search-collector", "application-manager"}
+	recorder := record.NewFakeRecorder(10)
+	r := &RegisteredClusterReconciler{Log: logr.Discard(), Recorder: recorder}
+
+	if err := r.syncOwnedManagedClusterAddOns(regCluster, managedCluster, hub, context.TODO()); err != nil {
+		t.Fatalf("Failed to sync the owned ManagedClusterAddOns: %s", err)
+	}
+	for name, exists := range map[string]bool{"application-manager": true, "policy-controller": false, "managed-serviceaccount": true} {
+		err := hub.Client.Get(context.TODO(), types.NamespacedName{Namespace: "mc", Name: name}, &addonv1alpha1.ManagedClusterAddOn{})
+		if exists && err != nil {
+			t.Fatalf("ManagedClusterAddOn %s expected to exist: %s", name, err)
+		}
+		if !exists && !k8serrors.IsNotFound(err) {
+			t.Fatalf("ManagedClusterAddOn %s expected to be deleted, got %v", name, err)
+		}
+	}
+	if len(recorder.Events) != 1 {
+		t.Fatalf("Expected 1 event, got %d", len(recorder.Events))
+	}
+	updated := &clusterapiv1.ManagedCluster{}
+	if err := hub.Client.Get(context.TODO(), types.NamespacedName{Name: "mc"}, updated); err != nil {
+		t.Fatalf("Failed to get the ManagedCluster: %s", err)
+	}
+	if updated.Annotations[ManagedClusterAddOnsAnnotation] != "application-manager,search-collector" {
+		t.Fatalf("Owned addons not as expected: %q", updated.Annotations[ManagedClusterAddOnsAnnotation])
+	}
+
+	// The addons already deleted are ignored
+	regCluster.Spec.AddOns = nil
+	if err := hub.Client.Delete(context.TODO(), &addonv1alpha1.ManagedClusterAddOn{ObjectMeta: metav1.ObjectMeta{Namespace: "mc", Name: "application-manager"}}); err != nil {
+		t.Fatalf("Failed to delete the ManagedClusterAddOn: %s", err)
+	}
+	if err := r.syncOwnedManagedClusterAddOns(regCluster, updated, hub, context.TODO()); err != nil {
+		t.Fatalf("Failed to sync the owned ManagedClusterAddOns: %s", err)
+	}
+	if _, ok := updated.Annotations[ManagedClusterAddOnsAnnotation]; ok {
+		t.Fatalf("Owned addons annotation expected to be removed")
+	}
+}
@@ -207,6 +207,7 @@ var _ = BeforeSuite(func() {
 			Scheme:             scheme,
 			HubClusters:        hubClusters,
 			HubApplier:         hubApplier,
+			Recorder:           mgr.GetEventRecorderFor("cluster-registration-operator"),
 		}
 		err = r.SetupWithManager(mgr, scheme)
 		Expect(err).To(BeNil())
@@ -0,0 +1,53 @@
+// Copyright Red Hat
+
+package registeredcluster
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	singaporev1alpha1 "github.com/stolostron/cluster-registration-operator/api/singapore/v1alpha1"
+	"github.com/stolostron/cluster-registration-operator/pkg/helpers"
+)
+
+// The names of the spans of the reconciliation steps.
+const (
+	spanReconcile                    string = "Reconcile"
+	spanCreateManagedCluster         string = "createManagedCluster"
+	spanUpdateImportCommand          string = "updateImportCommand"
+	spanSyncManagedServiceAccount    string = "syncManagedServiceAccount"
+	spanSyncManagedClusterKubeconfig string = "syncManagedClusterKubeconfig"
+	spanSyncManagedClusterAddOns     string = "syncManagedClusterAddOns"
+	spanUpdateStatus                 string = "updateRegisteredClusterStatus"
+	spanDetachFromSourceHub          string = "detachFromSourceHub"
+	spanSyncApproval                 string = "syncApproval"
+	spanSyncAutoImportSecret         string = "syncAutoImportSecret"
+)
+
+// startReconcileSpan starts the span of the reconciliation of the RegisteredCluster, parent of the spans of its steps.
+func startReconcileSpan(ctx context.Context, namespace, name string) (context.Context, trace.Span) {
+	return helpers.Tracer().Start(ctx, spanReconcile, trace.WithAttributes(
+		helpers.TracingAttributeWorkspace.String(namespace),
+		helpers.TracingAttributeRegisteredCluster.String(name),
+	))
+}
+
+// traceStep runs a step of the reconciliation in a span recording its error. The requests to the hub
+// and to the workspace cluster made with the context of the step are recorded as its children.
+func traceStep(ctx context.Context, name string, regCluster *singaporev1alpha1.RegisteredCluster, hubCluster *helpers.HubInstance, step func(ctx context.Context) error) error {
+	ctx, span := helpers.Tracer().Start(ctx, name, trace.WithAttributes(
+		helpers.TracingAttributeHub.String(hubCluster.HubConfig.Name),
+		helpers.TracingAttributeWorkspace.String(regCluster.Namespace),
+		helpers.TracingAttributeRegisteredCluster.String(regCluster.Name),
+	))
+	defer span.End()
+
+	if err := step(ctx); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, classifySyncError(err).Reason)
+		return err
+	}
+	return nil
+}
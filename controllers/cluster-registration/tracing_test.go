@@ -0,0 +1,67 @@
+// Copyright Red Hat
+
+package registeredcluster
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	singaporev1alpha1 "github.com/stolostron/cluster-registration-operator/api/singapore/v1alpha1"
+	"github.com/stolostron/cluster-registration-operator/pkg/helpers"
+)
+
+func TestTraceStep(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	defer otel.SetTracerProvider(otel.GetTracerProvider())
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+
+	regCluster := newPhaseRegisteredCluster("c", "ws", false, false, "")
+	hubCluster := &helpers.HubInstance{HubConfig: &singaporev1alpha1.HubConfig{ObjectMeta: metav1.ObjectMeta{Name: "hub-1"}}}
+
+	ctx, span := startReconcileSpan(context.TODO(), regCluster.Namespace, regCluster.Name)
+	if err := traceStep(ctx, spanCreateManagedCluster, regCluster, hubCluster, func(ctx context.Context) error {
+		return nil
+	}); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	stepErr := newSyncError(singaporev1alpha1.SyncedReasonImportSecretPending, true, errors.New("import secret not found"))
+	if err := traceStep(ctx, spanUpdateImportCommand, regCluster, hubCluster, func(ctx context.Context) error {
+		return stepErr
+	}); err != stepErr {
+		t.Fatalf("Error of the step not returned: %v", err)
+	}
+	span.End()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 3 {
+		t.Fatalf("Expected 3 spans, got %d", len(spans))
+	}
+	reconcile := spans[2]
+	for _, step := range spans[:2] {
+		if step.Parent.SpanID() != reconcile.SpanContext.SpanID() {
+			t.Fatalf("Span %s is not a child of the reconcile span", step.Name)
+		}
+		attributes := map[string]string{}
+		for _, attribute := range step.Attributes {
+			attributes[string(attribute.Key)] = attribute.Value.AsString()
+		}
+		if attributes["hub"] != "hub-1" || attributes["workspace"] != "ws" || attributes["registered_cluster"] != "c" {
+			t.Fatalf("Attributes of the span %s not as expected: %v", step.Name, attributes)
+		}
+	}
+	if spans[0].Name != spanCreateManagedCluster || spans[0].StatusCode == codes.Error {
+		t.Fatalf("Span %s not as expected: %s", spans[0].Name, spans[0].StatusCode)
+	}
+	if spans[1].Name != spanUpdateImportCommand || spans[1].StatusCode != codes.Error ||
+		spans[1].StatusMessage != singaporev1alpha1.SyncedReasonImportSecretPending {
+		t.Fatalf("Span %s not as expected: %s %s", spans[1].Name, spans[1].StatusCode, spans[1].StatusMessage)
+	}
+}
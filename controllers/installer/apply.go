@@ -0,0 +1,128 @@
+// Copyright Red Hat
+
+package installer
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/ghodss/yaml"
+	giterrors "github.com/pkg/errors"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	clusteradmapply "open-cluster-management.io/clusteradm/pkg/helpers/apply"
+	clusteradmasset "open-cluster-management.io/clusteradm/pkg/helpers/asset"
+
+	singaporev1alpha1 "github.com/stolostron/cluster-registration-operator/api/singapore/v1alpha1"
+)
+
+// installerFieldManager is the field manager of the objects applied by the installer.
+const installerFieldManager string = "cluster-registration-installer"
+
+// applyManifests renders the manifests and applies them with server-side apply, so the fields
+// removed from the manifests are removed from the objects and the fields set by others are kept.
+// The objects are labeled with the ManagedByLabel to be watched.
+// A DriftCorrected event is recorded on the ClusterRegistrar when an object applied with the same
+// manifest before was modified or deleted since.
+func (r *ClusterRegistrarReconciler) applyManifests(clusterRegistrar *singaporev1alpha1.ClusterRegistrar,
+	applier clusteradmapply.Applier,
+	reader clusteradmasset.ScenarioReader,
+	values interface{},
+	files ...string) error {
+	if r.appliedManifests == nil {
+		r.appliedManifests = map[string]string{}
+	}
+	for _, file := range files {
+		obj, err := renderManagedManifest(applier, reader, values, file)
+		if err != nil {
+			return err
+		}
+		key := manifestKey(obj)
+		hash, err := manifestHash(obj)
+		if err != nil {
+			return err
+		}
+		// The objects applied with another manifest are expected to change
+		resourceVersion, checkDrift := "", r.appliedManifests[key] == hash
+		if checkDrift {
+			existing := &unstructured.Unstructured{}
+			existing.SetGroupVersionKind(obj.GroupVersionKind())
+			err := r.Client.Get(context.TODO(), client.ObjectKeyFromObject(obj), existing)
+			if err != nil && !errors.IsNotFound(err) {
+				return giterrors.WithStack(err)
+			}
+			resourceVersion = existing.GetResourceVersion()
+		}
+		r.Log.V(1).Info("Apply", "kind", obj.GetKind(), "name", obj.GetName(), "namespace", obj.GetNamespace())
+		if err := r.Client.Patch(context.TODO(), obj, client.Apply, client.FieldOwner(installerFieldManager), client.ForceOwnership); err != nil {
+			return giterrors.WithStack(err)
+		}
+		r.appliedManifests[key] = hash
+		if checkDrift && obj.GetResourceVersion() != resourceVersion {
+			r.Log.Info("Drift corrected", "kind", obj.GetKind(), "name", obj.GetName(), "namespace", obj.GetNamespace())
+			r.Recorder.Eventf(clusterRegistrar, corev1.EventTypeNormal, EventReasonDriftCorrected,
+				"Re-applied the %s modified or deleted outside of the installer", key)
+		}
+	}
+	return nil
+}
+
+// manifestKey identifies the object of a manifest by kind, namespace and name.
+func manifestKey(obj *unstructured.Unstructured) string {
+	if len(obj.GetNamespace()) == 0 {
+		return obj.GetKind() + " " + obj.GetName()
+	}
+	return obj.GetKind() + " " + obj.GetNamespace() + "/" + obj.GetName()
+}
+
+// manifestHash returns the hash of the rendered manifest.
+func manifestHash(obj *unstructured.Unstructured) (string, error) {
+	b, err := obj.MarshalJSON()
+	if err != nil {
+		return "", giterrors.WithStack(err)
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(b)), nil
+}
+
+// renderManagedManifest returns the object described by the rendered manifest labeled with the ManagedByLabel.
+func renderManagedManifest(applier clusteradmapply.Applier,
+	reader clusteradmasset.ScenarioReader,
+	values interface{},
+	file string) (*unstructured.Unstructured, error) {
+	obj, err := renderManifest(applier, reader, values, file)
+	if err != nil {
+		return nil, err
+	}
+	labels := obj.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[ManagedByLabel] = ManagedByLabelValue
+	obj.SetLabels(labels)
+	return obj, nil
+}
+
+// renderManifest returns the object described by the rendered manifest.
+func renderManifest(applier clusteradmapply.Applier,
+	reader clusteradmasset.ScenarioReader,
+	values interface{},
+	file string) (*unstructured.Unstructured, error) {
+	b, err := applier.MustTemplateAsset(reader, values, "", file)
+	if err != nil {
+		return nil, giterrors.WithStack(err)
+	}
+	j, err := yaml.YAMLToJSON(b)
+	if err != nil {
+		return nil, giterrors.WithStack(err)
+	}
+	obj := &unstructured.Unstructured{}
+	if err := obj.UnmarshalJSON(j); err != nil {
+		return nil, giterrors.WithStack(err)
+	}
+	return obj, nil
+}
@@ -0,0 +1,230 @@
+// Copyright Red Hat
+
+package installer
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"fmt"
+	"time"
+
+	"github.com/openshift/library-go/pkg/crypto"
+	giterrors "github.com/pkg/errors"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/util/cert"
+)
+
+const (
+	// webhookCASecretName is the secret holding the self-signed CA of the webhook serving certificate.
+	webhookCASecretName string = "cluster-registration-webhook-ca"
+	// webhookServingSecretName is the secret holding the webhook serving certificate, mounted by the webhook.
+	webhookServingSecretName string = "cluster-registration-webhook-service"
+	// webhookServiceName is the name of the service of the webhook.
+	webhookServiceName string = "cluster-registration-webhook-service"
+	// caBundleKey is the key of the CA secret holding the current and previous CA certificates.
+	caBundleKey string = "ca-bundle.crt"
+	// kubeRootCAConfigMapName is the configmap published in every namespace with the CA of the kube-apiserver.
+	kubeRootCAConfigMapName string = "kube-root-ca.crt"
+
+	webhookCALifetime          = 5 * 365 * 24 * time.Hour
+	webhookServingCertLifetime = 365 * 24 * time.Hour
+)
+
+// webhookCertificates are the certificates injected in the manifests.
+type webhookCertificates struct {
+	// caBundle holds the CAs the APIService uses to verify the webhook serving certificate.
+	caBundle []byte
+	// kubeAPIServerCABundle holds the CAs the webhook configurations use to verify the kube-apiserver,
+	// which serves the admission reviews through the APIService.
+	kubeAPIServerCABundle []byte
+	// servingCertHash changes when the serving certificate is rotated to roll the webhook pods.
+	servingCertHash string
+}
+
+// ensureWebhookCertificates generates the self-signed CA and the webhook serving certificate,
+// and rotates them when a fifth of their lifetime is left. The previous CA stays in the CA bundle
+// until it expires, so the serving certificates it signed are trusted during the rotation.
+func (r *ClusterRegistrarReconciler) ensureWebhookCertificates() (*webhookCertificates, error) {
+	ca, caBundle, err := r.ensureWebhookCA()
+	if err != nil {
+		return nil, err
+	}
+
+	servingCert, err := r.ensureWebhookServingCert(ca)
+	if err != nil {
+		return nil, err
+	}
+
+	kubeRootCA, err := r.KubeClient.CoreV1().ConfigMaps(podNamespace).Get(context.TODO(), kubeRootCAConfigMapName, metav1.GetOptions{})
+	if err != nil {
+		return nil, giterrors.WithStack(err)
+	}
+
+	return &webhookCertificates{
+		caBundle:              caBundle,
+		kubeAPIServerCABundle: []byte(kubeRootCA.Data["ca.crt"]),
+		servingCertHash:       fmt.Sprintf("%x", sha256.Sum256(servingCert))[:16],
+	}, nil
+}
+
+// ensureWebhookCA returns the CA signing the serving certificate and the CA bundle.
+func (r *ClusterRegistrarReconciler) ensureWebhookCA() (*crypto.CA, []byte, error) {
+	secret, err := r.KubeClient.CoreV1().Secrets(podNamespace).Get(context.TODO(), webhookCASecretName, metav1.GetOptions{})
+	exists := err == nil
+	switch {
+	case errors.IsNotFound(err):
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      webhookCASecretName,
+				Namespace: podNamespace,
+				Labels:    map[string]string{ManagedByLabel: ManagedByLabelValue},
+			},
+			Type: corev1.SecretTypeTLS,
+		}
+	case err != nil:
+		return nil, nil, giterrors.WithStack(err)
+	}
+
+	ca, err := crypto.GetCAFromBytes(secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey])
+	if err == nil && !needsRotation(ca.Config.Certs[0], time.Now()) {
+		return ca, secret.Data[caBundleKey], nil
+	}
+
+	r.Log.Info("Generate the webhook CA", "name", webhookCASecretName, "namespace", podNamespace)
+	caConfig, err := crypto.MakeSelfSignedCAConfigForDuration(fmt.Sprintf("%s@%d", webhookCASecretName, time.Now().Unix()), webhookCALifetime)
+	if err != nil {
+		return nil, nil, giterrors.WithStack(err)
+	}
+	certPEM, keyPEM, err := caConfig.GetPEMBytes()
+	if err != nil {
+		return nil, nil, giterrors.WithStack(err)
+	}
+	caBundle, err := appendUnexpiredCerts(certPEM, secret.Data[caBundleKey], time.Now())
+	if err != nil {
+		return nil, nil, giterrors.WithStack(err)
+	}
+
+	secret.Data = map[string][]byte{
+		corev1.TLSCertKey:       certPEM,
+		corev1.TLSPrivateKeyKey: keyPEM,
+		caBundleKey:             caBundle,
+	}
+	if err := r.createOrUpdateSecret(secret, exists); err != nil {
+		return nil, nil, err
+	}
+	ca, err = crypto.GetCAFromBytes(certPEM, keyPEM)
+	if err != nil {
+		return nil, nil, giterrors.WithStack(err)
+	}
+	return ca, caBundle, nil
+}
+
+// ensureWebhookServingCert returns the PEM serving certificate of the webhook, it is issued again
+// when it is not signed by the CA, doesn't match the service hostnames or must be rotated.
+func (r *ClusterRegistrarReconciler) ensureWebhookServingCert(ca *crypto.CA) ([]byte, error) {
+	hostnames := sets.NewString(
+		fmt.Sprintf("%s.%s.svc", webhookServiceName, podNamespace),
+		fmt.Sprintf("%s.%s.svc.cluster.local", webhookServiceName, podNamespace),
+	)
+
+	secret, err := r.KubeClient.CoreV1().Secrets(podNamespace).Get(context.TODO(), webhookServingSecretName, metav1.GetOptions{})
+	exists := err == nil
+	switch {
+	case errors.IsNotFound(err):
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      webhookServingSecretName,
+				Namespace: podNamespace,
+				Labels:    map[string]string{ManagedByLabel: ManagedByLabelValue},
+			},
+			Type: corev1.SecretTypeTLS,
+		}
+	case err != nil:
+		return nil, giterrors.WithStack(err)
+	}
+
+	if isServingCertValid(secret.Data[corev1.TLSCertKey], ca, hostnames, time.Now()) {
+		return secret.Data[corev1.TLSCertKey], nil
+	}
+
+	r.Log.Info("Generate the webhook serving certificate", "name", webhookServingSecretName, "namespace", podNamespace)
+	servingCert, err := ca.MakeServerCertForDuration(hostnames, webhookServingCertLifetime)
+	if err != nil {
+		return nil, giterrors.WithStack(err)
+	}
+	certPEM, keyPEM, err := servingCert.GetPEMBytes()
+	if err != nil {
+		return nil, giterrors.WithStack(err)
+	}
+	secret.Data = map[string][]byte{
+		corev1.TLSCertKey:       certPEM,
+		corev1.TLSPrivateKeyKey: keyPEM,
+	}
+	if err := r.createOrUpdateSecret(secret, exists); err != nil {
+		return nil, err
+	}
+	return certPEM, nil
+}
+
+func (r *ClusterRegistrarReconciler) createOrUpdateSecret(secret *corev1.Secret, exists bool) error {
+	if !exists {
+		_, err := r.KubeClient.CoreV1().Secrets(secret.Namespace).Create(context.TODO(), secret, metav1.CreateOptions{})
+		return giterrors.WithStack(err)
+	}
+	_, err := r.KubeClient.CoreV1().Secrets(secret.Namespace).Update(context.TODO(), secret, metav1.UpdateOptions{})
+	return giterrors.WithStack(err)
+}
+
+// needsRotation returns true when less than a fifth of the certificate lifetime is left.
+func needsRotation(certificate *x509.Certificate, now time.Time) bool {
+	lifetime := certificate.NotAfter.Sub(certificate.NotBefore)
+	return now.After(certificate.NotAfter.Add(-lifetime / 5))
+}
+
+// isServingCertValid returns true when the PEM certificate is signed by the CA,
+// is valid for the hostnames and doesn't need to be rotated.
+func isServingCertValid(certPEM []byte, ca *crypto.CA, hostnames sets.String, now time.Time) bool {
+	certs, err := cert.ParseCertsPEM(certPEM)
+	if err != nil || len(certs) == 0 {
+		return false
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.Config.Certs[0])
+	for _, hostname := range hostnames.List() {
+		if _, err := certs[0].Verify(x509.VerifyOptions{DNSName: hostname, Roots: roots, CurrentTime: now}); err != nil {
+			return false
+		}
+	}
+	return !needsRotation(certs[0], now)
+}
+
+// appendUnexpiredCerts returns the PEM certificate followed by the unexpired certificates of the PEM bundle.
+func appendUnexpiredCerts(certPEM, bundlePEM []byte, now time.Time) ([]byte, error) {
+	bundle := &bytes.Buffer{}
+	bundle.Write(certPEM)
+	if len(bundlePEM) == 0 {
+		return bundle.Bytes(), nil
+	}
+	certs, err := cert.ParseCertsPEM(bundlePEM)
+	if err != nil {
+		// Drop an invalid bundle, the new CA replaces it
+		return bundle.Bytes(), nil
+	}
+	for _, c := range certs {
+		if now.After(c.NotAfter) {
+			continue
+		}
+		b, err := cert.EncodeCertificates(c)
+		if err != nil {
+			return nil, err
+		}
+		bundle.Write(b)
+	}
+	return bundle.Bytes(), nil
+}
@@ -0,0 +1,149 @@
+// Copyright Red Hat
+
+package installer
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/openshift/library-go/pkg/crypto"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/util/cert"
+	"k8s.io/client-go/util/keyutil"
+)
+
+func newCertificatesReconciler() *ClusterRegistrarReconciler {
+	podNamespace = installationNamespace
+	return &ClusterRegistrarReconciler{
+		KubeClient: fake.NewSimpleClientset(&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: kubeRootCAConfigMapName, Namespace: installationNamespace},
+			Data:       map[string]string{"ca.crt": "kube-apiserver-ca"},
+		}),
+		Log: logr.Discard(),
+	}
+}
+
+func getSecret(t *testing.T, r *ClusterRegistrarReconciler, name string) *corev1.Secret {
+	secret, err := r.KubeClient.CoreV1().Secrets(installationNamespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get secret %s: %s", name, err)
+	}
+	return secret
+}
+
+func TestEnsureWebhookCertificates(t *testing.T) {
+	r := newCertificatesReconciler()
+
+	certificates, err := r.ensureWebhookCertificates()
+	if err != nil {
+		t.Fatalf("Failed to ensure certificates: %s", err)
+	}
+	if string(certificates.kubeAPIServerCABundle) != "kube-apiserver-ca" {
+		t.Fatalf(`Kube-apiserver CA bundle not as expected: %s`, string(certificates.kubeAPIServerCABundle))
+	}
+	caSecret := getSecret(t, r, webhookCASecretName)
+	if string(certificates.caBundle) != string(caSecret.Data[caBundleKey]) {
+		t.Fatalf("CA bundle not as expected.")
+	}
+
+	ca, err := crypto.GetCAFromBytes(caSecret.Data[corev1.TLSCertKey], caSecret.Data[corev1.TLSPrivateKeyKey])
+	if err != nil {
+		t.Fatalf("Failed to parse CA: %s", err)
+	}
+	servingSecret := getSecret(t, r, webhookServingSecretName)
+	certs, err := cert.ParseCertsPEM(servingSecret.Data[corev1.TLSCertKey])
+	if err != nil {
+		t.Fatalf("Failed to parse serving certificate: %s", err)
+	}
+	if err := certs[0].CheckSignatureFrom(ca.Config.Certs[0]); err != nil {
+		t.Fatalf("Serving certificate not signed by the CA: %s", err)
+	}
+	if err := certs[0].VerifyHostname("cluster-registration-webhook-service." + installationNamespace + ".svc"); err != nil {
+		t.Fatalf("Serving certificate not valid for the service: %s", err)
+	}
+
+	again, err := r.ensureWebhookCertificates()
+	if err != nil {
+		t.Fatalf("Failed to ensure certificates: %s", err)
+	}
+	if again.servingCertHash != certificates.servingCertHash {
+		t.Fatalf("Serving certificate rotated but expected to be kept.")
+	}
+}
+
+func TestEnsureWebhookCertificatesRotation(t *testing.T) {
+	r := newCertificatesReconciler()
+
+	// A CA with less than a fifth of its lifetime left
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %s", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "old-ca"},
+		NotBefore:             time.Now().Add(-10 * time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create CA: %s", err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: cert.CertificateBlockType, Bytes: der})
+	keyPEM, err := keyutil.MarshalPrivateKeyToPEM(key)
+	if err != nil {
+		t.Fatalf("Failed to encode key: %s", err)
+	}
+	_, err = r.KubeClient.CoreV1().Secrets(installationNamespace).Create(context.TODO(), &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: webhookCASecretName, Namespace: installationNamespace},
+		Data: map[string][]byte{
+			corev1.TLSCertKey:       certPEM,
+			corev1.TLSPrivateKeyKey: keyPEM,
+			caBundleKey:             certPEM,
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create CA secret: %s", err)
+	}
+
+	certificates, err := r.ensureWebhookCertificates()
+	if err != nil {
+		t.Fatalf("Failed to ensure certificates: %s", err)
+	}
+	bundle, err := cert.ParseCertsPEM(certificates.caBundle)
+	if err != nil {
+		t.Fatalf("Failed to parse CA bundle: %s", err)
+	}
+	if len(bundle) != 2 || bundle[1].Subject.CommonName != "old-ca" {
+		t.Fatalf(`CA bundle not as expected, expected the new and old CAs: %d`, len(bundle))
+	}
+	caSecret := getSecret(t, r, webhookCASecretName)
+	if string(caSecret.Data[corev1.TLSCertKey]) == string(certPEM) {
+		t.Fatalf("CA not rotated.")
+	}
+}
+
+func TestNeedsRotation(t *testing.T) {
+	now := time.Now()
+	certificate := &x509.Certificate{NotBefore: now.Add(-80 * time.Hour), NotAfter: now.Add(30 * time.Hour)}
+	if needsRotation(certificate, now) {
+		t.Fatalf("Certificate rotated with more than a fifth of its lifetime left.")
+	}
+	certificate.NotAfter = now.Add(10 * time.Hour)
+	if !needsRotation(certificate, now) {
+		t.Fatalf("Certificate not rotated with less than a fifth of its lifetime left.")
+	}
+}
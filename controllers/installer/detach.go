@@ -0,0 +1,286 @@
+// Copyright Red Hat
+
+package installer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	giterrors "github.com/pkg/errors"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	singaporev1alpha1 "github.com/stolostron/cluster-registration-operator/api/singapore/v1alpha1"
+	"github.com/stolostron/cluster-registration-operator/pkg/helpers"
+)
+
+// The labels set by the manager on the ManagedClusters it creates or adopts for the RegisteredClusters.
+const (
+	registeredClusterNameLabel      string = "registeredcluster.singapore.open-cluster-management.io/name"
+	registeredClusterNamespaceLabel string = "registeredcluster.singapore.open-cluster-management.io/namespace"
+	registeredClusterAdoptedLabel   string = "registeredcluster.singapore.open-cluster-management.io/adopted"
+	managedClusterSetLabel          string = "cluster.open-cluster-management.io/clusterset"
+)
+
+var (
+	managedClusterGVR = schema.GroupVersionResource{
+		Group:    "cluster.open-cluster-management.io",
+		Version:  "v1",
+		Resource: "managedclusters",
+	}
+	managedClusterSetGVR = schema.GroupVersionResource{
+		Group:    "cluster.open-cluster-management.io",
+		Version:  "v1beta1",
+		Resource: "managedclustersets",
+	}
+)
+
+// newHubDynamicClient returns the client of the hub the kubeconfig gives access to, replaced in the tests.
+var newHubDynamicClient = defaultNewHubDynamicClient
+
+func defaultNewHubDynamicClient(kubeConfig []byte) (dynamic.Interface, error) {
+	hubKubeconfig, err := clientcmd.RESTConfigFromKubeConfig(kubeConfig)
+	if err != nil {
+		return nil, err
+	}
+	return dynamic.NewForConfig(hubKubeconfig)
+}
+
+// detachRegisteredClusters deletes the RegisteredClusters, then the ManagedClusters created for them on the hubs
+// and the ManagedClusterSets of the workspaces, while the manager is still running.
+// It returns true when the RegisteredClusters are detached and sets the Detaching condition with the progress.
+func (r *ClusterRegistrarReconciler) detachRegisteredClusters(clusterRegistrar *singaporev1alpha1.ClusterRegistrar) (bool, error) {
+	condition, err := r.deleteRegisteredClusters()
+	if err != nil {
+		return false, err
+	}
+	// The hubs can be shared by several installations, only the objects of the workspaces of this one are deleted
+	var workspaces []string
+	if condition == nil {
+		if workspaces, err = r.getWorkspaceNames(clusterRegistrar); err != nil {
+			return false, err
+		}
+		if condition, err = r.deleteHubManagedClusters(workspaces); err != nil {
+			return false, err
+		}
+	}
+	if condition == nil {
+		if err := r.deleteWorkspaceManagedClusterSets(workspaces); err != nil {
+			return false, err
+		}
+	}
+
+	detached := condition == nil
+	if detached {
+		condition = &metav1.Condition{
+			Type:    singaporev1alpha1.ClusterRegistrarConditionDetaching,
+			Status:  metav1.ConditionFalse,
+			Reason:  "Detached",
+			Message: "The RegisteredClusters are deregistered from the hubs",
+		}
+	}
+	r.Log.Info("Detach RegisteredClusters", "reason", condition.Reason, "message", condition.Message)
+	patch := client.MergeFrom(clusterRegistrar.DeepCopy())
+	clusterRegistrar.Status.Conditions = helpers.MergeStatusConditions(clusterRegistrar.Status.Conditions, *condition)
+	if err := r.Client.Status().Patch(context.TODO(), clusterRegistrar, patch); err != nil {
+		return false, giterrors.WithStack(err)
+	}
+	return detached, nil
+}
+
+// deleteRegisteredClusters deletes the RegisteredClusters and returns the Detaching condition
+// while some still exist. The webhook denies the deletion of the protected RegisteredClusters
+// and of those still used on the hub, they must be deleted with the force-delete annotation.
+func (r *ClusterRegistrarReconciler) deleteRegisteredClusters() (*metav1.Condition, error) {
+	regClusters := &singaporev1alpha1.RegisteredClusterList{}
+	if err := r.Client.List(context.TODO(), regClusters); err != nil {
+		if meta.IsNoMatchError(err) {
+			return nil, nil
+		}
+		return nil, giterrors.WithStack(err)
+	}
+	if len(regClusters.Items) == 0 {
+		return nil, nil
+	}
+
+	var denied []string
+	for i := range regClusters.Items {
+		regCluster := &regClusters.Items[i]
+		if regCluster.DeletionTimestamp != nil {
+			continue
+		}
+		r.Log.Info("Delete RegisteredCluster", helpers.LogKeyRegisteredCluster, regCluster.Name, helpers.LogKeyWorkspace, regCluster.Namespace)
+		err := r.Client.Delete(context.TODO(), regCluster)
+		switch {
+		case errors.IsNotFound(err):
+		case errors.IsForbidden(err) || errors.IsInvalid(err):
+			denied = append(denied, fmt.Sprintf("%s/%s: %s", regCluster.Namespace, regCluster.Name, err.Error()))
+		case err != nil:
+			return nil, giterrors.WithStack(err)
+		}
+	}
+
+	condition := &metav1.Condition{
+		Type:    singaporev1alpha1.ClusterRegistrarConditionDetaching,
+		Status:  metav1.ConditionTrue,
+		Reason:  "DeletingRegisteredClusters",
+		Message: fmt.Sprintf("Deleting %d RegisteredClusters", len(regClusters.Items)),
+	}
+	if len(denied) != 0 {
+		condition.Reason = "RegisteredClustersDeletionDenied"
+		condition.Message = fmt.Sprintf("The deletion of %d RegisteredClusters is denied, delete them with the force-delete annotation: %s",
+			len(denied), strings.Join(denied, "; "))
+	}
+	return condition, nil
+}
+
+// getWorkspaceNames returns the names of the workspace namespaces of the installation.
+func (r *ClusterRegistrarReconciler) getWorkspaceNames(clusterRegistrar *singaporev1alpha1.ClusterRegistrar) ([]string, error) {
+	values, err := newValues(clusterRegistrar, "", podNamespace)
+	if err != nil {
+		return nil, giterrors.WithStack(err)
+	}
+	namespaces, err := r.KubeClient.CoreV1().Namespaces().List(context.TODO(), metav1.ListOptions{LabelSelector: values.WorkspaceSelector})
+	if err != nil {
+		return nil, giterrors.WithStack(err)
+	}
+	names := []string{}
+	for _, namespace := range namespaces.Items {
+		names = append(names, namespace.Name)
+	}
+	return names, nil
+}
+
+// deleteHubManagedClusters deletes the ManagedClusters created for the RegisteredClusters of the workspaces
+// on the hubs and returns the Detaching condition while some still exist. The adopted ManagedClusters
+// are released by removing the labels of the manager instead.
+func (r *ClusterRegistrarReconciler) deleteHubManagedClusters(workspaces []string) (*metav1.Condition, error) {
+	if len(workspaces) == 0 {
+		return nil, nil
+	}
+	selector, err := newWorkspacesManagedClusterSelector(workspaces)
+	if err != nil {
+		return nil, giterrors.WithStack(err)
+	}
+	count := 0
+	err = r.forEachHub(func(hubConfig *singaporev1alpha1.HubConfig, hubClient dynamic.Interface) error {
+		managedClusters, err := hubClient.Resource(managedClusterGVR).List(context.TODO(),
+			metav1.ListOptions{LabelSelector: selector})
+		if err != nil {
+			return err
+		}
+		for _, managedCluster := range managedClusters.Items {
+			if managedCluster.GetLabels()[registeredClusterAdoptedLabel] == "true" {
+				r.Log.Info("Release ManagedCluster", helpers.LogKeyManagedCluster, managedCluster.GetName(), helpers.LogKeyHub, hubConfig.Name)
+				if err := releaseManagedCluster(hubClient, managedCluster.GetName()); err != nil {
+					return err
+				}
+				continue
+			}
+			count++
+			if managedCluster.GetDeletionTimestamp() != nil {
+				continue
+			}
+			r.Log.Info("Delete ManagedCluster", helpers.LogKeyManagedCluster, managedCluster.GetName(), helpers.LogKeyHub, hubConfig.Name)
+			err := hubClient.Resource(managedClusterGVR).Delete(context.TODO(), managedCluster.GetName(), metav1.DeleteOptions{})
+			if err != nil && !errors.IsNotFound(err) {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil || count == 0 {
+		return nil, err
+	}
+	return &metav1.Condition{
+		Type:    singaporev1alpha1.ClusterRegistrarConditionDetaching,
+		Status:  metav1.ConditionTrue,
+		Reason:  "DeletingManagedClusters",
+		Message: fmt.Sprintf("Deleting %d ManagedClusters on the hubs", count),
+	}, nil
+}
+
+// newWorkspacesManagedClusterSelector returns the label selector of the ManagedClusters of the RegisteredClusters of the workspaces.
+func newWorkspacesManagedClusterSelector(workspaces []string) (string, error) {
+	name, err := labels.NewRequirement(registeredClusterNameLabel, selection.Exists, nil)
+	if err != nil {
+		return "", err
+	}
+	namespace, err := labels.NewRequirement(registeredClusterNamespaceLabel, selection.In, workspaces)
+	if err != nil {
+		return "", err
+	}
+	return labels.NewSelector().Add(*name, *namespace).String(), nil
+}
+
+// releaseManagedCluster removes the labels of the manager from the adopted ManagedCluster, the ManagedClusterSet
+// label is removed too as the ManagedClusterSets of the workspaces are deleted.
+func releaseManagedCluster(hubClient dynamic.Interface, name string) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"labels": map[string]interface{}{
+				registeredClusterNameLabel:      nil,
+				registeredClusterNamespaceLabel: nil,
+				registeredClusterAdoptedLabel:   nil,
+				managedClusterSetLabel:          nil,
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = hubClient.Resource(managedClusterGVR).Patch(context.TODO(), name, types.MergePatchType, patch, metav1.PatchOptions{})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// deleteWorkspaceManagedClusterSets deletes the ManagedClusterSets of the workspaces on the hubs.
+func (r *ClusterRegistrarReconciler) deleteWorkspaceManagedClusterSets(workspaces []string) error {
+	return r.forEachHub(func(hubConfig *singaporev1alpha1.HubConfig, hubClient dynamic.Interface) error {
+		for _, workspace := range workspaces {
+			name := helpers.ManagedClusterSetNameForWorkspace(workspace)
+			r.Log.Info("Delete ManagedClusterSet", "name", name, helpers.LogKeyHub, hubConfig.Name)
+			err := hubClient.Resource(managedClusterSetGVR).Delete(context.TODO(), name, metav1.DeleteOptions{})
+			if err != nil && !errors.IsNotFound(err) {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// forEachHub calls f with the client of the hub of each HubConfig of the installation namespace.
+func (r *ClusterRegistrarReconciler) forEachHub(f func(hubConfig *singaporev1alpha1.HubConfig, hubClient dynamic.Interface) error) error {
+	hubConfigs := &singaporev1alpha1.HubConfigList{}
+	if err := r.Client.List(context.TODO(), hubConfigs, client.InNamespace(podNamespace)); err != nil {
+		return giterrors.WithStack(err)
+	}
+	for i := range hubConfigs.Items {
+		hubConfig := &hubConfigs.Items[i]
+		kubeConfig, err := r.getHubKubeConfig(hubConfig)
+		if err != nil {
+			return giterrors.WithStack(fmt.Errorf("failed to get the kubeconfig of HubConfig %s: %w", hubConfig.Name, err))
+		}
+		hubClient, err := newHubDynamicClient(kubeConfig)
+		if err != nil {
+			return giterrors.WithStack(fmt.Errorf("failed to create the client of HubConfig %s: %w", hubConfig.Name, err))
+		}
+		if err := f(hubConfig, hubClient); err != nil {
+			return giterrors.WithStack(fmt.Errorf("failed to detach from the hub of HubConfig %s: %w", hubConfig.Name, err))
+		}
+	}
+	return nil
+}
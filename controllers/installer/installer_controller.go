@@ -6,33 +6,34 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"time"
 
 	// "fmt"
 	// "os"
 
-	"github.com/ghodss/yaml"
 	giterrors "github.com/pkg/errors"
 
 	admissionregistration "k8s.io/api/admissionregistration/v1"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
-	rbacv1 "k8s.io/api/rbac/v1"
 	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
 	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
 	apiregistrationv1 "k8s.io/kube-aggregator/pkg/apis/apiregistration/v1"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
-	// "sigs.k8s.io/controller-runtime/pkg/handler"
-
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
-
-	// "sigs.k8s.io/controller-runtime/pkg/source"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	"github.com/go-logr/logr"
 	"github.com/stolostron/cluster-registration-operator/pkg/helpers"
@@ -40,6 +41,7 @@ import (
 	singaporev1alpha1 "github.com/stolostron/cluster-registration-operator/api/singapore/v1alpha1"
 	clusterregistrarconfig "github.com/stolostron/cluster-registration-operator/config"
 	"github.com/stolostron/cluster-registration-operator/deploy"
+	"github.com/stolostron/cluster-registration-operator/pkg/version"
 	clusteradmapply "open-cluster-management.io/clusteradm/pkg/helpers/apply"
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
@@ -54,26 +56,103 @@ type ClusterRegistrarReconciler struct {
 	APIExtensionClient apiextensionsclient.Interface
 	Log                logr.Logger
 	Scheme             *runtime.Scheme
+	Recorder           record.EventRecorder
+
+	// appliedManifests are the hashes of the manifests last applied by the reconciler, by object.
+	appliedManifests map[string]string
+	// hubPrerequisites is the condition of the last check of the hub prerequisites, done at hubPrerequisitesCheckTime.
+	hubPrerequisites          *metav1.Condition
+	hubPrerequisitesCheckTime time.Time
 }
 
+// The reasons of the events recorded on the ClusterRegistrars.
+const (
+	EventReasonInstalled       string = "Installed"
+	EventReasonUpgraded        string = "Upgraded"
+	EventReasonDriftCorrected  string = "DriftCorrected"
+	EventReasonInstallFailed   string = "InstallFailed"
+	EventReasonUninstallFailed string = "UninstallFailed"
+	EventReasonDuplicate       string = "Duplicate"
+)
+
 var podName, podNamespace string
 
+// The CRDs of the config directory.
+var crdFiles = []string{
+	"crd/singapore.open-cluster-management.io_clusterregistrars.yaml",
+	"crd/singapore.open-cluster-management.io_registeredclusters.yaml",
+	"crd/singapore.open-cluster-management.io_hubconfigs.yaml",
+}
+
+// The manifests of the deploy directory, in the order they are applied.
+var (
+	managerFiles = []string{
+		"cluster-registration-operator/service_account.yaml",
+		"cluster-registration-operator/leader_election_role.yaml",
+		"cluster-registration-operator/leader_election_role_binding.yaml",
+		"cluster-registration-operator/clusterrole.yaml",
+		"cluster-registration-operator/clusterrole_binding.yaml",
+		"cluster-registration-operator/metrics_service.yaml",
+	}
+	managerDeploymentFiles = []string{
+		"cluster-registration-operator/manager.yaml",
+	}
+	webhookFiles = []string{
+		"webhook/service_account.yaml",
+		"webhook/webhook_clusterrole.yaml",
+		"webhook/webhook_clusterrolebinding.yaml",
+		"webhook/webhook_role.yaml",
+		"webhook/webhook_rolebinding.yaml",
+		"webhook/webhook_service.yaml",
+	}
+	webhookDeploymentFiles = []string{
+		"webhook/webhook.yaml",
+	}
+	validatingWebhookConfigurationFile = "webhook/webhook_validating_config.yaml"
+	mutatingWebhookConfigurationFile   = "webhook/webhook_mutating_config.yaml"
+	// The v1 APIService serves the admission.k8s.io/v1 reviews,
+	// the v1alpha1 one the v1beta1 reviews of the configurations created by previous releases.
+	apiServiceFiles = []string{
+		"webhook/webhook_apiservice.yaml",
+		"webhook/webhook_apiservice_v1.yaml",
+	}
+)
+
+// installFiles returns all the manifests of the deploy directory, in the order they are applied.
+func installFiles() []string {
+	files := []string{}
+	files = append(files, managerFiles...)
+	files = append(files, managerDeploymentFiles...)
+	files = append(files, webhookFiles...)
+	files = append(files, webhookDeploymentFiles...)
+	files = append(files, validatingWebhookConfigurationFile, mutatingWebhookConfigurationFile)
+	files = append(files, apiServiceFiles...)
+	return files
+}
+
 // +kubebuilder:rbac:groups="",resources={namespaces, pods},verbs=get;list;watch
-// +kubebuilder:rbac:groups="",resources={services,serviceaccounts,configmaps},verbs=get;create;update;list;watch;delete
+// +kubebuilder:rbac:groups="",resources={services,serviceaccounts,configmaps},verbs=get;create;update;list;watch;delete;patch
+// +kubebuilder:rbac:groups="",resources={secrets},verbs=get;create;update;delete
+
+// +kubebuilder:rbac:groups="apps",resources={deployments},verbs=get;create;update;list;watch;delete;patch
 
-// +kubebuilder:rbac:groups="apps",resources={deployments},verbs=get;create;update;list;watch;delete
+// +kubebuilder:rbac:groups="rbac.authorization.k8s.io",resources={clusterroles},verbs=escalate;get;create;update;delete;bind;list;watch;patch
+// +kubebuilder:rbac:groups="rbac.authorization.k8s.io",resources={clusterrolebindings},verbs=get;create;update;delete;list;watch;patch
+// +kubebuilder:rbac:groups="rbac.authorization.k8s.io",resources={roles},verbs=get;create;update;delete;escalate;bind;list;watch;patch
+// +kubebuilder:rbac:groups="rbac.authorization.k8s.io",resources={rolebindings},verbs=get;create;update;delete;list;watch;patch
 
-// +kubebuilder:rbac:groups="rbac.authorization.k8s.io",resources={clusterroles},verbs=escalate;get;create;update;delete;bind;list
-// +kubebuilder:rbac:groups="rbac.authorization.k8s.io",resources={clusterrolebindings},verbs=get;create;update;delete;list;watch
-// +kubebuilder:rbac:groups="rbac.authorization.k8s.io",resources={roles},verbs=get;create;update;delete;escalate;bind;list;watch
-// +kubebuilder:rbac:groups="rbac.authorization.k8s.io",resources={rolebindings},verbs=get;create;update;delete;list;watch
+// +kubebuilder:rbac:groups="apiextensions.k8s.io",resources={customresourcedefinitions},verbs=get;create;update;delete;patch
 
-// +kubebuilder:rbac:groups="apiextensions.k8s.io",resources={customresourcedefinitions},verbs=get;create;update;delete
+// +kubebuilder:rbac:groups="admissionregistration.k8s.io",resources={validatingwebhookconfigurations,mutatingwebhookconfigurations},verbs=get;create;update;list;watch;delete;patch
+// +kubebuilder:rbac:groups="apiregistration.k8s.io",resources={apiservices},verbs=get;create;update;list;watch;delete;patch
 
-// +kubebuilder:rbac:groups="admissionregistration.k8s.io",resources={validatingwebhookconfigurations},verbs=get;create;update;list;watch;delete
-// +kubebuilder:rbac:groups="apiregistration.k8s.io",resources={apiservices},verbs=get;create;update;list;watch;delete
+// +kubebuilder:rbac:groups="";events.k8s.io,resources=events,verbs=create;update;patch
 
-// +kubebuilder:rbac:groups="singapore.open-cluster-management.io",resources={clusterregistrars},verbs=get;create;update;list;watch;delete
+// +kubebuilder:rbac:groups="singapore.open-cluster-management.io",resources={clusterregistrars},verbs=get;create;update;list;watch;delete;patch
+// +kubebuilder:rbac:groups="singapore.open-cluster-management.io",resources={clusterregistrars/status},verbs=get;update;patch
+// +kubebuilder:rbac:groups="singapore.open-cluster-management.io",resources={registeredclusters},verbs=get;list;watch;delete
+// +kubebuilder:rbac:groups="singapore.open-cluster-management.io",resources={hubconfigs},verbs=get;list;watch
+// +kubebuilder:rbac:groups="singapore.open-cluster-management.io",resources={hubconfigs/status},verbs=get;update;patch
 
 // +kubebuilder:rbac:groups="multicluster.openshift.io",resources={multiclusterengines},verbs=get;list;watch
 // +kubebuilder:rbac:groups="operator.open-cluster-management.io",resources={multiclusterhubs},verbs=get;list;watch
@@ -109,14 +188,32 @@ func (r *ClusterRegistrarReconciler) Reconcile(ctx context.Context, req ctrl.Req
 		return reconcile.Result{}, err
 	}
 
-	r.Log.Info("Instance", "instance", instance)
-	r.Log.Info("Running Reconcile for Cluster Registrar", "Name: ", instance.GetName(), " Namespace:", instance.GetNamespace())
+	r.Log.Info("Running Reconcile for Cluster Registrar", "name", instance.GetName(), "namespace", instance.GetNamespace())
+
+	// Only the oldest ClusterRegistrar is installed, the others are reported as duplicates
+	active, err := r.getActiveClusterRegistrar()
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if active != nil && (active.Namespace != instance.Namespace || active.Name != instance.Name) {
+		r.Log.Info("Duplicate ClusterRegistrar", "name", instance.Name, "namespace", instance.Namespace, "active", client.ObjectKeyFromObject(active).String())
+		if err := r.processDuplicateClusterRegistrar(instance, active); err != nil {
+			return reconcile.Result{}, err
+		}
+		// The duplicate is installed if the active one is deleted
+		return reconcile.Result{RequeueAfter: installRequeuePeriod}, nil
+	}
 
 	if instance.DeletionTimestamp != nil {
-		if err := r.processClusterRegistrarDeletion(instance); err != nil {
+		uninstalled, err := r.processClusterRegistrarDeletion(instance)
+		if err != nil {
+			r.Recorder.Event(instance, corev1.EventTypeWarning, EventReasonUninstallFailed, err.Error())
 			return reconcile.Result{}, err
 		}
-		r.Log.Info("remove finalizer", "Finalizer:", helpers.ClusterRegistrarFinalizer, "name", instance.Name, "namespace", instance.Namespace)
+		if !uninstalled {
+			return reconcile.Result{RequeueAfter: installRequeuePeriod}, nil
+		}
+		r.Log.Info("remove finalizer", "finalizer", helpers.ClusterRegistrarFinalizer, "name", instance.Name, "namespace", instance.Namespace)
 		controllerutil.RemoveFinalizer(instance, helpers.ClusterRegistrarFinalizer)
 		if err := r.Client.Update(context.TODO(), instance); err != nil {
 			return ctrl.Result{}, err
@@ -125,17 +222,30 @@ func (r *ClusterRegistrarReconciler) Reconcile(ctx context.Context, req ctrl.Req
 	}
 
 	// Add finalizer on clusterregistrar to make sure the installer process it.
-	controllerutil.AddFinalizer(instance, helpers.ClusterRegistrarFinalizer)
-
-	if err := r.Client.Update(context.TODO(), instance); err != nil {
-		return ctrl.Result{}, giterrors.WithStack(err)
+	// The update is only sent when the finalizer is missing, so the installation doesn't depend on the webhook.
+	if !controllerutil.ContainsFinalizer(instance, helpers.ClusterRegistrarFinalizer) {
+		controllerutil.AddFinalizer(instance, helpers.ClusterRegistrarFinalizer)
+		if err := r.Client.Update(context.TODO(), instance); err != nil {
+			return ctrl.Result{}, giterrors.WithStack(err)
+		}
 	}
 
-	if err := r.processClusterRegistrarCreation(instance); err != nil {
+	installErr := r.processClusterRegistrarCreation(instance)
+	ready, err := r.updateClusterRegistrarStatus(instance, installErr)
+	if installErr != nil {
+		r.Recorder.Event(instance, corev1.EventTypeWarning, EventReasonInstallFailed, installErr.Error())
+		return ctrl.Result{}, installErr
+	}
+	if err != nil {
 		return ctrl.Result{}, err
 	}
+	if !ready {
+		r.Log.Info("ClusterRegistrar not ready yet", "name", instance.Name, "namespace", instance.Namespace)
+		return ctrl.Result{RequeueAfter: installRequeuePeriod}, nil
+	}
 
-	return ctrl.Result{}, nil
+	// Check the hub prerequisites and the self-signed certificates for rotation
+	return ctrl.Result{RequeueAfter: hubPrerequisitesCheckPeriod}, nil
 }
 
 func (r *ClusterRegistrarReconciler) processClusterRegistrarCreation(clusterRegistrar *singaporev1alpha1.ClusterRegistrar) error {
@@ -149,279 +259,54 @@ func (r *ClusterRegistrarReconciler) processClusterRegistrarCreation(clusterRegi
 	applier := applierBuilder.WithClient(r.KubeClient, r.APIExtensionClient, r.DynamicClient).Build()
 	readerDeploy := deploy.GetScenarioResourcesReader()
 
-	//Deploy dex operator
-	files := []string{
-		"cluster-registration-operator/service_account.yaml",
-		"cluster-registration-operator/leader_election_role.yaml",
-		"cluster-registration-operator/leader_election_role_binding.yaml",
-		"cluster-registration-operator/clusterrole.yaml",
-		"cluster-registration-operator/clusterrole_binding.yaml",
-	}
-
-	image := pod.Spec.Containers[0].Image
-	values := struct {
-		Image     string
-		Namespace string
-	}{
-		Image:     image,
-		Namespace: podNamespace,
-	}
-
-	_, err := applier.ApplyDirectly(readerDeploy, values, false, "", files...)
+	values, err := newValues(clusterRegistrar, pod.Spec.Containers[0].Image, podNamespace)
 	if err != nil {
 		return giterrors.WithStack(err)
 	}
 
-	files = []string{
-		"cluster-registration-operator/manager.yaml",
-	}
-
-	_, err = applier.ApplyDeployments(readerDeploy, values, false, "", files...)
-	if err != nil {
-		return giterrors.WithStack(err)
-	}
-
-	//Deploy webhook
-	files = []string{
-		"webhook/service_account.yaml",
-		"webhook/webhook_clusterrole.yaml",
-		"webhook/webhook_clusterrolebinding.yaml",
-		"webhook/webhook_service.yaml",
-	}
-
-	_, err = applier.ApplyDirectly(readerDeploy, values, false, "", files...)
-	if err != nil {
-		return giterrors.WithStack(err)
-	}
-
-	files = []string{
-		"webhook/webhook.yaml",
-	}
-
-	_, err = applier.ApplyDeployments(readerDeploy, values, false, "", files...)
-	if err != nil {
-		return giterrors.WithStack(err)
-	}
-
-	b, err := applier.MustTemplateAsset(readerDeploy, values, "", "webhook/webhook_validating_config.yaml")
-	if err != nil {
-		return giterrors.WithStack(err)
-	}
-
-	validationWebhookConfiguration := &admissionregistration.ValidatingWebhookConfiguration{}
-	err = yaml.Unmarshal(b, validationWebhookConfiguration)
-	if err != nil {
-		return giterrors.WithStack(err)
-	}
-
-	if err := r.Client.Create(context.TODO(), validationWebhookConfiguration, &client.CreateOptions{}); err != nil {
-		if !errors.IsAlreadyExists(err) {
-			return giterrors.WithStack(err)
+	if values.CertificateProvider == string(singaporev1alpha1.CertificateProviderSelfSigned) {
+		certificates, err := r.ensureWebhookCertificates()
+		if err != nil {
+			return err
 		}
+		values.setWebhookCertificates(certificates)
 	}
 
-	b, err = applier.MustTemplateAsset(readerDeploy, values, "", "webhook/webhook_apiservice.yaml")
-	if err != nil {
-		return giterrors.WithStack(err)
-	}
-
-	apiService := &apiregistrationv1.APIService{}
-	err = yaml.Unmarshal(b, apiService)
-	if err != nil {
-		return giterrors.WithStack(err)
-	}
-	if err := r.Client.Create(context.TODO(), apiService, &client.CreateOptions{}); err != nil {
-		if !errors.IsAlreadyExists(err) {
-			return giterrors.WithStack(err)
+	if clusterRegistrar.Status.InstalledVersion != version.Version {
+		r.Log.Info("Upgrade", "from", clusterRegistrar.Status.InstalledVersion, "to", version.Version)
+		if err := r.runMigrations(applier, values, clusterRegistrar.Status.InstalledVersion); err != nil {
+			return err
 		}
 	}
 
-	return nil
-}
-
-func (r *ClusterRegistrarReconciler) processClusterRegistrarDeletion(clusterRegistrar *singaporev1alpha1.ClusterRegistrar) error {
-	r.Log.Info("processClusterRegistrarDeletion", "Name", clusterRegistrar.Name, "Namespace", clusterRegistrar.Namespace)
-	//Delete operator deployment
-	r.Log.Info("Delete deployment", "name", "cluster-registration-operator-manager", "namespace", podNamespace)
-	clusterRegOperatorDeployment := &appsv1.Deployment{}
-	err := r.Client.Get(context.TODO(), client.ObjectKey{Name: "cluster-registration-operator-manager", Namespace: podNamespace}, clusterRegOperatorDeployment)
-	switch {
-	case errors.IsNotFound(err):
-	case err == nil:
-		if err := r.Client.Delete(context.TODO(), clusterRegOperatorDeployment, &client.DeleteOptions{}); err != nil {
-			return giterrors.WithStack(err)
-		}
-	default:
-		return giterrors.WithStack(err)
-	}
-
-	r.Log.Info("Delete roleBinding", "name", "cluster-registration-operator-leader-election-rolebinding", "namespace", podNamespace)
-	clusterRegOperatorLeaderElectionRoleBinding := &rbacv1.RoleBinding{}
-	err = r.Client.Get(context.TODO(), client.ObjectKey{Name: "cluster-registration-operator-leader-election-rolebinding", Namespace: podNamespace}, clusterRegOperatorLeaderElectionRoleBinding)
-	switch {
-	case errors.IsNotFound(err):
-	case err == nil:
-		if err := r.Client.Delete(context.TODO(), clusterRegOperatorLeaderElectionRoleBinding, &client.DeleteOptions{}); err != nil {
-			return giterrors.WithStack(err)
-		}
-	default:
-		return giterrors.WithStack(err)
-	}
-
-	r.Log.Info("Delete ClusterRoleBinding", "name", "cluster-registration-operator-manager-rolebinding", "namespace", podNamespace)
-	clusterRegOperatorClusterRoleBinding := &rbacv1.ClusterRoleBinding{}
-	err = r.Client.Get(context.TODO(), client.ObjectKey{Name: "cluster-registration-operator-manager-rolebinding", Namespace: podNamespace}, clusterRegOperatorClusterRoleBinding)
-	switch {
-	case errors.IsNotFound(err):
-	case err == nil:
-		if err := r.Client.Delete(context.TODO(), clusterRegOperatorClusterRoleBinding, &client.DeleteOptions{}); err != nil {
-			return giterrors.WithStack(err)
-		}
-	default:
-		return giterrors.WithStack(err)
-	}
-
-	r.Log.Info("Delete serviceAccount", "name", "cluster-registration-operator-manager", "namespace", podNamespace)
-	clusterRegOperatorServiceAccount := &corev1.ServiceAccount{}
-	err = r.Client.Get(context.TODO(), client.ObjectKey{Name: "cluster-registration-operator-manager", Namespace: podNamespace}, clusterRegOperatorServiceAccount)
-	switch {
-	case errors.IsNotFound(err):
-	case err == nil:
-		if err := r.Client.Delete(context.TODO(), clusterRegOperatorServiceAccount, &client.DeleteOptions{}); err != nil {
-			return giterrors.WithStack(err)
-		}
-	default:
-		return giterrors.WithStack(err)
-	}
-
-	r.Log.Info("Delete ClusterRole", "name", "cluster-registration-operator-manager-role", "namespace", podNamespace)
-	clusterRegOperatorClusterRole := &rbacv1.ClusterRole{}
-	err = r.Client.Get(context.TODO(), client.ObjectKey{Name: "cluster-registration-operator-manager-role"}, clusterRegOperatorClusterRole)
-	switch {
-	case errors.IsNotFound(err):
-	case err == nil:
-		if err := r.Client.Delete(context.TODO(), clusterRegOperatorClusterRole, &client.DeleteOptions{}); err != nil {
-			return giterrors.WithStack(err)
-		}
-	default:
-		return giterrors.WithStack(err)
-	}
-
-	r.Log.Info("Delete Role", "name", "leader-election-operator-role", "namespace", podNamespace)
-	clusterRegOperatorRole := &rbacv1.Role{}
-	err = r.Client.Get(context.TODO(), client.ObjectKey{Name: "leader-election-operator-role", Namespace: podNamespace}, clusterRegOperatorRole)
-	switch {
-	case errors.IsNotFound(err):
-	case err == nil:
-		if err := r.Client.Delete(context.TODO(), clusterRegOperatorRole, &client.DeleteOptions{}); err != nil {
-			return giterrors.WithStack(err)
-		}
-	default:
-		return giterrors.WithStack(err)
-	}
-
-	// // Do not delete webhook on functional test as it is not installed
-	// pod := &corev1.Pod{}
-	// if err := r.Client.Get(context.TODO(), types.NamespacedName{Name: podName, Namespace: podNamespace}, pod); err != nil {
-	// 	return err
-	// }
-	// r.Log.Info("Pod", "Name", pod.Name, "Namespace", pod.Namespace, "deletiontimeStamp", pod.DeletionTimestamp)
-	// if strings.Contains(pod.Spec.Containers[0].Image, "coverage") {
-	// 	return nil
-	// }
-
-	//Delete webhook
-	r.Log.Info("Delete Deployment", "name", "cluster-registration-webhook-service", "namespace", podNamespace)
-	webhookDeployment := &appsv1.Deployment{}
-	err = r.Client.Get(context.TODO(), client.ObjectKey{Name: "cluster-registration-webhook-service", Namespace: podNamespace}, webhookDeployment)
-	switch {
-	case errors.IsNotFound(err):
-	case err == nil:
-		if err := r.Client.Delete(context.TODO(), webhookDeployment, &client.DeleteOptions{}); err != nil {
-			return giterrors.WithStack(err)
-		}
-	default:
-		return giterrors.WithStack(err)
-	}
-
-	r.Log.Info("Delete APIService", "name", "v1alpha1.admission.singapore.open-cluster-management.io")
-	apiService := &apiregistrationv1.APIService{}
-	err = r.Client.Get(context.TODO(), client.ObjectKey{Name: "v1alpha1.admission.singapore.open-cluster-management.io"}, apiService)
-	switch {
-	case errors.IsNotFound(err):
-	case err == nil:
-		if err := r.Client.Delete(context.TODO(), apiService, &client.DeleteOptions{}); err != nil {
-			return giterrors.WithStack(err)
-		}
-	default:
-		return giterrors.WithStack(err)
-	}
-
-	r.Log.Info("Delete ClusterRoleBinding", "name", "cluster-registration-webhook-service")
-	webHookClusterRoleBinding := &rbacv1.ClusterRoleBinding{}
-	err = r.Client.Get(context.TODO(), client.ObjectKey{Name: "cluster-registration-webhook-service"}, webHookClusterRoleBinding)
-	switch {
-	case errors.IsNotFound(err):
-	case err == nil:
-		if err := r.Client.Delete(context.TODO(), webHookClusterRoleBinding, &client.DeleteOptions{}); err != nil {
-			return giterrors.WithStack(err)
-		}
-	default:
-		return giterrors.WithStack(err)
+	if err := r.applyManifests(clusterRegistrar, applier, clusterregistrarconfig.GetScenarioResourcesReader(), nil, crdFiles...); err != nil {
+		return err
 	}
 
-	r.Log.Info("Delete ClusterRole", "name", "cluster-registration-webhook-service")
-	webHookClusterRole := &rbacv1.ClusterRole{}
-	err = r.Client.Get(context.TODO(), client.ObjectKey{Name: "cluster-registration-webhook-service"}, webHookClusterRole)
-	switch {
-	case errors.IsNotFound(err):
-	case err == nil:
-		if err := r.Client.Delete(context.TODO(), webHookClusterRole, &client.DeleteOptions{}); err != nil {
-			return giterrors.WithStack(err)
-		}
-	default:
-		return giterrors.WithStack(err)
-	}
+	return r.applyManifests(clusterRegistrar, applier, readerDeploy, values, installFiles()...)
+}
 
-	r.Log.Info("Delete serviceAccount", "name", "cluster-registration-webhook-service", "namespace", podNamespace)
-	webHookServiceAccount := &corev1.ServiceAccount{}
-	err = r.Client.Get(context.TODO(), client.ObjectKey{Name: "cluster-registration-webhook-service", Namespace: podNamespace}, webHookServiceAccount)
-	switch {
-	case errors.IsNotFound(err):
-	case err == nil:
-		if err := r.Client.Delete(context.TODO(), webHookServiceAccount, &client.DeleteOptions{}); err != nil {
-			return giterrors.WithStack(err)
+// installMissingCRDs applies the missing CRDs with the installer field manager on a new installation.
+// The existing CRDs are left to the reconciliation, which runs the migrations of the upgrades first.
+func (r *ClusterRegistrarReconciler) installMissingCRDs() error {
+	applier := clusteradmapply.NewApplierBuilder().Build()
+	for _, file := range crdFiles {
+		obj, err := renderManagedManifest(applier, clusterregistrarconfig.GetScenarioResourcesReader(), nil, file)
+		if err != nil {
+			return err
 		}
-	default:
-		return giterrors.WithStack(err)
-	}
-
-	r.Log.Info("Delete Service", "name", "cluster-registration-webhook-service", "namespace", podNamespace)
-	service := &corev1.Service{}
-	err = r.Client.Get(context.TODO(), client.ObjectKey{Name: "cluster-registration-webhook-service", Namespace: podNamespace}, service)
-	switch {
-	case errors.IsNotFound(err):
-	case err == nil:
-		if err := r.Client.Delete(context.TODO(), service, &client.DeleteOptions{}); err != nil {
+		_, err = r.APIExtensionClient.ApiextensionsV1().CustomResourceDefinitions().Get(context.TODO(), obj.GetName(), metav1.GetOptions{})
+		switch {
+		case err == nil:
+			continue
+		case !errors.IsNotFound(err):
 			return giterrors.WithStack(err)
 		}
-	default:
-		return giterrors.WithStack(err)
-	}
-
-	r.Log.Info("Delete ValidatingWebhookConfiguration", "name", "cluster-registration-webhook-service", "namespace", podNamespace)
-	validationWebhook := &admissionregistration.ValidatingWebhookConfiguration{}
-	err = r.Client.Get(context.TODO(), client.ObjectKey{Name: "cluster-registration-webhook-service", Namespace: podNamespace}, validationWebhook)
-	switch {
-	case errors.IsNotFound(err):
-	case err == nil:
-		if err := r.Client.Delete(context.TODO(), validationWebhook, &client.DeleteOptions{}); err != nil {
+		r.Log.Info("Install CRD", "name", obj.GetName())
+		if err := r.Client.Patch(context.TODO(), obj, client.Apply, client.FieldOwner(installerFieldManager), client.ForceOwnership); err != nil {
 			return giterrors.WithStack(err)
 		}
-	default:
-		return giterrors.WithStack(err)
 	}
-
 	return nil
 }
 
@@ -440,19 +325,9 @@ func (r *ClusterRegistrarReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		return giterrors.WithStack(err)
 	}
 
-	//Install CRD
-	applierBuilder := &clusteradmapply.ApplierBuilder{}
-	applier := applierBuilder.WithClient(r.KubeClient, r.APIExtensionClient, r.DynamicClient).Build()
-
-	readerClusterRegOperator := clusterregistrarconfig.GetScenarioResourcesReader()
-
-	files := []string{
-		"crd/singapore.open-cluster-management.io_clusterregistrars.yaml",
-		"crd/singapore.open-cluster-management.io_registeredclusters.yaml",
-		"crd/singapore.open-cluster-management.io_hubconfigs.yaml",
-	}
-	if _, err := applier.ApplyDirectly(readerClusterRegOperator, nil, false, "", files...); err != nil {
-		return giterrors.WithStack(err)
+	// The CRDs of the watched objects must exist before the controller starts
+	if err := r.installMissingCRDs(); err != nil {
+		return err
 	}
 
 	podName = os.Getenv("POD_NAME")
@@ -461,7 +336,22 @@ func (r *ClusterRegistrarReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		return fmt.Errorf("POD_NAME or POD_NAMESPACE not set")
 	}
 
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&singaporev1alpha1.ClusterRegistrar{}).
+	controllerBuilder := ctrl.NewControllerManagedBy(mgr).
+		For(&singaporev1alpha1.ClusterRegistrar{})
+	// Check the prerequisites of the hubs when the HubConfigs change
+	controllerBuilder.Watches(&source.Kind{Type: &singaporev1alpha1.HubConfig{}},
+		handler.EnqueueRequestsFromMapFunc(r.clusterRegistrarRequests),
+		builder.WithPredicates(predicate.GenerationChangedPredicate{}))
+	for _, obj := range managedObjects() {
+		predicates := []predicate.Predicate{managedByPredicate()}
+		if _, ok := obj.(*appsv1.Deployment); ok {
+			predicates = append(predicates, deploymentChangedPredicate())
+		}
+		controllerBuilder.Watches(&source.Kind{Type: obj},
+			handler.EnqueueRequestsFromMapFunc(r.clusterRegistrarRequests),
+			builder.WithPredicates(predicates...))
+	}
+
+	return controllerBuilder.
 		Complete(r)
 }
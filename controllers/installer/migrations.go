@@ -0,0 +1,133 @@
+// Copyright Red Hat
+
+package installer
+
+import (
+	"context"
+	"fmt"
+
+	giterrors "github.com/pkg/errors"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	utilversion "k8s.io/apimachinery/pkg/util/version"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	clusterregistrarconfig "github.com/stolostron/cluster-registration-operator/config"
+	"github.com/stolostron/cluster-registration-operator/deploy"
+	clusteradmapply "open-cluster-management.io/clusteradm/pkg/helpers/apply"
+)
+
+// legacyFieldManager is the field manager of the objects created and updated by the releases
+// which didn't use server-side apply, it defaults to the name of the binary.
+const legacyFieldManager string = "cluster-registration"
+
+// migration is a step run when upgrading from a version older than its version.
+// Migrations must be idempotent as they are run again if the upgrade fails.
+type migration struct {
+	version     string
+	description string
+	migrate     func(r *ClusterRegistrarReconciler, applier clusteradmapply.Applier, values *Values) error
+}
+
+// migrations are run in order when the installed version changes.
+var migrations = []migration{
+	{
+		version:     "0.0.2",
+		description: "Transfer the fields of the installed objects to the server-side apply field manager",
+		migrate:     transferLegacyManagedFields,
+	},
+}
+
+// pendingMigrations returns the migrations to run when upgrading from the installed version.
+// All the migrations are run when the installed version is unknown.
+func pendingMigrations(installedVersion string, migrations []migration) []migration {
+	installed, err := utilversion.ParseSemantic(installedVersion)
+	if err != nil {
+		return migrations
+	}
+	pending := []migration{}
+	for _, m := range migrations {
+		if installed.LessThan(utilversion.MustParseSemantic(m.version)) {
+			pending = append(pending, m)
+		}
+	}
+	return pending
+}
+
+// runMigrations runs the migrations pending for the installed version.
+func (r *ClusterRegistrarReconciler) runMigrations(applier clusteradmapply.Applier, values *Values, installedVersion string) error {
+	for _, m := range pendingMigrations(installedVersion, migrations) {
+		r.Log.Info("Run migration", "version", m.version, "description", m.description)
+		if err := m.migrate(r, applier, values); err != nil {
+			return giterrors.WithStack(fmt.Errorf("migration to %s failed (%s): %w", m.version, m.description, err))
+		}
+	}
+	return nil
+}
+
+// transferLegacyManagedFields gives the fields set by the legacy field manager to the installer
+// field manager, so the fields removed from the CRDs and the manifests are removed by the next apply.
+func transferLegacyManagedFields(r *ClusterRegistrarReconciler, applier clusteradmapply.Applier, values *Values) error {
+	objs := []*unstructured.Unstructured{}
+	for _, file := range crdFiles {
+		obj, err := renderManifest(applier, clusterregistrarconfig.GetScenarioResourcesReader(), nil, file)
+		if err != nil {
+			return err
+		}
+		objs = append(objs, obj)
+	}
+	readerDeploy := deploy.GetScenarioResourcesReader()
+	for _, file := range installFiles() {
+		obj, err := renderManifest(applier, readerDeploy, values, file)
+		if err != nil {
+			return err
+		}
+		objs = append(objs, obj)
+	}
+
+	for _, obj := range objs {
+		existing := &unstructured.Unstructured{}
+		existing.SetGroupVersionKind(obj.GroupVersionKind())
+		err := r.Client.Get(context.TODO(), client.ObjectKeyFromObject(obj), existing)
+		switch {
+		case errors.IsNotFound(err):
+			continue
+		case err != nil:
+			return giterrors.WithStack(err)
+		}
+
+		managedFields, ok := transferManagedFields(existing.GetManagedFields(), legacyFieldManager, installerFieldManager)
+		if !ok {
+			continue
+		}
+		r.Log.Info("Transfer managed fields", "kind", existing.GetKind(), "name", existing.GetName(), "namespace", existing.GetNamespace())
+		patch := client.MergeFrom(existing.DeepCopy())
+		existing.SetManagedFields(managedFields)
+		if err := r.Client.Patch(context.TODO(), existing, patch); err != nil {
+			return giterrors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+// transferManagedFields returns the managed fields with the update entry of the from manager
+// turned into an apply entry of the to manager. It returns false when there is nothing to transfer,
+// the to manager already applied the object or the from manager never updated it.
+func transferManagedFields(managedFields []metav1.ManagedFieldsEntry, from, to string) ([]metav1.ManagedFieldsEntry, bool) {
+	transferred := make([]metav1.ManagedFieldsEntry, 0, len(managedFields))
+	found := false
+	for _, entry := range managedFields {
+		if entry.Manager == to && entry.Operation == metav1.ManagedFieldsOperationApply {
+			return nil, false
+		}
+		if !found && entry.Manager == from && entry.Operation == metav1.ManagedFieldsOperationUpdate && len(entry.Subresource) == 0 {
+			entry.Manager = to
+			entry.Operation = metav1.ManagedFieldsOperationApply
+			found = true
+		}
+		transferred = append(transferred, entry)
+	}
+	return transferred, found
+}
@@ -0,0 +1,115 @@
+// Copyright Red Hat
+
+package installer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clusteradmapply "open-cluster-management.io/clusteradm/pkg/helpers/apply"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	singaporev1alpha1 "github.com/stolostron/cluster-registration-operator/api/singapore/v1alpha1"
+)
+
+func TestPendingMigrations(t *testing.T) {
+	migrations := []migration{
+		{version: "0.0.2", description: "first"},
+		{version: "0.1.0", description: "second"},
+		{version: "1.0.0", description: "third"},
+	}
+	cases := []struct {
+		installedVersion string
+		expected         []string
+	}{
+		{installedVersion: "", expected: []string{"first", "second", "third"}},
+		{installedVersion: "dev", expected: []string{"first", "second", "third"}},
+		{installedVersion: "0.0.1", expected: []string{"first", "second", "third"}},
+		{installedVersion: "0.0.2", expected: []string{"second", "third"}},
+		{installedVersion: "0.2.0", expected: []string{"third"}},
+		{installedVersion: "1.0.0", expected: []string{}},
+	}
+	for _, c := range cases {
+		t.Run(c.installedVersion, func(t *testing.T) {
+			pending := pendingMigrations(c.installedVersion, migrations)
+			if len(pending) != len(c.expected) {
+				t.Fatalf(`Pending migrations not as expected. Expected %v, actual %v`, c.expected, pending)
+			}
+			for i := range pending {
+				if pending[i].description != c.expected[i] {
+					t.Fatalf(`Pending migrations not as expected. Expected %v, actual %v`, c.expected, pending)
+				}
+			}
+		})
+	}
+}
+
+func TestMigrationsVersions(t *testing.T) {
+	// pendingMigrations panics on invalid versions
+	pendingMigrations("0.0.0", migrations)
+}
+
+func TestTransferManagedFields(t *testing.T) {
+	managedFields := []metav1.ManagedFieldsEntry{
+		{Manager: "kube-controller-manager", Operation: metav1.ManagedFieldsOperationUpdate, Subresource: "status"},
+		{Manager: legacyFieldManager, Operation: metav1.ManagedFieldsOperationUpdate},
+		{Manager: "service-ca", Operation: metav1.ManagedFieldsOperationUpdate},
+	}
+	transferred, ok := transferManagedFields(managedFields, legacyFieldManager, installerFieldManager)
+	if !ok {
+		t.Fatalf("Managed fields not transferred.")
+	}
+	if len(transferred) != len(managedFields) {
+		t.Fatalf(`Managed fields not as expected: %v`, transferred)
+	}
+	if transferred[1].Manager != installerFieldManager || transferred[1].Operation != metav1.ManagedFieldsOperationApply {
+		t.Fatalf(`Managed fields entry not as expected: %v`, transferred[1])
+	}
+	if transferred[0] != managedFields[0] || transferred[2] != managedFields[2] {
+		t.Fatalf(`Other managed fields entries changed: %v`, transferred)
+	}
+
+	if _, ok := transferManagedFields(transferred, legacyFieldManager, installerFieldManager); ok {
+		t.Fatalf("Managed fields transferred again.")
+	}
+	if _, ok := transferManagedFields(managedFields[2:], legacyFieldManager, installerFieldManager); ok {
+		t.Fatalf("Managed fields transferred without legacy entry.")
+	}
+}
+
+func TestTransferLegacyManagedFieldsCRDs(t *testing.T) {
+	s := runtime.NewScheme()
+	if err := apiextensionsv1.AddToScheme(s); err != nil {
+		t.Fatalf("Failed to build scheme: %s", err)
+	}
+	crd := &apiextensionsv1.CustomResourceDefinition{ObjectMeta: metav1.ObjectMeta{
+		Name:          "registeredclusters.singapore.open-cluster-management.io",
+		ManagedFields: []metav1.ManagedFieldsEntry{{Manager: legacyFieldManager, Operation: metav1.ManagedFieldsOperationUpdate}},
+	}}
+	r := &ClusterRegistrarReconciler{
+		Client: fake.NewClientBuilder().WithScheme(s).WithObjects(crd).Build(),
+		Log:    logr.Discard(),
+	}
+	values, err := newValues(&singaporev1alpha1.ClusterRegistrar{}, "installer-image", "cluster-reg-config")
+	if err != nil {
+		t.Fatalf("Failed to create the values: %s", err)
+	}
+
+	if err := transferLegacyManagedFields(r, clusteradmapply.NewApplierBuilder().Build(), values); err != nil {
+		t.Fatalf("Failed to transfer the managed fields: %s", err)
+	}
+	updated := &apiextensionsv1.CustomResourceDefinition{}
+	if err := r.Client.Get(context.TODO(), client.ObjectKeyFromObject(crd), updated); err != nil {
+		t.Fatalf("Failed to get the CRD: %s", err)
+	}
+	if len(updated.ManagedFields) != 1 || updated.ManagedFields[0].Manager != installerFieldManager ||
+		updated.ManagedFields[0].Operation != metav1.ManagedFieldsOperationApply {
+		t.Fatalf("Managed fields of the CRD not transferred: %v", updated.ManagedFields)
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright Red Hat
+
+package installer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	giterrors "github.com/pkg/errors"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	singaporev1alpha1 "github.com/stolostron/cluster-registration-operator/api/singapore/v1alpha1"
+	"github.com/stolostron/cluster-registration-operator/pkg/helpers"
+)
+
+// newHubPrerequisitesChecker returns the checker of the hub of a HubConfig, replaced in the tests.
+var newHubPrerequisitesChecker = helpers.NewHubPrerequisitesChecker
+
+// hubPrerequisitesCheckPeriod is the period the prerequisites of the hubs are checked at. They are not checked
+// on each reconciliation as an unreachable hub slows the check down.
+const hubPrerequisitesCheckPeriod = 5 * time.Minute
+
+// getHubPrerequisitesCondition returns the condition of the last check of the hub prerequisites,
+// they are checked again once the check period elapsed.
+func (r *ClusterRegistrarReconciler) getHubPrerequisitesCondition() metav1.Condition {
+	if r.hubPrerequisites != nil && time.Since(r.hubPrerequisitesCheckTime) < hubPrerequisitesCheckPeriod {
+		return *r.hubPrerequisites
+	}
+	condition, err := r.checkHubPrerequisites()
+	if err != nil {
+		return newErrorCondition(singaporev1alpha1.ClusterRegistrarConditionHubPrerequisitesMet, "HubConfigs", podNamespace, err)
+	}
+	r.hubPrerequisites = &condition
+	r.hubPrerequisitesCheckTime = time.Now()
+	return condition
+}
+
+// checkHubPrerequisites sets the prerequisites conditions of the HubConfigs of the installation namespace
+// and returns a condition summarizing them.
+func (r *ClusterRegistrarReconciler) checkHubPrerequisites() (metav1.Condition, error) {
+	conditionType := singaporev1alpha1.ClusterRegistrarConditionHubPrerequisitesMet
+	hubConfigs := &singaporev1alpha1.HubConfigList{}
+	if err := r.Client.List(context.TODO(), hubConfigs, client.InNamespace(podNamespace)); err != nil {
+		return metav1.Condition{}, giterrors.WithStack(err)
+	}
+	if len(hubConfigs.Items) == 0 {
+		return metav1.Condition{
+			Type:    conditionType,
+			Status:  metav1.ConditionFalse,
+			Reason:  "NoHubConfig",
+			Message: fmt.Sprintf("Create a HubConfig in the %s namespace to onboard a hub", podNamespace),
+		}, nil
+	}
+
+	var unmet []string
+	for i := range hubConfigs.Items {
+		hubConfig := &hubConfigs.Items[i]
+		conditions := r.getHubPrerequisitesConditions(hubConfig)
+		if len(helpers.UnmetHubPrerequisites(conditions)) != 0 {
+			unmet = append(unmet, hubConfig.Name)
+		}
+		patch := client.MergeFrom(hubConfig.DeepCopy())
+		hubConfig.Status.Conditions = helpers.MergeStatusConditions(hubConfig.Status.Conditions, conditions...)
+		// The conditions of the other HubConfigs are still updated, they are patched again at the next check
+		if err := r.Client.Status().Patch(context.TODO(), hubConfig, patch); err != nil {
+			r.Log.Error(err, "failed to update the prerequisites conditions of the HubConfig", helpers.LogKeyHub, hubConfig.Name)
+		}
+	}
+	if len(unmet) != 0 {
+		return metav1.Condition{
+			Type:    conditionType,
+			Status:  metav1.ConditionFalse,
+			Reason:  "PrerequisitesNotMet",
+			Message: fmt.Sprintf("The prerequisites of the HubConfigs %s are not met, check their conditions", strings.Join(unmet, ", ")),
+		}, nil
+	}
+	return metav1.Condition{
+		Type:    conditionType,
+		Status:  metav1.ConditionTrue,
+		Reason:  "PrerequisitesMet",
+		Message: "The prerequisites of all the hubs are met",
+	}, nil
+}
+
+// getHubPrerequisitesConditions returns the prerequisites conditions of the hub of the HubConfig,
+// they are unknown when the hub can't be reached.
+func (r *ClusterRegistrarReconciler) getHubPrerequisitesConditions(hubConfig *singaporev1alpha1.HubConfig) []metav1.Condition {
+	checker, err := r.newHubConfigPrerequisitesChecker(hubConfig)
+	if err != nil {
+		conditions := []metav1.Condition{}
+		for _, conditionType := range []string{
+			singaporev1alpha1.HubConfigConditionMultiClusterEngineAvailable,
+			singaporev1alpha1.HubConfigConditionManagedServiceAccountEnabled,
+			singaporev1alpha1.HubConfigConditionRequiredCRDsInstalled,
+		} {
+			conditions = append(conditions, metav1.Condition{
+				Type:    conditionType,
+				Status:  metav1.ConditionUnknown,
+				Reason:  "InvalidKubeConfig",
+				Message: fmt.Sprintf("Fix the kubeconfig secret %s: %s", hubConfig.Spec.KubeConfigSecretRef.Name, err.Error()),
+			})
+		}
+		return conditions
+	}
+	return checker.Check()
+}
+
+func (r *ClusterRegistrarReconciler) newHubConfigPrerequisitesChecker(hubConfig *singaporev1alpha1.HubConfig) (*helpers.HubPrerequisitesChecker, error) {
+	kubeConfig, err := r.getHubKubeConfig(hubConfig)
+	if err != nil {
+		return nil, err
+	}
+	return newHubPrerequisitesChecker(kubeConfig)
+}
+
+// getHubKubeConfig returns the kubeconfig of the hub stored in the secret of the HubConfig.
+func (r *ClusterRegistrarReconciler) getHubKubeConfig(hubConfig *singaporev1alpha1.HubConfig) ([]byte, error) {
+	secret, err := r.KubeClient.CoreV1().Secrets(hubConfig.Namespace).Get(context.TODO(),
+		hubConfig.Spec.KubeConfigSecretRef.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	kubeConfig, ok := secret.Data["kubeconfig"]
+	if !ok {
+		return nil, fmt.Errorf("secret has no kubeconfig key")
+	}
+	return kubeConfig, nil
+}
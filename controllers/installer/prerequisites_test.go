@@ -0,0 +1,140 @@
+// Copyright Red Hat
+
+package installer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiextensionsfake "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/fake"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	singaporev1alpha1 "github.com/stolostron/cluster-registration-operator/api/singapore/v1alpha1"
+	"github.com/stolostron/cluster-registration-operator/pkg/helpers"
+)
+
+// newFakeHubPrerequisitesChecker returns a checker of a hub with a supported MultiClusterEngine
+// and the required CRDs when the kubeconfig is "ready", of an empty hub otherwise.
+func newFakeHubPrerequisitesChecker(kubeConfig []byte) (*helpers.HubPrerequisitesChecker, error) {
+	objects := []runtime.Object{}
+	crds := []runtime.Object{}
+	if string(kubeConfig) == "ready" {
+		objects = append(objects, &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "multicluster.openshift.io/v1",
+			"kind":       "MultiClusterEngine",
+			"metadata":   map[string]interface{}{"name": "multiclusterengine"},
+			"spec": map[string]interface{}{
+				"overrides": map[string]interface{}{
+					"components": []interface{}{
+						map[string]interface{}{"name": "managed-service-account", "enabled": true},
+					},
+				},
+			},
+			"status": map[string]interface{}{"currentVersion": "2.0.0"},
+		}})
+		for _, name := range helpers.HubRequiredCRDNames {
+			crds = append(crds, &apiextensionsv1.CustomResourceDefinition{ObjectMeta: metav1.ObjectMeta{Name: name}})
+		}
+	}
+	return &helpers.HubPrerequisitesChecker{
+		DynamicClient: dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+			map[schema.GroupVersionResource]string{
+				helpers.MultiClusterEngineGVR: "MultiClusterEngineList",
+				helpers.MultiClusterHubGVR:    "MultiClusterHubList",
+			}, objects...),
+		APIExtensionClient: apiextensionsfake.NewSimpleClientset(crds...),
+	}, nil
+}
+
+func newPrerequisitesReconciler(t *testing.T, hubKubeConfigs map[string]string) *ClusterRegistrarReconciler {
+	newHubPrerequisitesChecker = newFakeHubPrerequisitesChecker
+	t.Cleanup(func() { newHubPrerequisitesChecker = helpers.NewHubPrerequisitesChecker })
+
+	objects := []client.Object{}
+	secrets := []runtime.Object{}
+	for name, kubeConfig := range hubKubeConfigs {
+		objects = append(objects, &singaporev1alpha1.HubConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: installationNamespace},
+			Spec: singaporev1alpha1.HubConfigSpec{
+				KubeConfigSecretRef: corev1.LocalObjectReference{Name: name + "-kubeconfig"},
+			},
+		})
+		secrets = append(secrets, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: name + "-kubeconfig", Namespace: installationNamespace},
+			Data:       map[string][]byte{"kubeconfig": []byte(kubeConfig)},
+		})
+	}
+	r, _ := newStatusReconciler(t, nil, objects...)
+	r.KubeClient = kubefake.NewSimpleClientset(secrets...)
+	return r
+}
+
+func TestCheckHubPrerequisites(t *testing.T) {
+	r := newPrerequisitesReconciler(t, map[string]string{"hub-1": "ready", "hub-2": "empty"})
+
+	condition, err := r.checkHubPrerequisites()
+	if err != nil {
+		t.Fatalf("Failed to check the hub prerequisites: %s", err)
+	}
+	checkCondition(t, []metav1.Condition{condition}, singaporev1alpha1.ClusterRegistrarConditionHubPrerequisitesMet, metav1.ConditionFalse, "PrerequisitesNotMet")
+
+	hubConfig := &singaporev1alpha1.HubConfig{}
+	if err := r.Client.Get(context.TODO(), client.ObjectKey{Name: "hub-1", Namespace: installationNamespace}, hubConfig); err != nil {
+		t.Fatalf("Failed to get HubConfig: %s", err)
+	}
+	checkCondition(t, hubConfig.Status.Conditions, singaporev1alpha1.HubConfigConditionManagedServiceAccountEnabled, metav1.ConditionTrue, "Enabled")
+
+	if err := r.Client.Get(context.TODO(), client.ObjectKey{Name: "hub-2", Namespace: installationNamespace}, hubConfig); err != nil {
+		t.Fatalf("Failed to get HubConfig: %s", err)
+	}
+	checkCondition(t, hubConfig.Status.Conditions, singaporev1alpha1.HubConfigConditionMultiClusterEngineAvailable, metav1.ConditionFalse, "NotFound")
+	checkCondition(t, hubConfig.Status.Conditions, singaporev1alpha1.HubConfigConditionRequiredCRDsInstalled, metav1.ConditionFalse, "NotFound")
+}
+
+func TestCheckHubPrerequisitesMet(t *testing.T) {
+	r := newPrerequisitesReconciler(t, map[string]string{"hub-1": "ready"})
+
+	condition, err := r.checkHubPrerequisites()
+	if err != nil {
+		t.Fatalf("Failed to check the hub prerequisites: %s", err)
+	}
+	checkCondition(t, []metav1.Condition{condition}, singaporev1alpha1.ClusterRegistrarConditionHubPrerequisitesMet, metav1.ConditionTrue, "PrerequisitesMet")
+}
+
+func TestCheckHubPrerequisitesNoHubConfig(t *testing.T) {
+	r := newPrerequisitesReconciler(t, nil)
+
+	condition, err := r.checkHubPrerequisites()
+	if err != nil {
+		t.Fatalf("Failed to check the hub prerequisites: %s", err)
+	}
+	checkCondition(t, []metav1.Condition{condition}, singaporev1alpha1.ClusterRegistrarConditionHubPrerequisitesMet, metav1.ConditionFalse, "NoHubConfig")
+}
+
+func TestGetHubPrerequisitesConditionPeriod(t *testing.T) {
+	r := newPrerequisitesReconciler(t, map[string]string{"hub-1": "ready"})
+
+	condition := r.getHubPrerequisitesCondition()
+	checkCondition(t, []metav1.Condition{condition}, singaporev1alpha1.ClusterRegistrarConditionHubPrerequisitesMet, metav1.ConditionTrue, "PrerequisitesMet")
+
+	// The hubs are not checked again before the end of the period
+	hubConfig := &singaporev1alpha1.HubConfig{ObjectMeta: metav1.ObjectMeta{Name: "hub-1", Namespace: installationNamespace}}
+	if err := r.Client.Delete(context.TODO(), hubConfig); err != nil {
+		t.Fatalf("Failed to delete HubConfig: %s", err)
+	}
+	condition = r.getHubPrerequisitesCondition()
+	checkCondition(t, []metav1.Condition{condition}, singaporev1alpha1.ClusterRegistrarConditionHubPrerequisitesMet, metav1.ConditionTrue, "PrerequisitesMet")
+
+	r.hubPrerequisitesCheckTime = time.Now().Add(-hubPrerequisitesCheckPeriod)
+	condition = r.getHubPrerequisitesCondition()
+	checkCondition(t, []metav1.Condition{condition}, singaporev1alpha1.ClusterRegistrarConditionHubPrerequisitesMet, metav1.ConditionFalse, "NoHubConfig")
+}
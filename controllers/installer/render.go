@@ -0,0 +1,80 @@
+// Copyright Red Hat
+
+package installer
+
+import (
+	"github.com/ghodss/yaml"
+	giterrors "github.com/pkg/errors"
+
+	singaporev1alpha1 "github.com/stolostron/cluster-registration-operator/api/singapore/v1alpha1"
+	clusterregistrarconfig "github.com/stolostron/cluster-registration-operator/config"
+	"github.com/stolostron/cluster-registration-operator/deploy"
+	clusteradmapply "open-cluster-management.io/clusteradm/pkg/helpers/apply"
+	clusteradmasset "open-cluster-management.io/clusteradm/pkg/helpers/asset"
+)
+
+// Manifest is a manifest rendered offline.
+type Manifest struct {
+	// File is the path of the template in the config or deploy directory.
+	File string
+	// Data is the YAML of the object.
+	Data []byte
+}
+
+// RenderOptions are the values of the installation which are read from the cluster when installing live.
+type RenderOptions struct {
+	// Image is the default image of the components, the image of the installer pod when installing live.
+	Image string
+	// Namespace is the installation namespace, the namespace of the installer pod when installing live.
+	Namespace string
+	// CABundle is the PEM CA bundle of the webhook serving certificate injected in the APIServices
+	// and KubeAPIServerCABundle the PEM CA bundle of the kube-apiserver injected in the webhook configurations,
+	// they are generated by the installer when the certificates are self-signed.
+	CABundle              []byte
+	KubeAPIServerCABundle []byte
+}
+
+// RenderManifests renders the CRDs and the manifests the installer applies for the ClusterRegistrar,
+// in the order they are applied, without connecting to a cluster.
+func RenderManifests(clusterRegistrar *singaporev1alpha1.ClusterRegistrar, options RenderOptions) ([]Manifest, error) {
+	values, err := newValues(clusterRegistrar, options.Image, options.Namespace)
+	if err != nil {
+		return nil, giterrors.WithStack(err)
+	}
+	if values.CertificateProvider == string(singaporev1alpha1.CertificateProviderSelfSigned) {
+		values.setWebhookCertificates(&webhookCertificates{
+			caBundle:              options.CABundle,
+			kubeAPIServerCABundle: options.KubeAPIServerCABundle,
+		})
+	}
+
+	applier := clusteradmapply.NewApplierBuilder().Build()
+	crdManifests, err := renderManifests(applier, clusterregistrarconfig.GetScenarioResourcesReader(), nil, crdFiles...)
+	if err != nil {
+		return nil, err
+	}
+	manifests, err := renderManifests(applier, deploy.GetScenarioResourcesReader(), values, installFiles()...)
+	if err != nil {
+		return nil, err
+	}
+	return append(crdManifests, manifests...), nil
+}
+
+func renderManifests(applier clusteradmapply.Applier,
+	reader clusteradmasset.ScenarioReader,
+	values interface{},
+	files ...string) ([]Manifest, error) {
+	manifests := make([]Manifest, 0, len(files))
+	for _, file := range files {
+		obj, err := renderManagedManifest(applier, reader, values, file)
+		if err != nil {
+			return nil, err
+		}
+		b, err := yaml.Marshal(obj.Object)
+		if err != nil {
+			return nil, giterrors.WithStack(err)
+		}
+		manifests = append(manifests, Manifest{File: file, Data: b})
+	}
+	return manifests, nil
+}
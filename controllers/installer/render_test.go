@@ -0,0 +1,83 @@
+// Copyright Red Hat
+
+package installer
+
+import (
+	"testing"
+
+	"github.com/ghodss/yaml"
+	appsv1 "k8s.io/api/apps/v1"
+	apiregistrationv1 "k8s.io/kube-aggregator/pkg/apis/apiregistration/v1"
+
+	singaporev1alpha1 "github.com/stolostron/cluster-registration-operator/api/singapore/v1alpha1"
+)
+
+func renderAPIService(t *testing.T, clusterRegistrar *singaporev1alpha1.ClusterRegistrar, options RenderOptions) *apiregistrationv1.APIService {
+	manifests, err := RenderManifests(clusterRegistrar, options)
+	if err != nil {
+		t.Fatalf("Failed to render: %s", err)
+	}
+	if len(manifests) != len(crdFiles)+len(installFiles()) {
+		t.Fatalf("Manifests not as expected: %d", len(manifests))
+	}
+	for _, manifest := range manifests {
+		if manifest.File != apiServiceFiles[0] {
+			continue
+		}
+		apiService := &apiregistrationv1.APIService{}
+		if err := yaml.Unmarshal(manifest.Data, apiService); err != nil {
+			t.Fatalf("Failed to unmarshal %s: %s\n%s", manifest.File, err, string(manifest.Data))
+		}
+		if apiService.Labels[ManagedByLabel] != ManagedByLabelValue {
+			t.Fatalf("Managed by label not set: %v", apiService.Labels)
+		}
+		return apiService
+	}
+	t.Fatalf("APIService not rendered")
+	return nil
+}
+
+func TestRenderManifests(t *testing.T) {
+	manifests, err := RenderManifests(&singaporev1alpha1.ClusterRegistrar{}, RenderOptions{Image: "installer-image", Namespace: "gitops"})
+	if err != nil {
+		t.Fatalf("Failed to render: %s", err)
+	}
+	if manifests[0].File != crdFiles[0] {
+		t.Fatalf("CRDs not rendered first: %s", manifests[0].File)
+	}
+	for _, manifest := range manifests {
+		if manifest.File != managerDeploymentFiles[0] {
+			continue
+		}
+		deployment := &appsv1.Deployment{}
+		if err := yaml.Unmarshal(manifest.Data, deployment); err != nil {
+			t.Fatalf("Failed to unmarshal %s: %s", manifest.File, err)
+		}
+		if deployment.Namespace != "gitops" {
+			t.Fatalf("Namespace not as expected. Expected %s, actual %s", "gitops", deployment.Namespace)
+		}
+		if deployment.Spec.Template.Spec.Containers[0].Image != "installer-image" {
+			t.Fatalf("Image not as expected. Expected %s, actual %s", "installer-image", deployment.Spec.Template.Spec.Containers[0].Image)
+		}
+	}
+}
+
+func TestRenderManifestsCABundle(t *testing.T) {
+	apiService := renderAPIService(t, &singaporev1alpha1.ClusterRegistrar{},
+		RenderOptions{Image: "installer-image", Namespace: "gitops", CABundle: []byte("ca")})
+	if string(apiService.Spec.CABundle) != "ca" {
+		t.Fatalf("CA bundle not injected: %q", apiService.Spec.CABundle)
+	}
+
+	clusterRegistrar := &singaporev1alpha1.ClusterRegistrar{
+		Spec: singaporev1alpha1.ClusterRegistrarSpec{CertificateProvider: singaporev1alpha1.CertificateProviderServiceCA},
+	}
+	apiService = renderAPIService(t, clusterRegistrar,
+		RenderOptions{Image: "installer-image", Namespace: "gitops", CABundle: []byte("ca")})
+	if len(apiService.Spec.CABundle) != 0 {
+		t.Fatalf("CA bundle injected with the service CA: %q", apiService.Spec.CABundle)
+	}
+	if _, ok := apiService.Annotations["service.beta.openshift.io/inject-cabundle"]; !ok {
+		t.Fatalf("Service CA annotation not set: %v", apiService.Annotations)
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright Red Hat
+
+package installer
+
+import (
+	"context"
+	"fmt"
+
+	giterrors "github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	singaporev1alpha1 "github.com/stolostron/cluster-registration-operator/api/singapore/v1alpha1"
+	"github.com/stolostron/cluster-registration-operator/pkg/helpers"
+)
+
+// clusterRegistrarReasonDuplicate is the reason of the Ready condition of the ClusterRegistrars which are not installed.
+const clusterRegistrarReasonDuplicate string = "Duplicate"
+
+// getActiveClusterRegistrar returns the ClusterRegistrar the installation is managed by, the oldest one of the cluster.
+func (r *ClusterRegistrarReconciler) getActiveClusterRegistrar() (*singaporev1alpha1.ClusterRegistrar, error) {
+	clusterRegistrarList := &singaporev1alpha1.ClusterRegistrarList{}
+	if err := r.Client.List(context.TODO(), clusterRegistrarList); err != nil {
+		return nil, giterrors.WithStack(err)
+	}
+	return helpers.GetActiveClusterRegistrar(clusterRegistrarList.Items), nil
+}
+
+// processDuplicateClusterRegistrar sets the Ready condition of a ClusterRegistrar which is not the active one.
+// It is never installed or uninstalled, its finalizer is removed so its deletion doesn't uninstall the active one.
+func (r *ClusterRegistrarReconciler) processDuplicateClusterRegistrar(clusterRegistrar, active *singaporev1alpha1.ClusterRegistrar) error {
+	if controllerutil.ContainsFinalizer(clusterRegistrar, helpers.ClusterRegistrarFinalizer) {
+		controllerutil.RemoveFinalizer(clusterRegistrar, helpers.ClusterRegistrarFinalizer)
+		if err := r.Client.Update(context.TODO(), clusterRegistrar); err != nil {
+			return giterrors.WithStack(err)
+		}
+	}
+	if clusterRegistrar.DeletionTimestamp != nil {
+		return nil
+	}
+	if condition := meta.FindStatusCondition(clusterRegistrar.Status.Conditions, singaporev1alpha1.ClusterRegistrarConditionReady); condition != nil &&
+		condition.Reason == clusterRegistrarReasonDuplicate {
+		return nil
+	}
+
+	patch := client.MergeFrom(clusterRegistrar.DeepCopy())
+	clusterRegistrar.Status.Conditions = helpers.MergeStatusConditions(clusterRegistrar.Status.Conditions, metav1.Condition{
+		Type:    singaporev1alpha1.ClusterRegistrarConditionReady,
+		Status:  metav1.ConditionFalse,
+		Reason:  clusterRegistrarReasonDuplicate,
+		Message: fmt.Sprintf("Only one ClusterRegistrar is allowed per cluster, %s/%s is installed", active.Namespace, active.Name),
+	})
+	clusterRegistrar.Status.ObservedGeneration = clusterRegistrar.Generation
+	if err := r.Client.Status().Patch(context.TODO(), clusterRegistrar, patch); err != nil {
+		return giterrors.WithStack(err)
+	}
+	r.Recorder.Eventf(clusterRegistrar, corev1.EventTypeWarning, EventReasonDuplicate,
+		"Only one ClusterRegistrar is allowed per cluster, %s/%s is installed", active.Namespace, active.Name)
+	return nil
+}
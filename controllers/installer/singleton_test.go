@@ -0,0 +1,49 @@
+// Copyright Red Hat
+
+package installer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	singaporev1alpha1 "github.com/stolostron/cluster-registration-operator/api/singapore/v1alpha1"
+	"github.com/stolostron/cluster-registration-operator/pkg/helpers"
+)
+
+func TestProcessDuplicateClusterRegistrar(t *testing.T) {
+	duplicate := &singaporev1alpha1.ClusterRegistrar{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "duplicate",
+			Namespace:         "other-namespace",
+			CreationTimestamp: metav1.NewTime(time.Now()),
+			Finalizers:        []string{helpers.ClusterRegistrarFinalizer},
+		},
+	}
+	r, active := newStatusReconciler(t, newEstablishedCRDs(), duplicate)
+
+	got, err := r.getActiveClusterRegistrar()
+	if err != nil {
+		t.Fatalf("Failed to get the active ClusterRegistrar: %s", err)
+	}
+	if got.Name != active.Name || got.Namespace != active.Namespace {
+		t.Fatalf(`Active ClusterRegistrar not as expected. Expected %s, actual %s`, active.Name, got.Name)
+	}
+
+	if err := r.processDuplicateClusterRegistrar(duplicate, got); err != nil {
+		t.Fatalf("Failed to process the duplicate ClusterRegistrar: %s", err)
+	}
+	updated := &singaporev1alpha1.ClusterRegistrar{}
+	if err := r.Client.Get(context.TODO(), client.ObjectKeyFromObject(duplicate), updated); err != nil {
+		t.Fatalf("Failed to get ClusterRegistrar: %s", err)
+	}
+	// The finalizer is removed so the deletion of the duplicate doesn't uninstall the active ClusterRegistrar
+	if len(updated.Finalizers) != 0 {
+		t.Fatalf(`Finalizers not expected: %v`, updated.Finalizers)
+	}
+	checkCondition(t, updated.Status.Conditions, singaporev1alpha1.ClusterRegistrarConditionReady, metav1.ConditionFalse, clusterRegistrarReasonDuplicate)
+	checkEvent(t, r.Recorder, EventReasonDuplicate)
+}
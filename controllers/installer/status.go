@@ -0,0 +1,293 @@
+// Copyright Red Hat
+
+package installer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	giterrors "github.com/pkg/errors"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	apiregistrationv1 "k8s.io/kube-aggregator/pkg/apis/apiregistration/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	singaporev1alpha1 "github.com/stolostron/cluster-registration-operator/api/singapore/v1alpha1"
+	"github.com/stolostron/cluster-registration-operator/pkg/helpers"
+	"github.com/stolostron/cluster-registration-operator/pkg/version"
+)
+
+// installRequeuePeriod is the period the installation is checked at until the ClusterRegistrar is ready.
+const installRequeuePeriod = 10 * time.Second
+
+const (
+	managerDeploymentName string = "cluster-registration-operator-manager"
+	webhookDeploymentName string = "cluster-registration-webhook-service"
+)
+
+var (
+	installedCRDNames = []string{
+		"clusterregistrars.singapore.open-cluster-management.io",
+		"registeredclusters.singapore.open-cluster-management.io",
+		"hubconfigs.singapore.open-cluster-management.io",
+	}
+
+	apiServiceNames = []string{
+		"v1alpha1.admission.singapore.open-cluster-management.io",
+		"v1.admission.singapore.open-cluster-management.io",
+	}
+)
+
+// updateClusterRegistrarStatus sets the conditions of the ClusterRegistrar from the installed resources
+// and returns true when it is ready. installErr is the error returned by the installation, if any.
+func (r *ClusterRegistrarReconciler) updateClusterRegistrarStatus(clusterRegistrar *singaporev1alpha1.ClusterRegistrar, installErr error) (bool, error) {
+	patch := client.MergeFrom(clusterRegistrar.DeepCopy())
+
+	conditions := []metav1.Condition{
+		r.getCRDsInstalledCondition(),
+		r.getDeploymentAvailableCondition(singaporev1alpha1.ClusterRegistrarConditionManagerAvailable, managerDeploymentName),
+		r.getDeploymentAvailableCondition(singaporev1alpha1.ClusterRegistrarConditionWebhookAvailable, webhookDeploymentName),
+		r.getAPIServiceAvailableCondition(),
+	}
+	ready := getReadyCondition(conditions, installErr)
+	conditions = append(conditions, ready,
+		getUpgradingCondition(clusterRegistrar.Status.InstalledVersion, ready.Status == metav1.ConditionTrue, installErr))
+
+	// The hub prerequisites don't block the installation, they are needed to onboard the hubs
+	conditions = append(conditions, r.getHubPrerequisitesCondition())
+
+	clusterRegistrar.Status.Conditions = helpers.MergeStatusConditions(clusterRegistrar.Status.Conditions, conditions...)
+	clusterRegistrar.Status.ObservedGeneration = clusterRegistrar.Generation
+	installedVersion := clusterRegistrar.Status.InstalledVersion
+	if ready.Status == metav1.ConditionTrue {
+		clusterRegistrar.Status.InstalledVersion = version.Version
+	}
+
+	if err := r.Client.Status().Patch(context.TODO(), clusterRegistrar, patch); err != nil {
+		return false, giterrors.WithStack(err)
+	}
+	switch {
+	case installedVersion == clusterRegistrar.Status.InstalledVersion:
+	case len(installedVersion) == 0:
+		r.Recorder.Eventf(clusterRegistrar, corev1.EventTypeNormal, EventReasonInstalled, "Installed version %s", version.Version)
+	default:
+		r.Recorder.Eventf(clusterRegistrar, corev1.EventTypeNormal, EventReasonUpgraded, "Upgraded from version %s to %s", installedVersion, version.Version)
+	}
+	return ready.Status == metav1.ConditionTrue, nil
+}
+
+func (r *ClusterRegistrarReconciler) getCRDsInstalledCondition() metav1.Condition {
+	condition := metav1.Condition{
+		Type:    singaporev1alpha1.ClusterRegistrarConditionCRDsInstalled,
+		Status:  metav1.ConditionTrue,
+		Reason:  "Established",
+		Message: "The CRDs are established",
+	}
+	var notEstablished []string
+	for _, name := range installedCRDNames {
+		crd, err := r.APIExtensionClient.ApiextensionsV1().CustomResourceDefinitions().Get(context.TODO(), name, metav1.GetOptions{})
+		if err != nil {
+			return newErrorCondition(condition.Type, "CustomResourceDefinition", name, err)
+		}
+		if !isCRDEstablished(crd) {
+			notEstablished = append(notEstablished, name)
+		}
+	}
+	if len(notEstablished) != 0 {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "NotEstablished"
+		condition.Message = fmt.Sprintf("The CRDs %s are not established", strings.Join(notEstablished, ", "))
+	}
+	return condition
+}
+
+func isCRDEstablished(crd *apiextensionsv1.CustomResourceDefinition) bool {
+	for _, condition := range crd.Status.Conditions {
+		if condition.Type == apiextensionsv1.Established {
+			return condition.Status == apiextensionsv1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// getDeploymentAvailableCondition returns a true condition when the deployment is rolled out and available.
+func (r *ClusterRegistrarReconciler) getDeploymentAvailableCondition(conditionType, name string) metav1.Condition {
+	deployment := &appsv1.Deployment{}
+	if err := r.Client.Get(context.TODO(), client.ObjectKey{Name: name, Namespace: podNamespace}, deployment); err != nil {
+		return newErrorCondition(conditionType, "Deployment", name, err)
+	}
+
+	if !isDeploymentRolledOut(deployment) {
+		return metav1.Condition{
+			Type:   conditionType,
+			Status: metav1.ConditionFalse,
+			Reason: "RollingOut",
+			Message: fmt.Sprintf("Deployment %s/%s: %d of %d updated replicas are available",
+				podNamespace, name, deployment.Status.AvailableReplicas, getDeploymentReplicas(deployment)),
+		}
+	}
+
+	for _, condition := range deployment.Status.Conditions {
+		if condition.Type != appsv1.DeploymentAvailable {
+			continue
+		}
+		if condition.Status != corev1.ConditionTrue {
+			return metav1.Condition{
+				Type:    conditionType,
+				Status:  metav1.ConditionFalse,
+				Reason:  condition.Reason,
+				Message: fmt.Sprintf("Deployment %s/%s: %s", podNamespace, name, condition.Message),
+			}
+		}
+		return metav1.Condition{
+			Type:    conditionType,
+			Status:  metav1.ConditionTrue,
+			Reason:  "Available",
+			Message: fmt.Sprintf("Deployment %s/%s is available", podNamespace, name),
+		}
+	}
+	return metav1.Condition{
+		Type:    conditionType,
+		Status:  metav1.ConditionFalse,
+		Reason:  "Unavailable",
+		Message: fmt.Sprintf("Deployment %s/%s has no %s condition", podNamespace, name, appsv1.DeploymentAvailable),
+	}
+}
+
+// getDeploymentReplicas returns the desired replicas of the deployment.
+func getDeploymentReplicas(deployment *appsv1.Deployment) int32 {
+	if deployment.Spec.Replicas != nil {
+		return *deployment.Spec.Replicas
+	}
+	return 1
+}
+
+// isDeploymentRolledOut returns true when the replicas of the last generation of the deployment are all updated and available.
+func isDeploymentRolledOut(deployment *appsv1.Deployment) bool {
+	return deployment.Status.ObservedGeneration >= deployment.Generation &&
+		deployment.Status.UpdatedReplicas >= getDeploymentReplicas(deployment) &&
+		deployment.Status.AvailableReplicas >= deployment.Status.UpdatedReplicas
+}
+
+func (r *ClusterRegistrarReconciler) getAPIServiceAvailableCondition() metav1.Condition {
+	conditionType := singaporev1alpha1.ClusterRegistrarConditionAPIServiceAvailable
+	for _, name := range apiServiceNames {
+		apiService := &apiregistrationv1.APIService{}
+		if err := r.Client.Get(context.TODO(), client.ObjectKey{Name: name}, apiService); err != nil {
+			return newErrorCondition(conditionType, "APIService", name, err)
+		}
+		available := false
+		for _, condition := range apiService.Status.Conditions {
+			if condition.Type != apiregistrationv1.Available {
+				continue
+			}
+			if condition.Status != apiregistrationv1.ConditionTrue {
+				return metav1.Condition{
+					Type:    conditionType,
+					Status:  metav1.ConditionFalse,
+					Reason:  condition.Reason,
+					Message: fmt.Sprintf("APIService %s: %s", name, condition.Message),
+				}
+			}
+			available = true
+		}
+		if !available {
+			return metav1.Condition{
+				Type:    conditionType,
+				Status:  metav1.ConditionFalse,
+				Reason:  "Unavailable",
+				Message: fmt.Sprintf("APIService %s has no %s condition", name, apiregistrationv1.Available),
+			}
+		}
+	}
+	return metav1.Condition{
+		Type:    conditionType,
+		Status:  metav1.ConditionTrue,
+		Reason:  "Available",
+		Message: "The admission APIServices are available",
+	}
+}
+
+// getReadyCondition returns a true condition when the installation succeeded and all the conditions are true.
+func getReadyCondition(conditions []metav1.Condition, installErr error) metav1.Condition {
+	if installErr != nil {
+		return metav1.Condition{
+			Type:    singaporev1alpha1.ClusterRegistrarConditionReady,
+			Status:  metav1.ConditionFalse,
+			Reason:  "InstallFailed",
+			Message: installErr.Error(),
+		}
+	}
+	var notReady []string
+	for _, condition := range conditions {
+		if condition.Status != metav1.ConditionTrue {
+			notReady = append(notReady, condition.Type)
+		}
+	}
+	if len(notReady) != 0 {
+		return metav1.Condition{
+			Type:    singaporev1alpha1.ClusterRegistrarConditionReady,
+			Status:  metav1.ConditionFalse,
+			Reason:  "Installing",
+			Message: fmt.Sprintf("Waiting for %s", strings.Join(notReady, ", ")),
+		}
+	}
+	return metav1.Condition{
+		Type:    singaporev1alpha1.ClusterRegistrarConditionReady,
+		Status:  metav1.ConditionTrue,
+		Reason:  "Installed",
+		Message: fmt.Sprintf("Version %s is installed", version.Version),
+	}
+}
+
+// getUpgradingCondition returns a true condition while the current version is installed over another one.
+func getUpgradingCondition(installedVersion string, ready bool, installErr error) metav1.Condition {
+	switch {
+	case installedVersion == version.Version || (len(installedVersion) != 0 && ready):
+		return metav1.Condition{
+			Type:    singaporev1alpha1.ClusterRegistrarConditionUpgrading,
+			Status:  metav1.ConditionFalse,
+			Reason:  "UpToDate",
+			Message: fmt.Sprintf("Version %s is installed", version.Version),
+		}
+	case len(installedVersion) == 0:
+		return metav1.Condition{
+			Type:    singaporev1alpha1.ClusterRegistrarConditionUpgrading,
+			Status:  metav1.ConditionFalse,
+			Reason:  "Installing",
+			Message: fmt.Sprintf("Installing version %s", version.Version),
+		}
+	case installErr != nil:
+		return metav1.Condition{
+			Type:    singaporev1alpha1.ClusterRegistrarConditionUpgrading,
+			Status:  metav1.ConditionTrue,
+			Reason:  "UpgradeFailed",
+			Message: fmt.Sprintf("Upgrade from %s to %s failed: %s", installedVersion, version.Version, installErr.Error()),
+		}
+	}
+	return metav1.Condition{
+		Type:    singaporev1alpha1.ClusterRegistrarConditionUpgrading,
+		Status:  metav1.ConditionTrue,
+		Reason:  "Upgrading",
+		Message: fmt.Sprintf("Upgrading from %s to %s", installedVersion, version.Version),
+	}
+}
+
+func newErrorCondition(conditionType, kind, name string, err error) metav1.Condition {
+	reason := "Error"
+	if errors.IsNotFound(err) {
+		reason = "NotFound"
+	}
+	return metav1.Condition{
+		Type:    conditionType,
+		Status:  metav1.ConditionFalse,
+		Reason:  reason,
+		Message: fmt.Sprintf("%s %s: %s", kind, name, err.Error()),
+	}
+}
@@ -0,0 +1,222 @@
+// Copyright Red Hat
+
+package installer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiextensionsfake "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/fake"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	apiregistrationv1 "k8s.io/kube-aggregator/pkg/apis/apiregistration/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	singaporev1alpha1 "github.com/stolostron/cluster-registration-operator/api/singapore/v1alpha1"
+	"github.com/stolostron/cluster-registration-operator/pkg/version"
+)
+
+func newEstablishedCRDs() []runtime.Object {
+	var crds []runtime.Object
+	for _, name := range installedCRDNames {
+		crds = append(crds, &apiextensionsv1.CustomResourceDefinition{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status: apiextensionsv1.CustomResourceDefinitionStatus{
+				Conditions: []apiextensionsv1.CustomResourceDefinitionCondition{
+					{Type: apiextensionsv1.Established, Status: apiextensionsv1.ConditionTrue},
+				},
+			},
+		})
+	}
+	return crds
+}
+
+func newAvailableDeployment(name string) *appsv1.Deployment {
+	replicas := int32(1)
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: installationNamespace},
+		Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+		Status: appsv1.DeploymentStatus{
+			UpdatedReplicas:   1,
+			AvailableReplicas: 1,
+			Conditions: []appsv1.DeploymentCondition{
+				{Type: appsv1.DeploymentAvailable, Status: corev1.ConditionTrue},
+			},
+		},
+	}
+}
+
+func newAvailableAPIServices() []client.Object {
+	var apiServices []client.Object
+	for _, name := range apiServiceNames {
+		apiServices = append(apiServices, &apiregistrationv1.APIService{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status: apiregistrationv1.APIServiceStatus{
+				Conditions: []apiregistrationv1.APIServiceCondition{
+					{Type: apiregistrationv1.Available, Status: apiregistrationv1.ConditionTrue},
+				},
+			},
+		})
+	}
+	return apiServices
+}
+
+func newStatusReconciler(t *testing.T, crds []runtime.Object, objects ...client.Object) (*ClusterRegistrarReconciler, *singaporev1alpha1.ClusterRegistrar) {
+	podNamespace = installationNamespace
+	s := runtime.NewScheme()
+	for _, addToScheme := range []func(*runtime.Scheme) error{
+		appsv1.AddToScheme, apiregistrationv1.AddToScheme, singaporev1alpha1.AddToScheme,
+	} {
+		if err := addToScheme(s); err != nil {
+			t.Fatalf("Failed to build scheme: %s", err)
+		}
+	}
+	clusterRegistrar := &singaporev1alpha1.ClusterRegistrar{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster-registrar", Namespace: podNamespace, Generation: 2},
+	}
+	objects = append(objects, clusterRegistrar)
+	return &ClusterRegistrarReconciler{
+		Client:             fake.NewClientBuilder().WithScheme(s).WithObjects(objects...).Build(),
+		APIExtensionClient: apiextensionsfake.NewSimpleClientset(crds...),
+		Log:                logr.Discard(),
+		Scheme:             s,
+		Recorder:           record.NewFakeRecorder(10),
+	}, clusterRegistrar
+}
+
+func checkCondition(t *testing.T, conditions []metav1.Condition, conditionType string, status metav1.ConditionStatus, reason string) {
+	condition := meta.FindStatusCondition(conditions, conditionType)
+	if condition == nil {
+		t.Fatalf("Condition %s not found in %v", conditionType, conditions)
+	}
+	if condition.Status != status || condition.Reason != reason {
+		t.Fatalf(`Condition %s not as expected. Expected %s/%s, actual %s/%s: %s`,
+			conditionType, status, reason, condition.Status, condition.Reason, condition.Message)
+	}
+}
+
+func TestUpdateClusterRegistrarStatusReady(t *testing.T) {
+	objects := append(newAvailableAPIServices(),
+		newAvailableDeployment(managerDeploymentName),
+		newAvailableDeployment(webhookDeploymentName))
+	r, clusterRegistrar := newStatusReconciler(t, newEstablishedCRDs(), objects...)
+
+	ready, err := r.updateClusterRegistrarStatus(clusterRegistrar, nil)
+	if err != nil {
+		t.Fatalf("Failed to update status: %s", err)
+	}
+	if !ready {
+		t.Fatalf("ClusterRegistrar not ready: %v", clusterRegistrar.Status.Conditions)
+	}
+
+	updated := &singaporev1alpha1.ClusterRegistrar{}
+	if err := r.Client.Get(context.TODO(), client.ObjectKeyFromObject(clusterRegistrar), updated); err != nil {
+		t.Fatalf("Failed to get ClusterRegistrar: %s", err)
+	}
+	checkCondition(t, updated.Status.Conditions, singaporev1alpha1.ClusterRegistrarConditionReady, metav1.ConditionTrue, "Installed")
+	if updated.Status.InstalledVersion != version.Version {
+		t.Fatalf(`InstalledVersion not as expected. Expected %s, actual %s`, version.Version, updated.Status.InstalledVersion)
+	}
+	if updated.Status.ObservedGeneration != 2 {
+		t.Fatalf(`ObservedGeneration not as expected. Expected %d, actual %d`, 2, updated.Status.ObservedGeneration)
+	}
+	checkEvent(t, r.Recorder, EventReasonInstalled)
+}
+
+func checkEvent(t *testing.T, recorder record.EventRecorder, reason string) {
+	select {
+	case event := <-recorder.(*record.FakeRecorder).Events:
+		if !strings.Contains(event, " "+reason+" ") {
+			t.Fatalf("Event not as expected. Expected reason %s, actual %s", reason, event)
+		}
+	default:
+		t.Fatalf("Event %s not recorded", reason)
+	}
+}
+
+func TestUpdateClusterRegistrarStatusNotReady(t *testing.T) {
+	rollingOut := newAvailableDeployment(webhookDeploymentName)
+	rollingOut.Status.AvailableReplicas = 0
+	crds := newEstablishedCRDs()
+	crds[0].(*apiextensionsv1.CustomResourceDefinition).Status.Conditions = nil
+	r, clusterRegistrar := newStatusReconciler(t, crds, newAvailableDeployment(managerDeploymentName), rollingOut)
+
+	ready, err := r.updateClusterRegistrarStatus(clusterRegistrar, nil)
+	if err != nil {
+		t.Fatalf("Failed to update status: %s", err)
+	}
+	if ready {
+		t.Fatalf("ClusterRegistrar ready but expected not to be.")
+	}
+	conditions := clusterRegistrar.Status.Conditions
+	checkCondition(t, conditions, singaporev1alpha1.ClusterRegistrarConditionCRDsInstalled, metav1.ConditionFalse, "NotEstablished")
+	checkCondition(t, conditions, singaporev1alpha1.ClusterRegistrarConditionManagerAvailable, metav1.ConditionTrue, "Available")
+	checkCondition(t, conditions, singaporev1alpha1.ClusterRegistrarConditionWebhookAvailable, metav1.ConditionFalse, "RollingOut")
+	checkCondition(t, conditions, singaporev1alpha1.ClusterRegistrarConditionAPIServiceAvailable, metav1.ConditionFalse, "NotFound")
+	checkCondition(t, conditions, singaporev1alpha1.ClusterRegistrarConditionReady, metav1.ConditionFalse, "Installing")
+	if len(clusterRegistrar.Status.InstalledVersion) != 0 {
+		t.Fatalf("InstalledVersion set but expected to be empty: %s", clusterRegistrar.Status.InstalledVersion)
+	}
+}
+
+func TestUpdateClusterRegistrarStatusInstallFailed(t *testing.T) {
+	objects := append(newAvailableAPIServices(),
+		newAvailableDeployment(managerDeploymentName),
+		newAvailableDeployment(webhookDeploymentName))
+	r, clusterRegistrar := newStatusReconciler(t, newEstablishedCRDs(), objects...)
+
+	ready, err := r.updateClusterRegistrarStatus(clusterRegistrar, fmt.Errorf("apply failed"))
+	if err != nil {
+		t.Fatalf("Failed to update status: %s", err)
+	}
+	if ready {
+		t.Fatalf("ClusterRegistrar ready but expected not to be.")
+	}
+	checkCondition(t, clusterRegistrar.Status.Conditions, singaporev1alpha1.ClusterRegistrarConditionReady, metav1.ConditionFalse, "InstallFailed")
+}
+
+func TestUpdateClusterRegistrarStatusUpgrading(t *testing.T) {
+	r, clusterRegistrar := newStatusReconciler(t, newEstablishedCRDs())
+	clusterRegistrar.Status.InstalledVersion = "0.0.1"
+	if err := r.Client.Status().Update(context.TODO(), clusterRegistrar); err != nil {
+		t.Fatalf("Failed to update status: %s", err)
+	}
+
+	if _, err := r.updateClusterRegistrarStatus(clusterRegistrar, nil); err != nil {
+		t.Fatalf("Failed to update status: %s", err)
+	}
+	checkCondition(t, clusterRegistrar.Status.Conditions, singaporev1alpha1.ClusterRegistrarConditionUpgrading, metav1.ConditionTrue, "Upgrading")
+	if clusterRegistrar.Status.InstalledVersion != "0.0.1" {
+		t.Fatalf(`InstalledVersion not as expected. Expected %s, actual %s`, "0.0.1", clusterRegistrar.Status.InstalledVersion)
+	}
+
+	if _, err := r.updateClusterRegistrarStatus(clusterRegistrar, fmt.Errorf("migration failed")); err != nil {
+		t.Fatalf("Failed to update status: %s", err)
+	}
+	checkCondition(t, clusterRegistrar.Status.Conditions, singaporev1alpha1.ClusterRegistrarConditionUpgrading, metav1.ConditionTrue, "UpgradeFailed")
+
+	objects := append(newAvailableAPIServices(),
+		newAvailableDeployment(managerDeploymentName),
+		newAvailableDeployment(webhookDeploymentName))
+	for _, obj := range objects {
+		if err := r.Client.Create(context.TODO(), obj); err != nil {
+			t.Fatalf("Failed to create %T: %s", obj, err)
+		}
+	}
+	if _, err := r.updateClusterRegistrarStatus(clusterRegistrar, nil); err != nil {
+		t.Fatalf("Failed to update status: %s", err)
+	}
+	checkCondition(t, clusterRegistrar.Status.Conditions, singaporev1alpha1.ClusterRegistrarConditionUpgrading, metav1.ConditionFalse, "UpToDate")
+	if clusterRegistrar.Status.InstalledVersion != version.Version {
+		t.Fatalf(`InstalledVersion not as expected. Expected %s, actual %s`, version.Version, clusterRegistrar.Status.InstalledVersion)
+	}
+}
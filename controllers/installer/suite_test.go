@@ -124,6 +124,7 @@ var _ = BeforeSuite(func() {
 			APIExtensionClient: apiextensionsclient.NewForConfigOrDie(cfg),
 			Log:                logf.Log,
 			Scheme:             scheme.Scheme,
+			Recorder:           mgr.GetEventRecorderFor("cluster-registration-installer"),
 		}
 		err := r.SetupWithManager(mgr)
 		Expect(err).To(BeNil())
@@ -0,0 +1,129 @@
+// Copyright Red Hat
+
+package installer
+
+import (
+	"context"
+	"fmt"
+
+	giterrors "github.com/pkg/errors"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	singaporev1alpha1 "github.com/stolostron/cluster-registration-operator/api/singapore/v1alpha1"
+	"github.com/stolostron/cluster-registration-operator/deploy"
+	"github.com/stolostron/cluster-registration-operator/pkg/helpers"
+	clusteradmapply "open-cluster-management.io/clusteradm/pkg/helpers/apply"
+	clusteradmasset "open-cluster-management.io/clusteradm/pkg/helpers/asset"
+)
+
+// processClusterRegistrarDeletion applies the deletion policy to the RegisteredClusters, then deletes the objects
+// described by the manifests of the deploy directory, in the reverse order of their creation, and the CRDs if requested.
+// It returns false when the uninstallation waits for the RegisteredClusters to be deleted or detached.
+func (r *ClusterRegistrarReconciler) processClusterRegistrarDeletion(clusterRegistrar *singaporev1alpha1.ClusterRegistrar) (bool, error) {
+	r.Log.Info("processClusterRegistrarDeletion", "Name", clusterRegistrar.Name, "Namespace", clusterRegistrar.Namespace)
+
+	switch {
+	case clusterRegistrar.Annotations[singaporev1alpha1.ForceUninstallAnnotation] == "true":
+		r.Log.Info("Force uninstall", "name", clusterRegistrar.Name, "namespace", clusterRegistrar.Namespace)
+	case clusterRegistrar.Spec.DeletionPolicy == singaporev1alpha1.DeletionPolicyRetain:
+		r.Log.Info("Retain the RegisteredClusters", "name", clusterRegistrar.Name, "namespace", clusterRegistrar.Namespace)
+	case clusterRegistrar.Spec.DeletionPolicy == singaporev1alpha1.DeletionPolicyDetach:
+		detached, err := r.detachRegisteredClusters(clusterRegistrar)
+		if err != nil || !detached {
+			return false, err
+		}
+	default:
+		blocked, err := r.isUninstallBlocked(clusterRegistrar)
+		if err != nil || blocked {
+			return false, err
+		}
+	}
+
+	values, err := newValues(clusterRegistrar, "", podNamespace)
+	if err != nil {
+		return false, giterrors.WithStack(err)
+	}
+
+	applierBuilder := &clusteradmapply.ApplierBuilder{}
+	applier := applierBuilder.WithClient(r.KubeClient, r.APIExtensionClient, r.DynamicClient).Build()
+	readerDeploy := deploy.GetScenarioResourcesReader()
+
+	files := installFiles()
+	for i := len(files) - 1; i >= 0; i-- {
+		if err := r.deleteManifest(applier, readerDeploy, values, files[i]); err != nil {
+			return false, err
+		}
+	}
+
+	for _, name := range []string{webhookServingSecretName, webhookCASecretName} {
+		r.Log.Info("Delete Secret", "name", name, "namespace", podNamespace)
+		err := r.KubeClient.CoreV1().Secrets(podNamespace).Delete(context.TODO(), name, metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return false, giterrors.WithStack(err)
+		}
+	}
+
+	// The removal of the CRDs would delete the retained RegisteredClusters, the webhook denies the combination
+	if clusterRegistrar.Spec.RemoveCRDs && clusterRegistrar.Spec.DeletionPolicy == singaporev1alpha1.DeletionPolicyRetain {
+		r.Log.Info("Keep the CRDs to retain the RegisteredClusters", "name", clusterRegistrar.Name, "namespace", clusterRegistrar.Namespace)
+	} else if clusterRegistrar.Spec.RemoveCRDs {
+		for _, name := range installedCRDNames {
+			r.Log.Info("Delete CustomResourceDefinition", "name", name)
+			err := r.APIExtensionClient.ApiextensionsV1().CustomResourceDefinitions().Delete(context.TODO(), name, metav1.DeleteOptions{})
+			if err != nil && !errors.IsNotFound(err) {
+				return false, giterrors.WithStack(err)
+			}
+		}
+	}
+
+	return true, nil
+}
+
+// deleteManifest deletes the object described by the rendered manifest, if it exists.
+func (r *ClusterRegistrarReconciler) deleteManifest(applier clusteradmapply.Applier,
+	reader clusteradmasset.ScenarioReader,
+	values *Values,
+	file string) error {
+	obj, err := renderManifest(applier, reader, values, file)
+	if err != nil {
+		return err
+	}
+
+	r.Log.Info("Delete", "kind", obj.GetKind(), "name", obj.GetName(), "namespace", obj.GetNamespace())
+	if err := r.Client.Delete(context.TODO(), obj, &client.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+		return giterrors.WithStack(err)
+	}
+	return nil
+}
+
+// isUninstallBlocked returns true and sets the UninstallBlocked condition when RegisteredClusters still exist.
+func (r *ClusterRegistrarReconciler) isUninstallBlocked(clusterRegistrar *singaporev1alpha1.ClusterRegistrar) (bool, error) {
+	regClusters := &singaporev1alpha1.RegisteredClusterList{}
+	if err := r.Client.List(context.TODO(), regClusters); err != nil {
+		if meta.IsNoMatchError(err) {
+			return false, nil
+		}
+		return false, giterrors.WithStack(err)
+	}
+	if len(regClusters.Items) == 0 {
+		return false, nil
+	}
+
+	r.Log.Info("Uninstall blocked by RegisteredClusters", "count", len(regClusters.Items))
+	patch := client.MergeFrom(clusterRegistrar.DeepCopy())
+	clusterRegistrar.Status.Conditions = helpers.MergeStatusConditions(clusterRegistrar.Status.Conditions, metav1.Condition{
+		Type:   singaporev1alpha1.ClusterRegistrarConditionUninstallBlocked,
+		Status: metav1.ConditionTrue,
+		Reason: "RegisteredClustersExist",
+		Message: fmt.Sprintf("%d RegisteredClusters still exist, delete them, set the deletion policy to Detach or Retain, "+
+			"or set the %s annotation to \"true\"", len(regClusters.Items), singaporev1alpha1.ForceUninstallAnnotation),
+	})
+	if err := r.Client.Status().Patch(context.TODO(), clusterRegistrar, patch); err != nil {
+		return true, giterrors.WithStack(err)
+	}
+	return true, nil
+}
@@ -0,0 +1,283 @@
+// Copyright Red Hat
+
+package installer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	admissionregistration "k8s.io/api/admissionregistration/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsfake "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/fake"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	apiregistrationv1 "k8s.io/kube-aggregator/pkg/apis/apiregistration/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	singaporev1alpha1 "github.com/stolostron/cluster-registration-operator/api/singapore/v1alpha1"
+	"github.com/stolostron/cluster-registration-operator/pkg/helpers"
+)
+
+func newUninstallReconciler(t *testing.T, clusterRegistrar *singaporev1alpha1.ClusterRegistrar, objects ...client.Object) *ClusterRegistrarReconciler {
+	podNamespace = installationNamespace
+	s := runtime.NewScheme()
+	for _, addToScheme := range []func(*runtime.Scheme) error{
+		clientgoscheme.AddToScheme, apiregistrationv1.AddToScheme, singaporev1alpha1.AddToScheme,
+	} {
+		if err := addToScheme(s); err != nil {
+			t.Fatalf("Failed to build scheme: %s", err)
+		}
+	}
+	objects = append(objects,
+		clusterRegistrar,
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: managerDeploymentName, Namespace: installationNamespace}},
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "cluster-registration-webhook-service", Namespace: installationNamespace}},
+		&admissionregistration.ValidatingWebhookConfiguration{ObjectMeta: metav1.ObjectMeta{Name: "cluster-registration-webhook-service"}},
+		&apiregistrationv1.APIService{ObjectMeta: metav1.ObjectMeta{Name: apiServiceNames[0]}},
+	)
+	return &ClusterRegistrarReconciler{
+		Client:             fake.NewClientBuilder().WithScheme(s).WithObjects(objects...).Build(),
+		KubeClient:         kubefake.NewSimpleClientset(),
+		APIExtensionClient: apiextensionsfake.NewSimpleClientset(newEstablishedCRDs()...),
+		Log:                logr.Discard(),
+		Scheme:             s,
+		Recorder:           record.NewFakeRecorder(10),
+	}
+}
+
+func newUninstalledClusterRegistrar() *singaporev1alpha1.ClusterRegistrar {
+	return &singaporev1alpha1.ClusterRegistrar{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster-registrar", Namespace: installationNamespace},
+	}
+}
+
+func checkDeleted(t *testing.T, r *ClusterRegistrarReconciler, obj client.Object) {
+	err := r.Client.Get(context.TODO(), client.ObjectKeyFromObject(obj), obj)
+	if !errors.IsNotFound(err) {
+		t.Fatalf("%T %s not deleted: %v", obj, obj.GetName(), err)
+	}
+}
+
+func TestProcessClusterRegistrarDeletion(t *testing.T) {
+	clusterRegistrar := newUninstalledClusterRegistrar()
+	r := newUninstallReconciler(t, clusterRegistrar)
+
+	uninstalled, err := r.processClusterRegistrarDeletion(clusterRegistrar)
+	if err != nil {
+		t.Fatalf("Failed to uninstall: %s", err)
+	}
+	if !uninstalled {
+		t.Fatalf("Uninstall blocked but expected to complete.")
+	}
+	checkDeleted(t, r, &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: managerDeploymentName, Namespace: installationNamespace}})
+	checkDeleted(t, r, &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "cluster-registration-webhook-service", Namespace: installationNamespace}})
+	checkDeleted(t, r, &admissionregistration.ValidatingWebhookConfiguration{ObjectMeta: metav1.ObjectMeta{Name: "cluster-registration-webhook-service"}})
+	checkDeleted(t, r, &apiregistrationv1.APIService{ObjectMeta: metav1.ObjectMeta{Name: apiServiceNames[0]}})
+
+	crds, err := r.APIExtensionClient.ApiextensionsV1().CustomResourceDefinitions().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("Failed to list CRDs: %s", err)
+	}
+	if len(crds.Items) != len(installedCRDNames) {
+		t.Fatalf("CRDs deleted but expected to be kept: %d", len(crds.Items))
+	}
+}
+
+func TestProcessClusterRegistrarDeletionRemoveCRDs(t *testing.T) {
+	clusterRegistrar := newUninstalledClusterRegistrar()
+	clusterRegistrar.Spec.RemoveCRDs = true
+	r := newUninstallReconciler(t, clusterRegistrar)
+
+	if _, err := r.processClusterRegistrarDeletion(clusterRegistrar); err != nil {
+		t.Fatalf("Failed to uninstall: %s", err)
+	}
+	crds, err := r.APIExtensionClient.ApiextensionsV1().CustomResourceDefinitions().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("Failed to list CRDs: %s", err)
+	}
+	if len(crds.Items) != 0 {
+		t.Fatalf("CRDs not deleted: %d", len(crds.Items))
+	}
+}
+
+func TestProcessClusterRegistrarDeletionBlocked(t *testing.T) {
+	clusterRegistrar := newUninstalledClusterRegistrar()
+	regCluster := &singaporev1alpha1.RegisteredCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster1", Namespace: "workspace"},
+	}
+	r := newUninstallReconciler(t, clusterRegistrar, regCluster)
+
+	uninstalled, err := r.processClusterRegistrarDeletion(clusterRegistrar)
+	if err != nil {
+		t.Fatalf("Failed to uninstall: %s", err)
+	}
+	if uninstalled {
+		t.Fatalf("Uninstall completed but expected to be blocked.")
+	}
+	checkCondition(t, clusterRegistrar.Status.Conditions, singaporev1alpha1.ClusterRegistrarConditionUninstallBlocked, metav1.ConditionTrue, "RegisteredClustersExist")
+	deployment := &appsv1.Deployment{}
+	if err := r.Client.Get(context.TODO(), client.ObjectKey{Name: managerDeploymentName, Namespace: installationNamespace}, deployment); err != nil {
+		t.Fatalf("Deployment deleted but expected to be kept: %s", err)
+	}
+
+	clusterRegistrar.Annotations = map[string]string{singaporev1alpha1.ForceUninstallAnnotation: "true"}
+	uninstalled, err = r.processClusterRegistrarDeletion(clusterRegistrar)
+	if err != nil {
+		t.Fatalf("Failed to uninstall: %s", err)
+	}
+	if !uninstalled {
+		t.Fatalf("Uninstall blocked but expected to be forced.")
+	}
+	checkDeleted(t, r, deployment)
+}
+
+func TestProcessClusterRegistrarDeletionRetain(t *testing.T) {
+	clusterRegistrar := newUninstalledClusterRegistrar()
+	clusterRegistrar.Spec.DeletionPolicy = singaporev1alpha1.DeletionPolicyRetain
+	// The CRDs are kept even if their removal is requested, it would delete the retained RegisteredClusters
+	clusterRegistrar.Spec.RemoveCRDs = true
+	regCluster := &singaporev1alpha1.RegisteredCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster1", Namespace: "workspace"},
+	}
+	r := newUninstallReconciler(t, clusterRegistrar, regCluster)
+
+	uninstalled, err := r.processClusterRegistrarDeletion(clusterRegistrar)
+	if err != nil {
+		t.Fatalf("Failed to uninstall: %s", err)
+	}
+	if !uninstalled {
+		t.Fatalf("Uninstall blocked but expected to complete.")
+	}
+	if err := r.Client.Get(context.TODO(), client.ObjectKeyFromObject(regCluster), regCluster); err != nil {
+		t.Fatalf("RegisteredCluster deleted but expected to be retained: %s", err)
+	}
+	crds, err := r.APIExtensionClient.ApiextensionsV1().CustomResourceDefinitions().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("Failed to list CRDs: %s", err)
+	}
+	if len(crds.Items) == 0 {
+		t.Fatalf("CRDs deleted but expected to be retained.")
+	}
+}
+
+func TestProcessClusterRegistrarDeletionDetach(t *testing.T) {
+	clusterRegistrar := newUninstalledClusterRegistrar()
+	clusterRegistrar.Spec.DeletionPolicy = singaporev1alpha1.DeletionPolicyDetach
+	regCluster := &singaporev1alpha1.RegisteredCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster1", Namespace: "workspace"},
+	}
+	hubConfig := &singaporev1alpha1.HubConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "hub", Namespace: installationNamespace},
+		Spec:       singaporev1alpha1.HubConfigSpec{KubeConfigSecretRef: corev1.LocalObjectReference{Name: "hub-kubeconfig"}},
+	}
+	r := newUninstallReconciler(t, clusterRegistrar, regCluster, hubConfig)
+	r.KubeClient = kubefake.NewSimpleClientset(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "workspace", Labels: map[string]string{helpers.WorkspaceProviderLabel: helpers.WorkspaceProviderLabelValue}}},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "hub-kubeconfig", Namespace: installationNamespace},
+			Data:       map[string][]byte{"kubeconfig": []byte("kubeconfig")},
+		},
+	)
+
+	hubClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{
+			managedClusterGVR:    "ManagedClusterList",
+			managedClusterSetGVR: "ManagedClusterSetList",
+		},
+		&unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "cluster.open-cluster-management.io/v1",
+			"kind":       "ManagedCluster",
+			"metadata": map[string]interface{}{
+				"name":   "registered-cluster-abcde",
+				"labels": map[string]interface{}{registeredClusterNameLabel: "cluster1", registeredClusterNamespaceLabel: "workspace"},
+			},
+		}},
+		// The ManagedClusters of the workspaces of other installations sharing the hub are kept
+		&unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "cluster.open-cluster-management.io/v1",
+			"kind":       "ManagedCluster",
+			"metadata": map[string]interface{}{
+				"name":   "registered-cluster-fghij",
+				"labels": map[string]interface{}{registeredClusterNameLabel: "cluster1", registeredClusterNamespaceLabel: "other-workspace"},
+			},
+		}},
+		&unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "cluster.open-cluster-management.io/v1",
+			"kind":       "ManagedCluster",
+			"metadata": map[string]interface{}{
+				"name": "adopted-cluster",
+				"labels": map[string]interface{}{
+					registeredClusterNameLabel:      "cluster2",
+					registeredClusterNamespaceLabel: "workspace",
+					registeredClusterAdoptedLabel:   "true",
+					managedClusterSetLabel:          "workspace",
+					"env":                           "prod",
+				},
+			},
+		}},
+		&unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "cluster.open-cluster-management.io/v1beta1",
+			"kind":       "ManagedClusterSet",
+			"metadata":   map[string]interface{}{"name": "workspace"},
+		}},
+	)
+	newHubDynamicClient = func(kubeConfig []byte) (dynamic.Interface, error) { return hubClient, nil }
+	defer func() { newHubDynamicClient = defaultNewHubDynamicClient }()
+
+	// The RegisteredClusters are deleted first, then the ManagedClusters and the ManagedClusterSets
+	uninstalled, err := r.processClusterRegistrarDeletion(clusterRegistrar)
+	if err != nil {
+		t.Fatalf("Failed to uninstall: %s", err)
+	}
+	if uninstalled {
+		t.Fatalf("Uninstall completed but expected to detach the RegisteredClusters.")
+	}
+	checkCondition(t, clusterRegistrar.Status.Conditions, singaporev1alpha1.ClusterRegistrarConditionDetaching, metav1.ConditionTrue, "DeletingRegisteredClusters")
+	checkDeleted(t, r, regCluster)
+
+	uninstalled, err = r.processClusterRegistrarDeletion(clusterRegistrar)
+	if err != nil {
+		t.Fatalf("Failed to uninstall: %s", err)
+	}
+	if uninstalled {
+		t.Fatalf("Uninstall completed but expected to delete the ManagedClusters.")
+	}
+	checkCondition(t, clusterRegistrar.Status.Conditions, singaporev1alpha1.ClusterRegistrarConditionDetaching, metav1.ConditionTrue, "DeletingManagedClusters")
+
+	uninstalled, err = r.processClusterRegistrarDeletion(clusterRegistrar)
+	if err != nil {
+		t.Fatalf("Failed to uninstall: %s", err)
+	}
+	if !uninstalled {
+		t.Fatalf("Uninstall blocked but expected to complete: %v", clusterRegistrar.Status.Conditions)
+	}
+	checkCondition(t, clusterRegistrar.Status.Conditions, singaporev1alpha1.ClusterRegistrarConditionDetaching, metav1.ConditionFalse, "Detached")
+	// The adopted ManagedCluster is released instead of deleted
+	adopted, err := hubClient.Resource(managedClusterGVR).Get(context.TODO(), "adopted-cluster", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Adopted ManagedCluster deleted: %v", err)
+	}
+	if labels := adopted.GetLabels(); len(labels) != 1 || labels["env"] != "prod" {
+		t.Fatalf("Adopted ManagedCluster not released: %v", labels)
+	}
+	if _, err := hubClient.Resource(managedClusterGVR).Get(context.TODO(), "registered-cluster-fghij", metav1.GetOptions{}); err != nil {
+		t.Fatalf("ManagedCluster of another installation deleted: %v", err)
+	}
+	if _, err := hubClient.Resource(managedClusterGVR).Get(context.TODO(), "registered-cluster-abcde", metav1.GetOptions{}); !errors.IsNotFound(err) {
+		t.Fatalf("ManagedCluster not deleted: %v", err)
+	}
+	if _, err := hubClient.Resource(managedClusterSetGVR).Get(context.TODO(), "workspace", metav1.GetOptions{}); !errors.IsNotFound(err) {
+		t.Fatalf("ManagedClusterSet not deleted: %v", err)
+	}
+}
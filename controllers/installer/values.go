@@ -0,0 +1,156 @@
+// Copyright Red Hat
+
+package installer
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	singaporev1alpha1 "github.com/stolostron/cluster-registration-operator/api/singapore/v1alpha1"
+	"github.com/stolostron/cluster-registration-operator/pkg/helpers"
+)
+
+// ConfigHashAnnotation is set on the pod templates of the deployments with the hash of the values
+// they are rendered from, so the pods are rolled when the ClusterRegistrar spec changes.
+const ConfigHashAnnotation string = "singapore.open-cluster-management.io/config-hash"
+
+// defaultTolerations allow the components to run on the infra and dedicated nodes.
+var defaultTolerations = []corev1.Toleration{
+	{
+		Key:      "node-role.kubernetes.io/infra",
+		Operator: corev1.TolerationOpExists,
+		Effect:   corev1.TaintEffectNoSchedule,
+	},
+	{
+		Key:      "dedicated",
+		Operator: corev1.TolerationOpExists,
+		Effect:   corev1.TaintEffectNoSchedule,
+	},
+}
+
+// defaultManagerResources are the compute resources of the manager container.
+var defaultManagerResources = corev1.ResourceRequirements{
+	Limits: corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse("100m"),
+		corev1.ResourceMemory: resource.MustParse("256Mi"),
+	},
+	Requests: corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse("50m"),
+		corev1.ResourceMemory: resource.MustParse("50Mi"),
+	},
+}
+
+// ComponentValues are the values used to render the deployment of a component.
+type ComponentValues struct {
+	Image        string
+	Replicas     int32
+	Resources    *corev1.ResourceRequirements
+	NodeSelector map[string]string
+	Tolerations  []corev1.Toleration
+}
+
+// Values are the values used to render the manifests of the deploy directory.
+type Values struct {
+	Namespace         string
+	Manager           ComponentValues
+	Webhook           ComponentValues
+	LogLevel          int32
+	LeaderElection    bool
+	WorkspaceSelector string
+	ConfigHash        string
+
+	// The concurrency of the manager reconciliations and the rate limits of its hub clients.
+	MaxConcurrentReconciles int32
+	HubQPS                  int32
+	HubBurst                int32
+
+	CertificateProvider string
+	// The base64 CA bundles injected in the APIServices and the webhook configurations
+	// and the hash of the serving certificate, set when the certificates are self-signed.
+	CABundle              string
+	KubeAPIServerCABundle string
+	ServingCertHash       string
+}
+
+// newValues returns the values for the ClusterRegistrar spec, the installer image and namespace are used by default.
+func newValues(clusterRegistrar *singaporev1alpha1.ClusterRegistrar, image, namespace string) (*Values, error) {
+	spec := clusterRegistrar.Spec
+	values := &Values{
+		Namespace:           namespace,
+		Manager:             newComponentValues(spec.Manager, image, &defaultManagerResources),
+		Webhook:             newComponentValues(spec.Webhook, image, nil),
+		LogLevel:            spec.LogLevel,
+		LeaderElection:      spec.LeaderElection == nil || *spec.LeaderElection,
+		WorkspaceSelector:   helpers.DefaultWorkspaceSelector,
+		CertificateProvider: string(singaporev1alpha1.CertificateProviderSelfSigned),
+
+		MaxConcurrentReconciles: int32(helpers.DefaultMaxConcurrentReconciles),
+		HubQPS:                  int32(helpers.DefaultHubClientQPS),
+		HubBurst:                int32(helpers.DefaultHubClientBurst),
+	}
+
+	if len(spec.CertificateProvider) != 0 {
+		values.CertificateProvider = string(spec.CertificateProvider)
+	}
+
+	if spec.Concurrency.MaxConcurrentReconciles != 0 {
+		values.MaxConcurrentReconciles = spec.Concurrency.MaxConcurrentReconciles
+	}
+	if spec.Concurrency.HubQPS != 0 {
+		values.HubQPS = spec.Concurrency.HubQPS
+	}
+	if spec.Concurrency.HubBurst != 0 {
+		values.HubBurst = spec.Concurrency.HubBurst
+	}
+
+	if spec.WorkspaceSelector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(spec.WorkspaceSelector)
+		if err != nil {
+			return nil, err
+		}
+		values.WorkspaceSelector = selector.String()
+	}
+
+	b, err := json.Marshal(values)
+	if err != nil {
+		return nil, err
+	}
+	values.ConfigHash = fmt.Sprintf("%x", sha256.Sum256(b))[:16]
+	return values, nil
+}
+
+// setWebhookCertificates sets the values injecting the self-signed certificates.
+func (values *Values) setWebhookCertificates(certificates *webhookCertificates) {
+	values.CABundle = base64.StdEncoding.EncodeToString(certificates.caBundle)
+	values.KubeAPIServerCABundle = base64.StdEncoding.EncodeToString(certificates.kubeAPIServerCABundle)
+	values.ServingCertHash = certificates.servingCertHash
+}
+
+func newComponentValues(spec singaporev1alpha1.ComponentSpec, image string, defaultResources *corev1.ResourceRequirements) ComponentValues {
+	values := ComponentValues{
+		Image:        image,
+		Replicas:     1,
+		Resources:    defaultResources,
+		NodeSelector: spec.NodeSelector,
+		Tolerations:  defaultTolerations,
+	}
+	if len(spec.Image) != 0 {
+		values.Image = spec.Image
+	}
+	if spec.Replicas != nil {
+		values.Replicas = *spec.Replicas
+	}
+	if spec.Resources != nil {
+		values.Resources = spec.Resources
+	}
+	if len(spec.Tolerations) != 0 {
+		values.Tolerations = spec.Tolerations
+	}
+	return values
+}
@@ -0,0 +1,165 @@
+// Copyright Red Hat
+
+package installer
+
+import (
+	"testing"
+
+	"github.com/ghodss/yaml"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusteradmapply "open-cluster-management.io/clusteradm/pkg/helpers/apply"
+
+	singaporev1alpha1 "github.com/stolostron/cluster-registration-operator/api/singapore/v1alpha1"
+	"github.com/stolostron/cluster-registration-operator/deploy"
+	"github.com/stolostron/cluster-registration-operator/pkg/helpers"
+)
+
+func renderDeployment(t *testing.T, values *Values, file string) *appsv1.Deployment {
+	applier := clusteradmapply.NewApplierBuilder().Build()
+	b, err := applier.MustTemplateAsset(deploy.GetScenarioResourcesReader(), values, "", file)
+	if err != nil {
+		t.Fatalf("Failed to render %s: %s", file, err)
+	}
+	deployment := &appsv1.Deployment{}
+	if err := yaml.Unmarshal(b, deployment); err != nil {
+		t.Fatalf("Failed to unmarshal %s: %s\n%s", file, err, string(b))
+	}
+	return deployment
+}
+
+func TestNewValuesDefault(t *testing.T) {
+	values, err := newValues(&singaporev1alpha1.ClusterRegistrar{}, "installer-image", "cluster-reg-config")
+	if err != nil {
+		t.Fatalf("Failed to compute values: %s", err)
+	}
+
+	manager := renderDeployment(t, values, "cluster-registration-operator/manager.yaml")
+	if *manager.Spec.Replicas != 1 {
+		t.Fatalf(`Replicas not as expected. Expected %d, actual %d`, 1, *manager.Spec.Replicas)
+	}
+	container := manager.Spec.Template.Spec.Containers[0]
+	if container.Image != "installer-image" {
+		t.Fatalf(`Image not as expected. Expected %s, actual %s`, "installer-image", container.Image)
+	}
+	if !contains(container.Args, "--enable-leader-election=true") {
+		t.Fatalf("Leader election not enabled: %v", container.Args)
+	}
+	if !contains(container.Args, "--workspace-selector="+helpers.DefaultWorkspaceSelector) {
+		t.Fatalf("Workspace selector not set: %v", container.Args)
+	}
+	if !contains(container.Args, "--max-concurrent-reconciles=1") || !contains(container.Args, "--hub-qps=20") ||
+		!contains(container.Args, "--hub-burst=50") {
+		t.Fatalf("Concurrency args not as expected: %v", container.Args)
+	}
+	if !container.Resources.Limits.Memory().Equal(resource.MustParse("256Mi")) {
+		t.Fatalf(`Memory limit not as expected: %v`, container.Resources.Limits)
+	}
+	if len(manager.Spec.Template.Spec.Tolerations) != 2 {
+		t.Fatalf(`Tolerations not as expected: %v`, manager.Spec.Template.Spec.Tolerations)
+	}
+
+	webhook := renderDeployment(t, values, "webhook/webhook.yaml")
+	if webhook.Spec.Template.Spec.Containers[0].Image != "installer-image" {
+		t.Fatalf(`Image not as expected. Expected %s, actual %s`, "installer-image", webhook.Spec.Template.Spec.Containers[0].Image)
+	}
+}
+
+func TestNewValuesOverrides(t *testing.T) {
+	replicas := int32(3)
+	leaderElection := false
+	clusterRegistrar := &singaporev1alpha1.ClusterRegistrar{
+		Spec: singaporev1alpha1.ClusterRegistrarSpec{
+			Manager: singaporev1alpha1.ComponentSpec{
+				Image:    "manager-image",
+				Replicas: &replicas,
+				Resources: &corev1.ResourceRequirements{
+					Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Gi")},
+				},
+				NodeSelector: map[string]string{"node-role.kubernetes.io/infra": ""},
+				Tolerations:  []corev1.Toleration{{Key: "infra", Operator: corev1.TolerationOpExists}},
+			},
+			Webhook: singaporev1alpha1.ComponentSpec{
+				Image: "webhook-image",
+			},
+			LogLevel:       4,
+			LeaderElection: &leaderElection,
+			WorkspaceSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"tenant": "true"},
+			},
+			Concurrency: singaporev1alpha1.ConcurrencySpec{
+				MaxConcurrentReconciles: 5,
+				HubQPS:                  50,
+			},
+		},
+	}
+	values, err := newValues(clusterRegistrar, "installer-image", "cluster-reg-config")
+	if err != nil {
+		t.Fatalf("Failed to compute values: %s", err)
+	}
+
+	manager := renderDeployment(t, values, "cluster-registration-operator/manager.yaml")
+	if *manager.Spec.Replicas != 3 {
+		t.Fatalf(`Replicas not as expected. Expected %d, actual %d`, 3, *manager.Spec.Replicas)
+	}
+	container := manager.Spec.Template.Spec.Containers[0]
+	if container.Image != "manager-image" {
+		t.Fatalf(`Image not as expected. Expected %s, actual %s`, "manager-image", container.Image)
+	}
+	if !contains(container.Args, "--enable-leader-election=false") {
+		t.Fatalf("Leader election not disabled: %v", container.Args)
+	}
+	if !contains(container.Args, "--v=4") || !contains(container.Args, "--workspace-selector=tenant=true") {
+		t.Fatalf("Args not as expected: %v", container.Args)
+	}
+	if !contains(container.Args, "--max-concurrent-reconciles=5") || !contains(container.Args, "--hub-qps=50") ||
+		!contains(container.Args, "--hub-burst=50") {
+		t.Fatalf("Concurrency args not as expected: %v", container.Args)
+	}
+	if !container.Resources.Limits.Memory().Equal(resource.MustParse("1Gi")) {
+		t.Fatalf(`Memory limit not as expected: %v`, container.Resources.Limits)
+	}
+	if _, ok := manager.Spec.Template.Spec.NodeSelector["node-role.kubernetes.io/infra"]; !ok {
+		t.Fatalf(`NodeSelector not as expected: %v`, manager.Spec.Template.Spec.NodeSelector)
+	}
+	if len(manager.Spec.Template.Spec.Tolerations) != 1 || manager.Spec.Template.Spec.Tolerations[0].Key != "infra" {
+		t.Fatalf(`Tolerations not as expected: %v`, manager.Spec.Template.Spec.Tolerations)
+	}
+
+	webhook := renderDeployment(t, values, "webhook/webhook.yaml")
+	if webhook.Spec.Template.Spec.Containers[0].Image != "webhook-image" {
+		t.Fatalf(`Image not as expected. Expected %s, actual %s`, "webhook-image", webhook.Spec.Template.Spec.Containers[0].Image)
+	}
+}
+
+func TestNewValuesConfigHash(t *testing.T) {
+	clusterRegistrar := &singaporev1alpha1.ClusterRegistrar{}
+	values, err := newValues(clusterRegistrar, "installer-image", "cluster-reg-config")
+	if err != nil {
+		t.Fatalf("Failed to compute values: %s", err)
+	}
+	clusterRegistrar.Spec.LogLevel = 2
+	newValues, err := newValues(clusterRegistrar, "installer-image", "cluster-reg-config")
+	if err != nil {
+		t.Fatalf("Failed to compute values: %s", err)
+	}
+	if values.ConfigHash == newValues.ConfigHash {
+		t.Fatalf("Config hash not updated on spec change.")
+	}
+
+	manager := renderDeployment(t, newValues, "cluster-registration-operator/manager.yaml")
+	if manager.Spec.Template.Annotations[ConfigHashAnnotation] != newValues.ConfigHash {
+		t.Fatalf(`Config hash annotation not as expected: %v`, manager.Spec.Template.Annotations)
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,112 @@
+// Copyright Red Hat
+
+package installer
+
+import (
+	"context"
+
+	admissionregistration "k8s.io/api/admissionregistration/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	apiregistrationv1 "k8s.io/kube-aggregator/pkg/apis/apiregistration/v1"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	singaporev1alpha1 "github.com/stolostron/cluster-registration-operator/api/singapore/v1alpha1"
+)
+
+const (
+	// ManagedByLabel is set on the objects applied by the installer.
+	ManagedByLabel string = "app.kubernetes.io/managed-by"
+	// ManagedByLabelValue is the value of the ManagedByLabel on the objects applied by the installer.
+	ManagedByLabelValue string = "cluster-registration-installer"
+)
+
+// managedObjects are the kinds of the objects applied by the installer which are watched
+// to re-apply them on drift or deletion.
+func managedObjects() []client.Object {
+	return []client.Object{
+		&appsv1.Deployment{},
+		&corev1.Service{},
+		&corev1.ServiceAccount{},
+		&rbacv1.ClusterRole{},
+		&rbacv1.ClusterRoleBinding{},
+		&rbacv1.Role{},
+		&rbacv1.RoleBinding{},
+		&admissionregistration.ValidatingWebhookConfiguration{},
+		&admissionregistration.MutatingWebhookConfiguration{},
+		&apiregistrationv1.APIService{},
+	}
+}
+
+// NewCache is the cache builder of the installer manager, the cache holds only
+// the objects applied by the installer for the watched kinds.
+func NewCache() cache.NewCacheFunc {
+	selectors := cache.SelectorsByObject{}
+	selector := labels.SelectorFromSet(labels.Set{ManagedByLabel: ManagedByLabelValue})
+	for _, obj := range managedObjects() {
+		selectors[obj] = cache.ObjectSelector{Label: selector}
+	}
+	return cache.BuilderWithOptions(cache.Options{SelectorsByObject: selectors})
+}
+
+func managedByPredicate() predicate.Predicate {
+	return predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		return obj.GetLabels()[ManagedByLabel] == ManagedByLabelValue
+	})
+}
+
+// deploymentChangedPredicate filters out the status-only updates of the Deployments, such as the replica counts
+// changing during a rollout. The updates of the spec, of the metadata and of the availability reported in the
+// conditions of the ClusterRegistrar are kept.
+func deploymentChangedPredicate() predicate.Predicate {
+	return predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldDeployment, okOld := e.ObjectOld.(*appsv1.Deployment)
+			newDeployment, okNew := e.ObjectNew.(*appsv1.Deployment)
+			if !okOld || !okNew {
+				return true
+			}
+			return oldDeployment.Generation != newDeployment.Generation ||
+				!equality.Semantic.DeepEqual(oldDeployment.Labels, newDeployment.Labels) ||
+				!equality.Semantic.DeepEqual(oldDeployment.Annotations, newDeployment.Annotations) ||
+				isDeploymentRolledOut(oldDeployment) != isDeploymentRolledOut(newDeployment) ||
+				getDeploymentAvailableStatus(oldDeployment) != getDeploymentAvailableStatus(newDeployment)
+		},
+	}
+}
+
+// getDeploymentAvailableStatus returns the status of the Available condition of the deployment.
+func getDeploymentAvailableStatus(deployment *appsv1.Deployment) corev1.ConditionStatus {
+	for _, condition := range deployment.Status.Conditions {
+		if condition.Type == appsv1.DeploymentAvailable {
+			return condition.Status
+		}
+	}
+	return corev1.ConditionUnknown
+}
+
+// clusterRegistrarRequests returns the requests of the ClusterRegistrars of the installation namespace.
+func (r *ClusterRegistrarReconciler) clusterRegistrarRequests(obj client.Object) []reconcile.Request {
+	clusterRegistrars := &singaporev1alpha1.ClusterRegistrarList{}
+	if err := r.Client.List(context.TODO(), clusterRegistrars, client.InNamespace(podNamespace)); err != nil {
+		r.Log.Error(err, "failed to list the ClusterRegistrars")
+		return nil
+	}
+	r.Log.V(1).Info("Processing managed object event", "kind", obj.GetObjectKind().GroupVersionKind().Kind,
+		"name", obj.GetName(), "namespace", obj.GetNamespace())
+	req := make([]reconcile.Request, 0, len(clusterRegistrars.Items))
+	for _, clusterRegistrar := range clusterRegistrars.Items {
+		req = append(req, reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: clusterRegistrar.Name, Namespace: clusterRegistrar.Namespace},
+		})
+	}
+	return req
+}
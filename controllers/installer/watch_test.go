@@ -0,0 +1,74 @@
+// Copyright Red Hat
+
+package installer
+
+import (
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	singaporev1alpha1 "github.com/stolostron/cluster-registration-operator/api/singapore/v1alpha1"
+)
+
+func TestManagedByPredicate(t *testing.T) {
+	managed := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{
+		Name:   managerDeploymentName,
+		Labels: map[string]string{ManagedByLabel: ManagedByLabelValue},
+	}}
+	other := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "other"}}
+
+	p := managedByPredicate()
+	if !p.Delete(event.DeleteEvent{Object: managed}) {
+		t.Fatalf("Deletion of a managed object filtered out.")
+	}
+	if !p.Update(event.UpdateEvent{ObjectOld: managed, ObjectNew: managed}) {
+		t.Fatalf("Update of a managed object filtered out.")
+	}
+	if p.Delete(event.DeleteEvent{Object: other}) {
+		t.Fatalf("Deletion of an unmanaged object not filtered out.")
+	}
+}
+
+func TestClusterRegistrarRequests(t *testing.T) {
+	r, clusterRegistrar := newStatusReconciler(t, nil,
+		&singaporev1alpha1.ClusterRegistrar{ObjectMeta: metav1.ObjectMeta{Name: "other-namespace", Namespace: "other"}})
+
+	requests := r.clusterRegistrarRequests(&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: managerDeploymentName}})
+	if len(requests) != 1 {
+		t.Fatalf(`Requests not as expected: %v`, requests)
+	}
+	if requests[0].Name != clusterRegistrar.Name || requests[0].Namespace != clusterRegistrar.Namespace {
+		t.Fatalf(`Request not as expected. Expected %s/%s, actual %s`, clusterRegistrar.Namespace, clusterRegistrar.Name, requests[0])
+	}
+}
+
+func TestDeploymentChangedPredicate(t *testing.T) {
+	deployment := newAvailableDeployment(managerDeploymentName)
+	deployment.Generation = 1
+	deployment.Status.ObservedGeneration = 1
+
+	p := deploymentChangedPredicate()
+	statusOnly := deployment.DeepCopy()
+	statusOnly.Status.ReadyReplicas = 1
+	if p.Update(event.UpdateEvent{ObjectOld: deployment, ObjectNew: statusOnly}) {
+		t.Fatalf("Status-only update not filtered out.")
+	}
+
+	specChanged := deployment.DeepCopy()
+	specChanged.Generation = 2
+	if !p.Update(event.UpdateEvent{ObjectOld: deployment, ObjectNew: specChanged}) {
+		t.Fatalf("Spec update filtered out.")
+	}
+
+	unavailable := deployment.DeepCopy()
+	unavailable.Status.AvailableReplicas = 0
+	if !p.Update(event.UpdateEvent{ObjectOld: deployment, ObjectNew: unavailable}) {
+		t.Fatalf("Availability update filtered out.")
+	}
+
+	if !p.Delete(event.DeleteEvent{Object: deployment}) {
+		t.Fatalf("Deletion filtered out.")
+	}
+}
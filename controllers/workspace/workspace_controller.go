@@ -4,21 +4,30 @@ package workspace
 
 import (
 	"context"
+	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/stolostron/cluster-registration-operator/resources"
 
 	giterrors "github.com/pkg/errors"
+	singaporev1alpha1 "github.com/stolostron/cluster-registration-operator/api/singapore/v1alpha1"
 	"github.com/stolostron/cluster-registration-operator/pkg/helpers"
 	corev1 "k8s.io/api/core/v1"
 	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
 	clusteradmapply "open-cluster-management.io/clusteradm/pkg/helpers/apply"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 )
@@ -32,25 +41,63 @@ type WorkspaceReconciler struct {
 	Log                logr.Logger
 	Scheme             *runtime.Scheme
 	HubClusters        []helpers.HubInstance
+	Recorder           record.EventRecorder
+	// MaxConcurrentReconciles is the number of workspaces reconciled in parallel.
+	MaxConcurrentReconciles int
+}
+
+// The reasons of the events recorded on the workspaces.
+const (
+	EventReasonManagedClusterSetCreated    string = "ManagedClusterSetCreated"
+	EventReasonManagedClusterSetSyncFailed string = "ManagedClusterSetSyncFailed"
+	EventReasonHubChanged                  string = "HubChanged"
+)
+
+// migratingRequeuePeriod is the period the hub of the RegisteredClusters still migrating is synced again at.
+const migratingRequeuePeriod = time.Minute
+
+var managedClusterSetGVR = schema.GroupVersionResource{
+	Group:    "cluster.open-cluster-management.io",
+	Version:  "v1beta1",
+	Resource: "managedclustersets",
 }
 
 func (r *WorkspaceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	logger := r.Log.WithValues("namespace", req.Namespace, "name", req.Name)
+	logger := r.Log.WithValues(helpers.LogKeyWorkspace, req.Name)
 	logger.Info("Reconciling...")
 
 	//TODO - handle delete
 
-	if err := r.syncManagedClusterSet(req.Name, ctx); err != nil {
+	namespace := &corev1.Namespace{}
+	if err := r.Client.Get(ctx, types.NamespacedName{Name: req.Name}, namespace); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, giterrors.WithStack(err)
+	}
+
+	if err := r.syncManagedClusterSet(namespace, ctx); err != nil {
 		logger.Error(err, "failed to sync ManagedClusterSet")
-		//TODO - should we report a status on the namespace?
+		r.Recorder.Event(namespace, corev1.EventTypeWarning, EventReasonManagedClusterSetSyncFailed, err.Error())
+		return ctrl.Result{}, err
+	}
+
+	pending, err := r.syncRegisteredClusterHubs(namespace, ctx)
+	if err != nil {
+		logger.Error(err, "failed to sync the hub of the RegisteredClusters")
 		return ctrl.Result{}, err
 	}
+	if pending {
+		logger.V(1).Info("RegisteredClusters still migrating, the hub is changed once their migration is completed")
+		return ctrl.Result{RequeueAfter: migratingRequeuePeriod}, nil
+	}
 
 	return ctrl.Result{}, nil
 }
 
-func (r *WorkspaceReconciler) syncManagedClusterSet(name string, ctx context.Context) error {
-	hubCluster, err := helpers.GetHubCluster(name, r.HubClusters)
+func (r *WorkspaceReconciler) syncManagedClusterSet(namespace *corev1.Namespace, ctx context.Context) error {
+	name := namespace.Name
+	hubCluster, err := helpers.GetWorkspaceHubCluster(namespace, r.HubClusters)
 	if err != nil {
 		return err
 	}
@@ -59,6 +106,11 @@ func (r *WorkspaceReconciler) syncManagedClusterSet(name string, ctx context.Con
 	readerDeploy := resources.GetScenarioResourcesReader()
 
 	mcsName := helpers.ManagedClusterSetNameForWorkspace(name)
+	_, err = hubCluster.DynamicClient.Resource(managedClusterSetGVR).Get(ctx, mcsName, metav1.GetOptions{})
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return giterrors.WithStack(err)
+	}
+	created := k8serrors.IsNotFound(err)
 
 	files := []string{
 		"workspace/managed_cluster_set.yaml",
@@ -74,13 +126,57 @@ func (r *WorkspaceReconciler) syncManagedClusterSet(name string, ctx context.Con
 	if err != nil {
 		return giterrors.WithStack(err)
 	}
+	if created {
+		r.Recorder.Eventf(namespace, corev1.EventTypeNormal, EventReasonManagedClusterSetCreated,
+			"Created ManagedClusterSet %s on the hub %s", mcsName, hubCluster.HubConfig.Name)
+	}
 	return nil
 }
 
+// syncRegisteredClusterHubs sets the hub selected by the annotation of the workspace on its RegisteredClusters,
+// which are migrated to the new hub by the RegisteredCluster controller. The hub of the RegisteredClusters still
+// migrating can't be changed, they are skipped and it returns true so they are synced again later.
+func (r *WorkspaceReconciler) syncRegisteredClusterHubs(namespace *corev1.Namespace, ctx context.Context) (bool, error) {
+	hubConfigName := namespace.Annotations[helpers.WorkspaceHubConfigAnnotation]
+	if len(hubConfigName) == 0 {
+		return false, nil
+	}
+	if _, err := helpers.GetHubClusterByName(hubConfigName, r.HubClusters); err != nil {
+		return false, err
+	}
+
+	regClusters := &singaporev1alpha1.RegisteredClusterList{}
+	if err := r.Client.List(ctx, regClusters, client.InNamespace(namespace.Name)); err != nil {
+		return false, giterrors.WithStack(err)
+	}
+	pending := false
+	var errs []error
+	for i := range regClusters.Items {
+		regCluster := &regClusters.Items[i]
+		if regCluster.Spec.HubConfigName == hubConfigName {
+			continue
+		}
+		// The webhook denies the hub change until the migration is completed
+		if _, ok := regCluster.Annotations[singaporev1alpha1.MigrationSourceHubAnnotation]; ok {
+			pending = true
+			continue
+		}
+		patch := client.MergeFrom(regCluster.DeepCopy())
+		regCluster.Spec.HubConfigName = hubConfigName
+		if err := r.Client.Patch(ctx, regCluster, patch); err != nil {
+			errs = append(errs, giterrors.WithStack(err))
+			continue
+		}
+		r.Recorder.Eventf(namespace, corev1.EventTypeNormal, EventReasonHubChanged,
+			"Migrating the RegisteredCluster %s to the hub %s", regCluster.Name, hubConfigName)
+	}
+	return pending, utilerrors.NewAggregate(errs)
+}
+
 func workspaceNamespacesPredicate() predicate.Predicate {
 	f := func(obj client.Object) bool {
-		log := ctrl.Log.WithName("controllers").WithName("workspace").WithName("workspaceNamespacesPredicate").WithValues("namespace", obj.GetNamespace(), "name", obj.GetName())
-		if obj.GetLabels()["toolchain.dev.openshift.com/provider"] == "codeready-toolchain" {
+		log := ctrl.Log.WithName("controllers").WithName("workspace").WithName("workspaceNamespacesPredicate").WithValues(helpers.LogKeyWorkspace, obj.GetName())
+		if helpers.IsWorkspace(obj.GetLabels()) {
 			log.V(1).Info("process appstudio workspace")
 			return true
 		}
@@ -110,5 +206,6 @@ func (r *WorkspaceReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	// clusterapiv1.AddToScheme(r.Scheme) //I think I don't need this..set in main
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&corev1.Namespace{}, builder.WithPredicates(workspaceNamespacesPredicate())). // only care about appstudio workspace namespaces
+		WithOptions(controller.Options{MaxConcurrentReconciles: r.MaxConcurrentReconciles}).
 		Complete(r)
 }
@@ -0,0 +1,82 @@
+// Copyright Red Hat
+
+package helpers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/kubernetes"
+	clusteradmapply "open-cluster-management.io/clusteradm/pkg/helpers/apply"
+	"open-cluster-management.io/clusteradm/pkg/helpers/asset"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ApplyResources applies the resources of the files rendered by the applier, with its owner and cache, through the client.
+// The requests are made with the context, so they are canceled and traced with the caller, while the applier
+// makes its own requests with context.TODO(). The objects are read as unstructured, which the clients of the managers
+// don't cache, so no informer is started. It is meant for the custom resources, which the applier updates the same way.
+func ApplyResources(ctx context.Context, c client.Client, applier *clusteradmapply.Applier, reader asset.ScenarioReader, values interface{}, files ...string) error {
+	for _, name := range files {
+		b, err := applier.MustTemplateAsset(reader, values, "", name)
+		if err != nil {
+			if clusteradmapply.IsEmptyAsset(err) {
+				continue
+			}
+			return err
+		}
+		j, err := reader.ToJSON(b)
+		if err != nil {
+			return err
+		}
+		required := &unstructured.Unstructured{}
+		if err := required.UnmarshalJSON(j); err != nil {
+			return err
+		}
+
+		existing := &unstructured.Unstructured{}
+		existing.SetGroupVersionKind(required.GroupVersionKind())
+		err = c.Get(ctx, client.ObjectKeyFromObject(required), existing)
+		switch {
+		case errors.IsNotFound(err):
+			actual := required.DeepCopy()
+			if err := c.Create(ctx, actual); err != nil {
+				return err
+			}
+			applier.GetCache().UpdateCachedResourceMetadata(required, actual)
+			continue
+		case err != nil:
+			return err
+		case applier.GetCache().SafeToSkipApply(required, existing):
+			continue
+		}
+
+		actual := required.DeepCopy()
+		actual.SetResourceVersion(existing.GetResourceVersion())
+		if err := c.Update(ctx, actual); err != nil {
+			return err
+		}
+		applier.GetCache().UpdateCachedResourceMetadata(required, actual)
+	}
+	return nil
+}
+
+// ApplyDirectly applies the standard kubernetes resources of the files rendered by the applier, with its owner and cache,
+// through the kube client. Like the applier, the metadata added by others is kept, but the requests are made with the context.
+func ApplyDirectly(ctx context.Context, kubeClient kubernetes.Interface, applier *clusteradmapply.Applier, reader asset.ScenarioReader, values interface{}, files ...string) error {
+	clients := resourceapply.NewClientHolder().WithKubernetes(kubeClient)
+	recorder := events.NewInMemoryRecorder(TracerName)
+	results := resourceapply.ApplyDirectly(ctx, clients, recorder, applier.GetCache(), func(name string) ([]byte, error) {
+		return applier.MustTemplateAsset(reader, values, "", name)
+	}, files...)
+	for _, result := range results {
+		if result.Error != nil && !clusteradmapply.IsEmptyAsset(result.Error) {
+			return fmt.Errorf("%q (%s): %w", result.File, result.Type, result.Error)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+// Copyright Red Hat
+
+package helpers
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clusteradmapply "open-cluster-management.io/clusteradm/pkg/helpers/apply"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/stolostron/cluster-registration-operator/resources"
+)
+
+type applyContextKey struct{}
+
+// contextClient counts the requests made with the context of the test.
+type contextClient struct {
+	client.Client
+	requests int
+	t        *testing.T
+}
+
+func (c *contextClient) checkContext(ctx context.Context) {
+	if ctx.Value(applyContextKey{}) == nil {
+		c.t.Fatalf("Request not made with the context of the caller")
+	}
+	c.requests++
+}
+
+func (c *contextClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object) error {
+	c.checkContext(ctx)
+	return c.Client.Get(ctx, key, obj)
+}
+
+func (c *contextClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	c.checkContext(ctx)
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+func (c *contextClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	c.checkContext(ctx)
+	return c.Client.Update(ctx, obj, opts...)
+}
+
+func TestApplyResources(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed to add the scheme: %s", err)
+	}
+	c := &contextClient{Client: fake.NewClientBuilder().WithScheme(scheme).Build(), t: t}
+	applier := clusteradmapply.NewApplierBuilder().Build()
+	ctx := context.WithValue(context.TODO(), applyContextKey{}, true)
+	values := struct {
+		Name          string
+		Namespace     string
+		ImportCommand string
+	}{Name: "c", Namespace: "ws", ImportCommand: "kubectl apply"}
+
+	apply := func() {
+		if err := ApplyResources(ctx, c, &applier, resources.GetScenarioResourcesReader(), values, "cluster-registration/import_configmap.yaml"); err != nil {
+			t.Fatalf("Failed to apply the resources: %s", err)
+		}
+	}
+	checkImportCommand := func(expected string) {
+		configMap := &corev1.ConfigMap{}
+		if err := c.Client.Get(context.TODO(), types.NamespacedName{Namespace: "ws", Name: "c-import"}, configMap); err != nil {
+			t.Fatalf("Failed to get the ConfigMap: %s", err)
+		}
+		if configMap.Data["importCommand"] != expected+"\n" {
+			t.Fatalf("Import command not as expected: %q", configMap.Data["importCommand"])
+		}
+	}
+
+	apply()
+	checkImportCommand("kubectl apply")
+	if c.requests != 2 {
+		t.Fatalf("Expected a get and a create, got %d requests", c.requests)
+	}
+
+	// The unchanged resources are not updated again
+	apply()
+	if c.requests != 3 {
+		t.Fatalf("Expected a get, got %d requests", c.requests-2)
+	}
+
+	values.ImportCommand = "oc apply"
+	apply()
+	checkImportCommand("oc apply")
+	if c.requests != 5 {
+		t.Fatalf("Expected a get and an update, got %d requests", c.requests-3)
+	}
+}
+
+func TestApplyDirectly(t *testing.T) {
+	kubeClient := kubefake.NewSimpleClientset(&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+		Namespace: "ws", Name: "c-import", Labels: map[string]string{"backup": "true"},
+	}})
+	applier := clusteradmapply.NewApplierBuilder().Build()
+	values := struct {
+		Name          string
+		Namespace     string
+		ImportCommand string
+	}{Name: "c", Namespace: "ws", ImportCommand: "kubectl apply"}
+
+	if err := ApplyDirectly(context.TODO(), kubeClient, &applier, resources.GetScenarioResourcesReader(), values, "cluster-registration/import_configmap.yaml"); err != nil {
+		t.Fatalf("Failed to apply the resources: %s", err)
+	}
+	configMap, err := kubeClient.CoreV1().ConfigMaps("ws").Get(context.TODO(), "c-import", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get the ConfigMap: %s", err)
+	}
+	if configMap.Data["importCommand"] != "kubectl apply\n" {
+		t.Fatalf("Import command not as expected: %q", configMap.Data["importCommand"])
+	}
+	// The metadata added by others is kept
+	if configMap.Labels["backup"] != "true" {
+		t.Fatalf("Labels not as expected: %v", configMap.Labels)
+	}
+
+	if err := ApplyDirectly(context.TODO(), kubeClient, &applier, resources.GetScenarioResourcesReader(), values, "cluster-registration/missing.yaml"); err == nil {
+		t.Fatalf("Expected an error applying a missing file")
+	}
+}
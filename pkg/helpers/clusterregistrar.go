@@ -0,0 +1,28 @@
+// Copyright Red Hat
+
+package helpers
+
+import (
+	"sort"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	singaporev1alpha1 "github.com/stolostron/cluster-registration-operator/api/singapore/v1alpha1"
+)
+
+// GetActiveClusterRegistrar returns the ClusterRegistrar the installation is managed by, the oldest one,
+// or nil when there is none. The webhook denies creating a second ClusterRegistrar, but others can be
+// created while the webhook is not installed.
+func GetActiveClusterRegistrar(clusterRegistrars []singaporev1alpha1.ClusterRegistrar) *singaporev1alpha1.ClusterRegistrar {
+	if len(clusterRegistrars) == 0 {
+		return nil
+	}
+	items := append([]singaporev1alpha1.ClusterRegistrar{}, clusterRegistrars...)
+	sort.Slice(items, func(i, j int) bool {
+		if !items[i].CreationTimestamp.Equal(&items[j].CreationTimestamp) {
+			return items[i].CreationTimestamp.Before(&items[j].CreationTimestamp)
+		}
+		return client.ObjectKeyFromObject(&items[i]).String() < client.ObjectKeyFromObject(&items[j]).String()
+	})
+	return &items[0]
+}
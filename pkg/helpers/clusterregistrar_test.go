@@ -0,0 +1,31 @@
+// Copyright Red Hat
+
+package helpers
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	singaporev1alpha1 "github.com/stolostron/cluster-registration-operator/api/singapore/v1alpha1"
+)
+
+func TestGetActiveClusterRegistrar(t *testing.T) {
+	if GetActiveClusterRegistrar(nil) != nil {
+		t.Fatalf("No active ClusterRegistrar expected without ClusterRegistrars")
+	}
+	now := time.Now()
+	clusterRegistrars := []singaporev1alpha1.ClusterRegistrar{
+		{ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: "ns", CreationTimestamp: metav1.NewTime(now)}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "c", Namespace: "ns", CreationTimestamp: metav1.NewTime(now.Add(-time.Hour))}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "b", Namespace: "ns", CreationTimestamp: metav1.NewTime(now.Add(-time.Hour))}},
+	}
+	// The oldest one is active, by name when created at the same time
+	if active := GetActiveClusterRegistrar(clusterRegistrars); active.Name != "b" {
+		t.Fatalf("Active ClusterRegistrar not as expected. Expected b, actual %s", active.Name)
+	}
+	if clusterRegistrars[0].Name != "a" {
+		t.Fatalf("ClusterRegistrars not expected to be reordered")
+	}
+}
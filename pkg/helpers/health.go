@@ -0,0 +1,185 @@
+// Copyright Red Hat
+
+package helpers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	clusterapiv1 "open-cluster-management.io/api/cluster/v1"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+)
+
+// cacheSyncWaitTimeout bounds the wait for the hub caches in a probe.
+const cacheSyncWaitTimeout = time.Second
+
+// hubCacheHeartbeatResyncPeriod is the period the ManagedCluster informers of the hub caches resync the heartbeat,
+// so it is recorded while the informers run even when no ManagedCluster changes.
+const hubCacheHeartbeatResyncPeriod = time.Minute
+
+// WebhookAPIServiceName is the aggregated APIService through which the webhook is reached.
+const WebhookAPIServiceName string = "v1.admission.singapore.open-cluster-management.io"
+
+var apiServiceGVR = schema.GroupVersionResource{
+	Group:    "apiregistration.k8s.io",
+	Version:  "v1",
+	Resource: "apiservices",
+}
+
+// cacheSyncer waits for the informers of a cache to be synced.
+type cacheSyncer interface {
+	WaitForCacheSync(ctx context.Context) bool
+}
+
+func hubCacheSyncers(hubInstances []HubInstance) map[string]cacheSyncer {
+	syncers := map[string]cacheSyncer{}
+	for _, hubInstance := range hubInstances {
+		syncers[hubInstance.HubConfig.Name] = hubInstance.Cluster.GetCache()
+	}
+	return syncers
+}
+
+// unsyncedHubs returns the sorted names of the hubs whose caches are not synced.
+func unsyncedHubs(ctx context.Context, syncers map[string]cacheSyncer) []string {
+	var unsynced []string
+	for name, syncer := range syncers {
+		ctx, cancel := context.WithTimeout(ctx, cacheSyncWaitTimeout)
+		synced := syncer.WaitForCacheSync(ctx)
+		cancel()
+		if !synced {
+			unsynced = append(unsynced, name)
+		}
+	}
+	sort.Strings(unsynced)
+	return unsynced
+}
+
+// HubCachesSyncedChecker is a readiness check failing until the caches of all the hubs are synced.
+// The hub caches are started on all the replicas, so the replicas which are not the leader are ready too.
+func HubCachesSyncedChecker(hubInstances []HubInstance) healthz.Checker {
+	return newHubCachesSyncedChecker(hubCacheSyncers(hubInstances))
+}
+
+func newHubCachesSyncedChecker(syncers map[string]cacheSyncer) healthz.Checker {
+	return func(req *http.Request) error {
+		if unsynced := unsyncedHubs(req.Context(), syncers); len(unsynced) != 0 {
+			return fmt.Errorf("the caches of the hubs %s are not synced", strings.Join(unsynced, ", "))
+		}
+		return nil
+	}
+}
+
+// hubCacheHeartbeat is an event handler of the ManagedCluster informer of a hub cache recording the time of its last event.
+// The informer resyncs the handler every hubCacheHeartbeatResyncPeriod, the heartbeat stops when the informer stops.
+type hubCacheHeartbeat struct {
+	lock    sync.Mutex
+	now     func() time.Time
+	last    time.Time
+	objects int
+}
+
+func (h *hubCacheHeartbeat) beat(delta int) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.last = h.now()
+	h.objects += delta
+}
+
+func (h *hubCacheHeartbeat) OnAdd(obj interface{}) { h.beat(1) }
+
+func (h *hubCacheHeartbeat) OnUpdate(oldObj, newObj interface{}) { h.beat(0) }
+
+func (h *hubCacheHeartbeat) OnDelete(obj interface{}) { h.beat(-1) }
+
+// stale returns true when no event was received within the timeout. The informer of a hub without
+// ManagedCluster has nothing to resync, its heartbeat is never stale.
+func (h *hubCacheHeartbeat) stale(timeout time.Duration) bool {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	return h.objects > 0 && h.now().Sub(h.last) > timeout
+}
+
+// HubCachesStuckChecker is a liveness check failing when the caches of a hub are not synced within the sync timeout
+// after the start, or when the ManagedCluster informer of a synced hub cache sends no event within the heartbeat timeout,
+// which happens when its informers are stuck. It must be created before the manager starts the hub caches.
+func HubCachesStuckChecker(ctx context.Context, hubInstances []HubInstance, syncTimeout, heartbeatTimeout time.Duration) (healthz.Checker, error) {
+	heartbeats := map[string]*hubCacheHeartbeat{}
+	for _, hubInstance := range hubInstances {
+		informer, err := hubInstance.Cluster.GetCache().GetInformer(ctx, &clusterapiv1.ManagedCluster{})
+		if err != nil {
+			return nil, err
+		}
+		heartbeat := &hubCacheHeartbeat{now: time.Now}
+		informer.AddEventHandlerWithResyncPeriod(heartbeat, hubCacheHeartbeatResyncPeriod)
+		heartbeats[hubInstance.HubConfig.Name] = heartbeat
+	}
+	return newHubCachesStuckChecker(hubCacheSyncers(hubInstances), heartbeats, syncTimeout, heartbeatTimeout, time.Now), nil
+}
+
+func newHubCachesStuckChecker(syncers map[string]cacheSyncer, heartbeats map[string]*hubCacheHeartbeat,
+	syncTimeout, heartbeatTimeout time.Duration, now func() time.Time) healthz.Checker {
+	start := now()
+	var lock sync.Mutex
+	synced := false
+	return func(req *http.Request) error {
+		lock.Lock()
+		defer lock.Unlock()
+		if !synced {
+			unsynced := unsyncedHubs(req.Context(), syncers)
+			if len(unsynced) != 0 {
+				if now().Sub(start) > syncTimeout {
+					return fmt.Errorf("the caches of the hubs %s are not synced after %s", strings.Join(unsynced, ", "), syncTimeout)
+				}
+				return nil
+			}
+			synced = true
+		}
+		var stale []string
+		for name, heartbeat := range heartbeats {
+			if heartbeat.stale(heartbeatTimeout) {
+				stale = append(stale, name)
+			}
+		}
+		if len(stale) != 0 {
+			sort.Strings(stale)
+			return fmt.Errorf("the caches of the hubs %s received no event for %s", strings.Join(stale, ", "), heartbeatTimeout)
+		}
+		return nil
+	}
+}
+
+// APIServiceAvailableChecker is a readiness check failing when the APIService is not available,
+// so the manager is ready only when the webhook validating its objects is reachable.
+func APIServiceAvailableChecker(dynamicClient dynamic.Interface, name string) healthz.Checker {
+	return func(req *http.Request) error {
+		apiService, err := dynamicClient.Resource(apiServiceGVR).Get(req.Context(), name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return fmt.Errorf("APIService %s not found", name)
+		}
+		if err != nil {
+			return err
+		}
+		conditions, _, _ := unstructured.NestedSlice(apiService.Object, "status", "conditions")
+		for _, c := range conditions {
+			condition, ok := c.(map[string]interface{})
+			if !ok || condition["type"] != "Available" {
+				continue
+			}
+			if condition["status"] == "True" {
+				return nil
+			}
+			return fmt.Errorf("APIService %s not available: %v", name, condition["message"])
+		}
+		return fmt.Errorf("APIService %s not available", name)
+	}
+}
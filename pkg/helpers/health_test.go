@@ -0,0 +1,121 @@
+// Copyright Red Hat
+
+package helpers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+type fakeCacheSyncer bool
+
+func (s fakeCacheSyncer) WaitForCacheSync(ctx context.Context) bool {
+	return bool(s)
+}
+
+func newProbeRequest(t *testing.T) *http.Request {
+	req, err := http.NewRequest(http.MethodGet, "/readyz", nil)
+	if err != nil {
+		t.Fatalf("Failed to create request: %s", err)
+	}
+	return req
+}
+
+func TestHubCachesSyncedChecker(t *testing.T) {
+	checker := newHubCachesSyncedChecker(map[string]cacheSyncer{"hub-1": fakeCacheSyncer(true), "hub-2": fakeCacheSyncer(false)})
+	if err := checker(newProbeRequest(t)); err == nil {
+		t.Fatalf("Ready but the caches of hub-2 are not synced.")
+	}
+
+	checker = newHubCachesSyncedChecker(map[string]cacheSyncer{"hub-1": fakeCacheSyncer(true), "hub-2": fakeCacheSyncer(true)})
+	if err := checker(newProbeRequest(t)); err != nil {
+		t.Fatalf("Not ready but the caches are synced: %s", err)
+	}
+}
+
+func TestHubCachesStuckChecker(t *testing.T) {
+	now := time.Now()
+	syncers := map[string]cacheSyncer{"hub-1": fakeCacheSyncer(false)}
+	checker := newHubCachesStuckChecker(syncers, nil, time.Minute, time.Minute, func() time.Time { return now })
+	if err := checker(newProbeRequest(t)); err != nil {
+		t.Fatalf("Not live but the caches are syncing: %s", err)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if err := checker(newProbeRequest(t)); err == nil {
+		t.Fatalf("Live but the caches are stuck.")
+	}
+
+	syncers["hub-1"] = fakeCacheSyncer(true)
+	if err := checker(newProbeRequest(t)); err != nil {
+		t.Fatalf("Not live but the caches are synced: %s", err)
+	}
+}
+
+func TestHubCachesStuckCheckerHeartbeat(t *testing.T) {
+	now := time.Now()
+	clock := func() time.Time { return now }
+	heartbeats := map[string]*hubCacheHeartbeat{"hub-1": {now: clock}, "hub-2": {now: clock}}
+	syncers := map[string]cacheSyncer{"hub-1": fakeCacheSyncer(true), "hub-2": fakeCacheSyncer(true)}
+	checker := newHubCachesStuckChecker(syncers, heartbeats, time.Minute, 5*time.Minute, clock)
+
+	heartbeats["hub-1"].OnAdd(nil)
+	heartbeats["hub-2"].OnAdd(nil)
+	if err := checker(newProbeRequest(t)); err != nil {
+		t.Fatalf("Not live but the caches are synced: %s", err)
+	}
+
+	// The informer of hub-1 keeps resyncing, the one of hub-2 stopped after the sync
+	for i := 0; i < 6; i++ {
+		now = now.Add(time.Minute)
+		heartbeats["hub-1"].OnUpdate(nil, nil)
+	}
+	if err := checker(newProbeRequest(t)); err == nil || !strings.Contains(err.Error(), "hub-2") || strings.Contains(err.Error(), "hub-1") {
+		t.Fatalf("Expected the cache of hub-2 to be stale: %v", err)
+	}
+
+	// A hub without ManagedCluster has no event to resync
+	heartbeats["hub-2"].OnDelete(nil)
+	now = now.Add(time.Hour)
+	heartbeats["hub-1"].OnUpdate(nil, nil)
+	if err := checker(newProbeRequest(t)); err != nil {
+		t.Fatalf("Not live but the cache of the hub without ManagedCluster is expected to be live: %s", err)
+	}
+}
+
+func TestAPIServiceAvailableChecker(t *testing.T) {
+	newAPIService := func(status string) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "apiregistration.k8s.io/v1",
+			"kind":       "APIService",
+			"metadata":   map[string]interface{}{"name": WebhookAPIServiceName},
+			"status": map[string]interface{}{
+				"conditions": []interface{}{
+					map[string]interface{}{"type": "Available", "status": status},
+				},
+			},
+		}}
+	}
+
+	checker := APIServiceAvailableChecker(dynamicfake.NewSimpleDynamicClient(runtime.NewScheme()), WebhookAPIServiceName)
+	if err := checker(newProbeRequest(t)); err == nil {
+		t.Fatalf("Ready but the APIService doesn't exist.")
+	}
+
+	checker = APIServiceAvailableChecker(dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), newAPIService("False")), WebhookAPIServiceName)
+	if err := checker(newProbeRequest(t)); err == nil {
+		t.Fatalf("Ready but the APIService is not available.")
+	}
+
+	checker = APIServiceAvailableChecker(dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), newAPIService("True")), WebhookAPIServiceName)
+	if err := checker(newProbeRequest(t)); err != nil {
+		t.Fatalf("Not ready but the APIService is available: %s", err)
+	}
+}
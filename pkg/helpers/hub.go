@@ -5,9 +5,12 @@ package helpers
 import (
 	"context"
 	"errors"
+	"fmt"
+	"net/http"
 	"os"
 
 	singaporev1alpha1 "github.com/stolostron/cluster-registration-operator/api/singapore/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
@@ -23,6 +26,7 @@ import (
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/util/flowcontrol"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/cluster"
@@ -50,6 +54,8 @@ type HubInstance struct {
 	DynamicClient      dynamic.Interface
 	APIExtensionClient apiextensionsclient.Interface
 	HubApplier         clusteradmapply.Applier
+	// RateLimiter limits the requests of all the clients of the hub.
+	RateLimiter flowcontrol.RateLimiter
 }
 
 // GetConditionStatus returns the status for a given condition type and whether the condition was found
@@ -72,6 +78,27 @@ func GetHubCluster(workspace string, hubInstances []HubInstance) (HubInstance, e
 	return hubInstances[0], nil
 }
 
+// GetWorkspaceHubCluster returns the hub selected by the annotation of the workspace namespace, or the hub of the workspace
+func GetWorkspaceHubCluster(namespace *corev1.Namespace, hubInstances []HubInstance) (HubInstance, error) {
+	if hubConfigName := namespace.Annotations[WorkspaceHubConfigAnnotation]; len(hubConfigName) != 0 {
+		return GetHubClusterByName(hubConfigName, hubInstances)
+	}
+	return GetHubCluster(namespace.Name, hubInstances)
+}
+
+// GetHubClusterByName returns the hub instance created from the HubConfig with the given name
+func GetHubClusterByName(hubConfigName string, hubInstances []HubInstance) (HubInstance, error) {
+	for _, hubInstance := range hubInstances {
+		if hubInstance.HubConfig.Name == hubConfigName {
+			return hubInstance, nil
+		}
+	}
+	return HubInstance{}, fmt.Errorf("hubConfig %s not found", hubConfigName)
+}
+
+// WrapHubTransport, when set, wraps the transport of the clients of each hub, for example to instrument them.
+var WrapHubTransport func(hubConfigName string, rt http.RoundTripper) http.RoundTripper
+
 func GetHubClusters(mgr ctrl.Manager) ([]HubInstance, error) {
 	setupLog := ctrl.Log.WithName("setup")
 	setupLog.Info("setup registeredCluster manager")
@@ -109,7 +136,7 @@ func GetHubClusters(mgr ctrl.Manager) ([]HubInstance, error) {
 	hubInstances := make([]HubInstance, 0)
 
 	for _, hubConfigU := range hubConfigListU.Items {
-		setupLog.Info("convert to hubConfig structure", "name", hubConfigU.GetName())
+		setupLog.Info("convert to hubConfig structure", LogKeyHub, hubConfigU.GetName())
 		hubConfig := &singaporev1alpha1.HubConfig{}
 		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(hubConfigU.Object, hubConfig); err != nil {
 			return nil, err
@@ -121,16 +148,16 @@ func GetHubClusters(mgr ctrl.Manager) ([]HubInstance, error) {
 			metav1.GetOptions{})
 		if err != nil {
 			setupLog.Error(err, "unable to read kubeconfig secret for MCE cluster",
-				"HubConfig Name", hubConfig.GetName(),
-				"HubConfig Secret Name", hubConfig.Spec.KubeConfigSecretRef.Name)
+				LogKeyHub, hubConfig.GetName(),
+				"secret", hubConfig.Spec.KubeConfigSecretRef.Name)
 			return nil, err
 		}
 
 		kubeConfigData, ok := configSecret.Data["kubeconfig"]
 		if !ok {
 			setupLog.Error(err, "HubConfig secret missing kubeconfig data",
-				"HubConfig Name", hubConfig.GetName(),
-				"HubConfig Secret Name", hubConfig.Spec.KubeConfigSecretRef.Name)
+				LogKeyHub, hubConfig.GetName(),
+				"secret", hubConfig.Spec.KubeConfigSecretRef.Name)
 			return nil, errors.New("HubConfig secret missing kubeconfig data")
 		}
 
@@ -141,6 +168,22 @@ func GetHubClusters(mgr ctrl.Manager) ([]HubInstance, error) {
 			return nil, err
 		}
 
+		// The clients of the hub share its rate limiter
+		rateLimiter := newHubRateLimiter()
+		hubKubeconfig.QPS = hubClientQPS
+		hubKubeconfig.Burst = hubClientBurst
+		hubKubeconfig.RateLimiter = rateLimiter
+
+		hubName := hubConfig.Name
+		hubKubeconfig.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+			return TraceTransport(rt, TracingAttributeHub.String(hubName))
+		})
+		if WrapHubTransport != nil {
+			hubKubeconfig.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+				return WrapHubTransport(hubName, rt)
+			})
+		}
+
 		// Add MCE cluster
 		hubCluster, err := cluster.New(hubKubeconfig,
 			func(o *cluster.Options) {
@@ -161,7 +204,10 @@ func GetHubClusters(mgr ctrl.Manager) ([]HubInstance, error) {
 		kubeClient := kubernetes.NewForConfigOrDie(hubKubeconfig)
 		dynamicClient := dynamic.NewForConfigOrDie(hubKubeconfig)
 		apiExtensionClient := apiextensionsclient.NewForConfigOrDie(hubKubeconfig)
-		hubApplier := clusteradmapply.NewApplierBuilder().WithClient(kubeClient, apiExtensionClient, dynamicClient).Build()
+		hubApplier := clusteradmapply.NewApplierBuilder().
+			WithClient(kubeClient, apiExtensionClient, dynamicClient).
+			WithCache(NewResourceCache()).
+			Build()
 
 		hubInstance := HubInstance{
 			HubConfig:          hubConfig,
@@ -171,6 +217,7 @@ func GetHubClusters(mgr ctrl.Manager) ([]HubInstance, error) {
 			DynamicClient:      dynamicClient,
 			APIExtensionClient: apiExtensionClient,
 			HubApplier:         hubApplier,
+			RateLimiter:        rateLimiter,
 		}
 
 		hubInstances = append(hubInstances, hubInstance)
@@ -5,6 +5,8 @@ package helpers
 import (
 	"testing"
 
+	singaporev1alpha1 "github.com/stolostron/cluster-registration-operator/api/singapore/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -36,3 +38,37 @@ func TestGetConditionStatusNotFound(t *testing.T) {
 		t.Fatalf("Condition found but expected to be not found.")
 	}
 }
+
+func TestGetHubClusterByName(t *testing.T) {
+	hubInstances := []HubInstance{
+		{HubConfig: &singaporev1alpha1.HubConfig{ObjectMeta: metav1.ObjectMeta{Name: "hub-1"}}},
+		{HubConfig: &singaporev1alpha1.HubConfig{ObjectMeta: metav1.ObjectMeta{Name: "hub-2"}}},
+	}
+	hubInstance, err := GetHubClusterByName("hub-2", hubInstances)
+	if err != nil {
+		t.Fatalf("Hub not found as expected: %s", err)
+	}
+	if hubInstance.HubConfig.Name != "hub-2" {
+		t.Fatalf(`Hub not as expected. Expected %s, actual %s`, "hub-2", hubInstance.HubConfig.Name)
+	}
+	if _, err := GetHubClusterByName("hub-3", hubInstances); err == nil {
+		t.Fatalf("Hub found but expected to be not found.")
+	}
+}
+
+func TestGetWorkspaceHubCluster(t *testing.T) {
+	hubInstances := []HubInstance{
+		{HubConfig: &singaporev1alpha1.HubConfig{ObjectMeta: metav1.ObjectMeta{Name: "hub-1"}}},
+		{HubConfig: &singaporev1alpha1.HubConfig{ObjectMeta: metav1.ObjectMeta{Name: "hub-2"}}},
+	}
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ws"}}
+	hubInstance, err := GetWorkspaceHubCluster(namespace, hubInstances)
+	if err != nil || hubInstance.HubConfig.Name != "hub-1" {
+		t.Fatalf(`Hub of the workspace not as expected: %v, %v`, hubInstance.HubConfig, err)
+	}
+	namespace.Annotations = map[string]string{WorkspaceHubConfigAnnotation: "hub-2"}
+	hubInstance, err = GetWorkspaceHubCluster(namespace, hubInstances)
+	if err != nil || hubInstance.HubConfig.Name != "hub-2" {
+		t.Fatalf(`Hub of the annotation not as expected: %v, %v`, hubInstance.HubConfig, err)
+	}
+}
@@ -0,0 +1,242 @@
+// Copyright Red Hat
+
+package helpers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	goflag "flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/go-logr/zapr"
+	"github.com/spf13/pflag"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"k8s.io/apimachinery/pkg/runtime"
+	crzap "sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+// The keys of the values identifying the objects in the logs.
+const (
+	LogKeyWorkspace         = "workspace"
+	LogKeyRegisteredCluster = "registeredCluster"
+	LogKeyHub               = "hub"
+	LogKeyManagedCluster    = "managedCluster"
+)
+
+const (
+	LogFormatJSON    = "json"
+	LogFormatConsole = "console"
+)
+
+// LoggingOptions are the options of the zap logger of the manager and the installer.
+type LoggingOptions struct {
+	// Format is the encoding of the logs, json or console.
+	Format string
+	// Level is the minimum level logged, debug, info, error or a verbosity. When empty, the klog verbosity is used.
+	Level string
+	// StacktraceLevel is the level from which the stack traces are logged.
+	StacktraceLevel string
+	// Sampling drops the repeated messages beyond 100 per second.
+	Sampling bool
+	// Output is where the logs are written, stderr by default.
+	Output io.Writer
+}
+
+// AddFlags adds the flags of the logging options.
+func (o *LoggingOptions) AddFlags(flags *pflag.FlagSet) {
+	flags.StringVar(&o.Format, "log-format", LogFormatJSON, "The encoding of the logs, json or console.")
+	flags.StringVar(&o.Level, "log-level", "",
+		"The minimum level logged, debug, info, error or a verbosity such as 2. Defaults to the --v verbosity.")
+	flags.StringVar(&o.StacktraceLevel, "log-stacktrace-level", "error",
+		"The level from which the stack traces are logged, info, error or panic.")
+	flags.BoolVar(&o.Sampling, "log-sampling", false,
+		"Drop the repeated messages beyond 100 per second, ignored for the verbosities above 1.")
+}
+
+// NewLogger returns the logger configured by the options, redacting the credentials from the logs.
+func (o *LoggingOptions) NewLogger() (logr.Logger, error) {
+	level, err := o.level()
+	if err != nil {
+		return logr.Discard(), err
+	}
+	stacktraceLevel, err := parseLogLevel(o.StacktraceLevel)
+	if err != nil {
+		return logr.Discard(), err
+	}
+
+	var encoder zapcore.Encoder
+	switch o.Format {
+	case LogFormatJSON:
+		config := zap.NewProductionEncoderConfig()
+		config.EncodeTime = zapcore.ISO8601TimeEncoder
+		encoder = zapcore.NewJSONEncoder(config)
+	case LogFormatConsole:
+		config := zap.NewDevelopmentEncoderConfig()
+		encoder = zapcore.NewConsoleEncoder(config)
+	default:
+		return logr.Discard(), fmt.Errorf("unknown log format %q, must be %s or %s", o.Format, LogFormatJSON, LogFormatConsole)
+	}
+
+	output := o.Output
+	if output == nil {
+		output = os.Stderr
+	}
+	sink := zapcore.Lock(zapcore.AddSync(output))
+
+	// The objects are logged by kind, namespace and name only
+	var core zapcore.Core = zapcore.NewCore(&crzap.KubeAwareEncoder{Encoder: encoder}, sink, level)
+	core = &redactingCore{Core: core}
+	// The sampler doesn't support the levels below debug
+	if o.Sampling && !level.Enabled(zapcore.Level(-2)) {
+		core = zapcore.NewSamplerWithOptions(core, time.Second, 100, 100)
+	}
+	return zapr.NewLogger(zap.New(core, zap.AddCaller(), zap.AddStacktrace(stacktraceLevel), zap.ErrorOutput(sink))), nil
+}
+
+// level returns the level of the options, or the level of the klog verbosity set by the installer.
+func (o *LoggingOptions) level() (zapcore.Level, error) {
+	if len(o.Level) != 0 {
+		return parseLogLevel(o.Level)
+	}
+	if v := goflag.Lookup("v"); v != nil {
+		if verbosity, err := strconv.Atoi(v.Value.String()); err == nil && verbosity > 0 {
+			return zapcore.Level(-verbosity), nil
+		}
+	}
+	return zapcore.InfoLevel, nil
+}
+
+// parseLogLevel parses a zap level name or a logr verbosity.
+func parseLogLevel(level string) (zapcore.Level, error) {
+	if verbosity, err := strconv.Atoi(level); err == nil {
+		if verbosity < 0 {
+			return zapcore.InfoLevel, fmt.Errorf("the log verbosity must be positive, got %d", verbosity)
+		}
+		return zapcore.Level(-verbosity), nil
+	}
+	var l zapcore.Level
+	if err := l.UnmarshalText([]byte(strings.ToLower(level))); err != nil {
+		return zapcore.InfoLevel, fmt.Errorf("unknown log level %q", level)
+	}
+	return l, nil
+}
+
+const redacted = "REDACTED"
+
+// secretPatterns match the credentials of the kubeconfigs and of the import secrets.
+var secretPatterns = []struct {
+	re          *regexp.Regexp
+	replacement string
+}{
+	// The values of the credential keys of a kubeconfig, in YAML or JSON
+	{regexp.MustCompile(`(?i)((?:token|client-key-data|client-certificate-data|password)\\?"?\s*[:=]\s*\\?"?)[^\s"\\,}]+`), "${1}" + redacted},
+	{regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9\-._~+/]+=*`), "${1}" + redacted},
+	// The service account tokens
+	{regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`), redacted},
+	// The base64 encoded data, such as the kubeconfigs and the manifests of the import secrets
+	{regexp.MustCompile(`[A-Za-z0-9+/]{100,}={0,2}`), redacted},
+}
+
+// RedactSecrets replaces the credentials in the text.
+func RedactSecrets(text string) string {
+	for _, pattern := range secretPatterns {
+		text = pattern.re.ReplaceAllString(text, pattern.replacement)
+	}
+	return text
+}
+
+// redactingCore redacts the credentials from the messages and the values of the logs.
+type redactingCore struct {
+	zapcore.Core
+}
+
+func (c *redactingCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redactingCore{Core: c.Core.With(redactFields(fields))}
+}
+
+func (c *redactingCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+func (c *redactingCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	entry.Message = RedactSecrets(entry.Message)
+	return c.Core.Write(entry, redactFields(fields))
+}
+
+func redactFields(fields []zapcore.Field) []zapcore.Field {
+	redactedFields := make([]zapcore.Field, len(fields))
+	for i, field := range fields {
+		redactedFields[i] = redactField(field)
+	}
+	return redactedFields
+}
+
+// redactField redacts the value of the field, the values containing credentials are replaced by their redacted text.
+func redactField(field zapcore.Field) zapcore.Field {
+	var text string
+	switch field.Type {
+	case zapcore.StringType:
+		field.String = RedactSecrets(field.String)
+		return field
+	case zapcore.ErrorType:
+		text = field.Interface.(error).Error()
+	case zapcore.StringerType:
+		text = field.Interface.(fmt.Stringer).String()
+	case zapcore.BinaryType:
+		// The binary values are logged base64 encoded
+		b := field.Interface.([]byte)
+		if encoded := base64.StdEncoding.EncodeToString(b); RedactSecrets(encoded) != encoded {
+			return zap.String(field.Key, redacted)
+		}
+		text = string(b)
+	case zapcore.ByteStringType:
+		text = string(field.Interface.([]byte))
+	case zapcore.ObjectMarshalerType:
+		enc := zapcore.NewMapObjectEncoder()
+		if err := field.Interface.(zapcore.ObjectMarshaler).MarshalLogObject(enc); err != nil {
+			return field
+		}
+		b, err := json.Marshal(enc.Fields)
+		if err != nil {
+			return field
+		}
+		text = string(b)
+	case zapcore.ArrayMarshalerType:
+		enc := zapcore.NewMapObjectEncoder()
+		if err := enc.AddArray(field.Key, field.Interface.(zapcore.ArrayMarshaler)); err != nil {
+			return field
+		}
+		b, err := json.Marshal(enc.Fields[field.Key])
+		if err != nil {
+			return field
+		}
+		text = string(b)
+	case zapcore.ReflectType:
+		// The objects are logged by kind, namespace and name only
+		if _, ok := field.Interface.(runtime.Object); ok {
+			return field
+		}
+		b, err := json.Marshal(field.Interface)
+		if err != nil {
+			return field
+		}
+		text = string(b)
+	default:
+		return field
+	}
+	if redactedText := RedactSecrets(text); redactedText != text {
+		return zap.String(field.Key, redactedText)
+	}
+	return field
+}
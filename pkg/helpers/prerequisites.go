@@ -0,0 +1,247 @@
+// Copyright Red Hat
+
+package helpers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	singaporev1alpha1 "github.com/stolostron/cluster-registration-operator/api/singapore/v1alpha1"
+
+	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilversion "k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// hubPrerequisitesTimeout bounds the requests to the hub, which may be unreachable.
+const hubPrerequisitesTimeout = 10 * time.Second
+
+// managedServiceAccountComponent is the multicluster engine component installing the managed-serviceaccount addon.
+const managedServiceAccountComponent string = "managed-service-account"
+
+var (
+	// MultiClusterEngineGVR and MultiClusterHubGVR are the resources installing the multicluster engine on the hub.
+	MultiClusterEngineGVR = schema.GroupVersionResource{
+		Group:    "multicluster.openshift.io",
+		Version:  "v1",
+		Resource: "multiclusterengines",
+	}
+	MultiClusterHubGVR = schema.GroupVersionResource{
+		Group:    "operator.open-cluster-management.io",
+		Version:  "v1",
+		Resource: "multiclusterhubs",
+	}
+
+	// The minimal versions of the multicluster engine and of the advanced cluster management shipping it.
+	minMultiClusterEngineVersion = utilversion.MustParseGeneric("2.0.0")
+	minMultiClusterHubVersion    = utilversion.MustParseGeneric("2.5.0")
+
+	// HubRequiredCRDNames are the CRDs used by the manager on the hub.
+	HubRequiredCRDNames = []string{
+		"managedclusters.cluster.open-cluster-management.io",
+		"managedclustersets.cluster.open-cluster-management.io",
+		"manifestworks.work.open-cluster-management.io",
+		"managedserviceaccounts.authentication.open-cluster-management.io",
+	}
+)
+
+// HubPrerequisitesChecker checks the prerequisites of a hub.
+type HubPrerequisitesChecker struct {
+	DynamicClient      dynamic.Interface
+	APIExtensionClient apiextensionsclient.Interface
+}
+
+// NewHubPrerequisitesChecker returns a checker of the hub the kubeconfig gives access to.
+func NewHubPrerequisitesChecker(kubeConfig []byte) (*HubPrerequisitesChecker, error) {
+	hubKubeconfig, err := clientcmd.RESTConfigFromKubeConfig(kubeConfig)
+	if err != nil {
+		return nil, err
+	}
+	hubKubeconfig.Timeout = hubPrerequisitesTimeout
+	dynamicClient, err := dynamic.NewForConfig(hubKubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	apiExtensionClient, err := apiextensionsclient.NewForConfig(hubKubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	return &HubPrerequisitesChecker{
+		DynamicClient:      dynamicClient,
+		APIExtensionClient: apiExtensionClient,
+	}, nil
+}
+
+// Check returns a condition per prerequisite of the hub, the message of the unmet ones tells how to fix them.
+// The condition is unknown when the prerequisite can't be checked.
+func (c *HubPrerequisitesChecker) Check() []metav1.Condition {
+	mce, mceErr := c.getMultiClusterEngine()
+	return []metav1.Condition{
+		c.getMultiClusterEngineAvailableCondition(mce, mceErr),
+		getManagedServiceAccountEnabledCondition(mce, mceErr),
+		c.getRequiredCRDsInstalledCondition(),
+	}
+}
+
+// UnmetHubPrerequisites returns the unmet prerequisites of the conditions returned by Check.
+func UnmetHubPrerequisites(conditions []metav1.Condition) (unmet []string) {
+	for _, condition := range conditions {
+		if condition.Status != metav1.ConditionTrue {
+			unmet = append(unmet, condition.Type)
+		}
+	}
+	return unmet
+}
+
+// getMultiClusterEngine returns the MultiClusterEngine of the hub, nil if there is none.
+func (c *HubPrerequisitesChecker) getMultiClusterEngine() (*unstructured.Unstructured, error) {
+	mces, err := c.DynamicClient.Resource(MultiClusterEngineGVR).List(context.TODO(), metav1.ListOptions{})
+	switch {
+	case errors.IsNotFound(err):
+		return nil, nil
+	case err != nil:
+		return nil, err
+	case len(mces.Items) == 0:
+		return nil, nil
+	}
+	return &mces.Items[0], nil
+}
+
+func (c *HubPrerequisitesChecker) getMultiClusterEngineAvailableCondition(mce *unstructured.Unstructured, mceErr error) metav1.Condition {
+	conditionType := singaporev1alpha1.HubConfigConditionMultiClusterEngineAvailable
+	if mceErr != nil {
+		return newCheckFailedCondition(conditionType, "MultiClusterEngine", mceErr)
+	}
+	if mce != nil {
+		return newVersionCondition(conditionType, "MultiClusterEngine", mce, minMultiClusterEngineVersion)
+	}
+
+	// The advanced cluster management older than 2.5.0 doesn't install a MultiClusterEngine
+	mchs, err := c.DynamicClient.Resource(MultiClusterHubGVR).List(context.TODO(), metav1.ListOptions{})
+	switch {
+	case err != nil && !errors.IsNotFound(err):
+		return newCheckFailedCondition(conditionType, "MultiClusterHub", err)
+	case err == nil && len(mchs.Items) != 0:
+		return newVersionCondition(conditionType, "MultiClusterHub", &mchs.Items[0], minMultiClusterHubVersion)
+	}
+	return metav1.Condition{
+		Type:   conditionType,
+		Status: metav1.ConditionFalse,
+		Reason: "NotFound",
+		Message: fmt.Sprintf("No MultiClusterEngine or MultiClusterHub found on the hub, "+
+			"install the multicluster engine %s or later", minMultiClusterEngineVersion),
+	}
+}
+
+// newVersionCondition returns a true condition when the current version of the MultiClusterEngine or MultiClusterHub
+// is at least the minimal version.
+func newVersionCondition(conditionType, kind string, obj *unstructured.Unstructured, minVersion *utilversion.Version) metav1.Condition {
+	currentVersion, _, _ := unstructured.NestedString(obj.Object, "status", "currentVersion")
+	v, err := utilversion.ParseGeneric(currentVersion)
+	if err != nil {
+		return metav1.Condition{
+			Type:   conditionType,
+			Status: metav1.ConditionUnknown,
+			Reason: "VersionUnknown",
+			Message: fmt.Sprintf("%s %s has no valid current version %q, wait for its installation to complete",
+				kind, obj.GetName(), currentVersion),
+		}
+	}
+	if v.LessThan(minVersion) {
+		return metav1.Condition{
+			Type:    conditionType,
+			Status:  metav1.ConditionFalse,
+			Reason:  "UnsupportedVersion",
+			Message: fmt.Sprintf("%s %s version %s is not supported, upgrade it to %s or later", kind, obj.GetName(), v, minVersion),
+		}
+	}
+	return metav1.Condition{
+		Type:    conditionType,
+		Status:  metav1.ConditionTrue,
+		Reason:  "Available",
+		Message: fmt.Sprintf("%s %s version %s is installed", kind, obj.GetName(), v),
+	}
+}
+
+func getManagedServiceAccountEnabledCondition(mce *unstructured.Unstructured, mceErr error) metav1.Condition {
+	conditionType := singaporev1alpha1.HubConfigConditionManagedServiceAccountEnabled
+	if mceErr != nil {
+		return newCheckFailedCondition(conditionType, "MultiClusterEngine", mceErr)
+	}
+	if mce == nil {
+		return metav1.Condition{
+			Type:    conditionType,
+			Status:  metav1.ConditionFalse,
+			Reason:  "MultiClusterEngineNotFound",
+			Message: "No MultiClusterEngine found on the hub, install the multicluster engine and enable its managed-service-account component",
+		}
+	}
+	components, _, _ := unstructured.NestedSlice(mce.Object, "spec", "overrides", "components")
+	for _, c := range components {
+		component, ok := c.(map[string]interface{})
+		if !ok || component["name"] != managedServiceAccountComponent {
+			continue
+		}
+		if enabled, ok := component["enabled"].(bool); ok && enabled {
+			return metav1.Condition{
+				Type:    conditionType,
+				Status:  metav1.ConditionTrue,
+				Reason:  "Enabled",
+				Message: fmt.Sprintf("The %s component is enabled", managedServiceAccountComponent),
+			}
+		}
+	}
+	return metav1.Condition{
+		Type:   conditionType,
+		Status: metav1.ConditionFalse,
+		Reason: "Disabled",
+		Message: fmt.Sprintf("The %s component is not enabled, add {\"name\": %q, \"enabled\": true} "+
+			"to spec.overrides.components of MultiClusterEngine %s on the hub",
+			managedServiceAccountComponent, managedServiceAccountComponent, mce.GetName()),
+	}
+}
+
+func (c *HubPrerequisitesChecker) getRequiredCRDsInstalledCondition() metav1.Condition {
+	conditionType := singaporev1alpha1.HubConfigConditionRequiredCRDsInstalled
+	var missing []string
+	for _, name := range HubRequiredCRDNames {
+		_, err := c.APIExtensionClient.ApiextensionsV1().CustomResourceDefinitions().Get(context.TODO(), name, metav1.GetOptions{})
+		switch {
+		case errors.IsNotFound(err):
+			missing = append(missing, name)
+		case err != nil:
+			return newCheckFailedCondition(conditionType, "CustomResourceDefinition", err)
+		}
+	}
+	if len(missing) != 0 {
+		return metav1.Condition{
+			Type:   conditionType,
+			Status: metav1.ConditionFalse,
+			Reason: "NotFound",
+			Message: fmt.Sprintf("The CRDs %s are not installed on the hub, "+
+				"install the multicluster engine and enable its managed-service-account component", strings.Join(missing, ", ")),
+		}
+	}
+	return metav1.Condition{
+		Type:    conditionType,
+		Status:  metav1.ConditionTrue,
+		Reason:  "Installed",
+		Message: "The required CRDs are installed",
+	}
+}
+
+func newCheckFailedCondition(conditionType, kind string, err error) metav1.Condition {
+	return metav1.Condition{
+		Type:    conditionType,
+		Status:  metav1.ConditionUnknown,
+		Reason:  "CheckFailed",
+		Message: fmt.Sprintf("Failed to get the %s from the hub, check the hub is reachable with the kubeconfig of the HubConfig: %s", kind, err.Error()),
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright Red Hat
+
+package helpers
+
+import (
+	"testing"
+
+	singaporev1alpha1 "github.com/stolostron/cluster-registration-operator/api/singapore/v1alpha1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiextensionsfake "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/fake"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func newMultiClusterEngine(version string, managedServiceAccountEnabled bool) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "multicluster.openshift.io/v1",
+		"kind":       "MultiClusterEngine",
+		"metadata":   map[string]interface{}{"name": "multiclusterengine"},
+		"spec": map[string]interface{}{
+			"overrides": map[string]interface{}{
+				"components": []interface{}{
+					map[string]interface{}{"name": "managed-service-account", "enabled": managedServiceAccountEnabled},
+				},
+			},
+		},
+		"status": map[string]interface{}{"currentVersion": version},
+	}}
+}
+
+func newHubPrerequisitesChecker(crdNames []string, objects ...runtime.Object) *HubPrerequisitesChecker {
+	crds := []runtime.Object{}
+	for _, name := range crdNames {
+		crds = append(crds, &apiextensionsv1.CustomResourceDefinition{ObjectMeta: metav1.ObjectMeta{Name: name}})
+	}
+	return &HubPrerequisitesChecker{
+		DynamicClient: dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+			map[schema.GroupVersionResource]string{
+				MultiClusterEngineGVR: "MultiClusterEngineList",
+				MultiClusterHubGVR:    "MultiClusterHubList",
+			}, objects...),
+		APIExtensionClient: apiextensionsfake.NewSimpleClientset(crds...),
+	}
+}
+
+func checkPrerequisite(t *testing.T, conditions []metav1.Condition, conditionType string, status metav1.ConditionStatus, reason string) {
+	condition := meta.FindStatusCondition(conditions, conditionType)
+	if condition == nil {
+		t.Fatalf("Condition %s not found", conditionType)
+	}
+	if condition.Status != status || condition.Reason != reason {
+		t.Fatalf("Condition %s not as expected. Expected %s/%s, actual %s/%s: %s",
+			conditionType, status, reason, condition.Status, condition.Reason, condition.Message)
+	}
+}
+
+func TestHubPrerequisitesMet(t *testing.T) {
+	checker := newHubPrerequisitesChecker(HubRequiredCRDNames, newMultiClusterEngine("2.0.1", true))
+	conditions := checker.Check()
+	if unmet := UnmetHubPrerequisites(conditions); len(unmet) != 0 {
+		t.Fatalf("Prerequisites not met: %v", conditions)
+	}
+}
+
+func TestHubPrerequisitesNotMet(t *testing.T) {
+	checker := newHubPrerequisitesChecker(HubRequiredCRDNames[:3], newMultiClusterEngine("1.0.0", false))
+	conditions := checker.Check()
+	checkPrerequisite(t, conditions, singaporev1alpha1.HubConfigConditionMultiClusterEngineAvailable, metav1.ConditionFalse, "UnsupportedVersion")
+	checkPrerequisite(t, conditions, singaporev1alpha1.HubConfigConditionManagedServiceAccountEnabled, metav1.ConditionFalse, "Disabled")
+	checkPrerequisite(t, conditions, singaporev1alpha1.HubConfigConditionRequiredCRDsInstalled, metav1.ConditionFalse, "NotFound")
+	if unmet := UnmetHubPrerequisites(conditions); len(unmet) != 3 {
+		t.Fatalf("Unmet prerequisites not as expected: %v", unmet)
+	}
+}
+
+func TestHubPrerequisitesNoMultiClusterEngine(t *testing.T) {
+	conditions := newHubPrerequisitesChecker(nil).Check()
+	checkPrerequisite(t, conditions, singaporev1alpha1.HubConfigConditionMultiClusterEngineAvailable, metav1.ConditionFalse, "NotFound")
+	checkPrerequisite(t, conditions, singaporev1alpha1.HubConfigConditionManagedServiceAccountEnabled, metav1.ConditionFalse, "MultiClusterEngineNotFound")
+
+	mch := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "operator.open-cluster-management.io/v1",
+		"kind":       "MultiClusterHub",
+		"metadata":   map[string]interface{}{"name": "multiclusterhub", "namespace": "open-cluster-management"},
+		"status":     map[string]interface{}{"currentVersion": "2.4.3"},
+	}}
+	conditions = newHubPrerequisitesChecker(nil, mch).Check()
+	checkPrerequisite(t, conditions, singaporev1alpha1.HubConfigConditionMultiClusterEngineAvailable, metav1.ConditionFalse, "UnsupportedVersion")
+}
@@ -0,0 +1,67 @@
+// Copyright Red Hat
+
+package helpers
+
+import (
+	"fmt"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/util/flowcontrol"
+
+	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
+	clusteradmapply "open-cluster-management.io/clusteradm/pkg/helpers/apply"
+)
+
+const (
+	// DefaultMaxConcurrentReconciles is the default number of RegisteredClusters reconciled in parallel.
+	DefaultMaxConcurrentReconciles int = 1
+	// DefaultHubClientQPS is the default number of requests per second sent to each hub.
+	DefaultHubClientQPS float32 = 20
+	// DefaultHubClientBurst is the default number of requests sent at once to each hub.
+	DefaultHubClientBurst int = 50
+)
+
+var (
+	hubClientQPS   = DefaultHubClientQPS
+	hubClientBurst = DefaultHubClientBurst
+)
+
+// SetHubClientRateLimits sets the rate of the requests sent to each hub by the clients of the HubInstances.
+func SetHubClientRateLimits(qps float32, burst int) error {
+	if qps <= 0 || burst <= 0 {
+		return fmt.Errorf("the hub QPS and burst must be positive, got %v and %d", qps, burst)
+	}
+	hubClientQPS = qps
+	hubClientBurst = burst
+	return nil
+}
+
+// newHubRateLimiter returns the token bucket limiter shared by the clients of a hub.
+func newHubRateLimiter() flowcontrol.RateLimiter {
+	return flowcontrol.NewTokenBucketRateLimiter(hubClientQPS, hubClientBurst)
+}
+
+// syncResourceCache is a ResourceCache safe for concurrent use, the cache of library-go is not
+// and the appliers of a hub are shared by the reconciliations running in parallel.
+type syncResourceCache struct {
+	lock  sync.Mutex
+	cache resourceapply.ResourceCache
+}
+
+// NewResourceCache returns a cache of the applied resources safe for concurrent use.
+func NewResourceCache() resourceapply.ResourceCache {
+	return &syncResourceCache{cache: clusteradmapply.NewResourceCache()}
+}
+
+func (c *syncResourceCache) UpdateCachedResourceMetadata(required runtime.Object, actual runtime.Object) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.cache.UpdateCachedResourceMetadata(required, actual)
+}
+
+func (c *syncResourceCache) SafeToSkipApply(required runtime.Object, existing runtime.Object) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.cache.SafeToSkipApply(required, existing)
+}
@@ -0,0 +1,44 @@
+// Copyright Red Hat
+
+package helpers
+
+import (
+	"sync"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSetHubClientRateLimits(t *testing.T) {
+	defer func() {
+		hubClientQPS = DefaultHubClientQPS
+		hubClientBurst = DefaultHubClientBurst
+	}()
+
+	if err := SetHubClientRateLimits(0, 10); err == nil {
+		t.Fatalf("Expected an error for a null QPS")
+	}
+	if err := SetHubClientRateLimits(5, 10); err != nil {
+		t.Fatalf("Failed to set the rate limits: %s", err)
+	}
+	if qps := newHubRateLimiter().QPS(); qps != 5 {
+		t.Fatalf("QPS not as expected. Expected %v, actual %v", 5, qps)
+	}
+}
+
+func TestResourceCacheConcurrentUse(t *testing.T) {
+	cache := NewResourceCache()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			required := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "c", Namespace: "ns"}}
+			actual := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "c", Namespace: "ns", ResourceVersion: "1"}}
+			cache.UpdateCachedResourceMetadata(required, actual)
+			cache.SafeToSkipApply(required, actual)
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,86 @@
+// Copyright Red Hat
+
+package helpers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpgrpc"
+	"go.opentelemetry.io/otel/propagation"
+	sdkresource "go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/semconv"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracerName is the name of the tracer of the operator spans.
+const TracerName = "github.com/stolostron/cluster-registration-operator"
+
+// The attributes of the operator spans.
+const (
+	TracingAttributeHub               = attribute.Key("hub")
+	TracingAttributeWorkspace         = attribute.Key("workspace")
+	TracingAttributeRegisteredCluster = attribute.Key("registered_cluster")
+)
+
+// TracingOptions configures the export of the traces to an OTLP collector.
+type TracingOptions struct {
+	// Endpoint is the host:port of the OTLP gRPC collector, the traces are not exported when it is empty.
+	Endpoint string
+	// Insecure disables the TLS of the connection to the collector.
+	Insecure bool
+	// SamplingRatio is the fraction of the traces sampled, between 0 and 1.
+	SamplingRatio float64
+}
+
+// SetupTracing sets the global tracer provider exporting the spans of the service to the OTLP collector,
+// and returns the function flushing the pending spans and stopping the export.
+func SetupTracing(ctx context.Context, serviceName string, options TracingOptions) (func(context.Context) error, error) {
+	if len(options.Endpoint) == 0 {
+		return func(context.Context) error { return nil }, nil
+	}
+	if options.SamplingRatio < 0 || options.SamplingRatio > 1 {
+		return nil, fmt.Errorf("the tracing sampling ratio must be between 0 and 1, got %v", options.SamplingRatio)
+	}
+
+	driverOptions := []otlpgrpc.Option{otlpgrpc.WithEndpoint(options.Endpoint)}
+	if options.Insecure {
+		driverOptions = append(driverOptions, otlpgrpc.WithInsecure())
+	}
+	// The exporter connects in the background, the spans are dropped while the collector is unreachable
+	exporter, err := otlp.NewExporter(ctx, otlpgrpc.NewDriver(driverOptions...))
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(options.SamplingRatio))),
+		sdktrace.WithResource(sdkresource.NewWithAttributes(semconv.ServiceNameKey.String(serviceName))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	return provider.Shutdown, nil
+}
+
+// Tracer returns the tracer of the operator spans, they are dropped unless the tracing is set up.
+func Tracer() trace.Tracer {
+	return otel.Tracer(TracerName)
+}
+
+// TraceTransport wraps the transport of a client to record a span with the attributes for each request
+// made within a span, the requests of the informers are not traced.
+func TraceTransport(rt http.RoundTripper, attributes ...attribute.KeyValue) http.RoundTripper {
+	return otelhttp.NewTransport(rt,
+		otelhttp.WithSpanOptions(trace.WithAttributes(attributes...)),
+		otelhttp.WithFilter(func(r *http.Request) bool {
+			return trace.SpanContextFromContext(r.Context()).IsValid()
+		}),
+	)
+}
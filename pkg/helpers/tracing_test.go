@@ -0,0 +1,128 @@
+// Copyright Red Hat
+
+package helpers
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	collectortracev1 "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracev1 "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/grpc"
+)
+
+// testCollector is an in-process OTLP collector keeping the spans it receives.
+type testCollector struct {
+	collectortracev1.UnimplementedTraceServiceServer
+	lock  sync.Mutex
+	spans []*tracev1.Span
+}
+
+func (c *testCollector) Export(_ context.Context, req *collectortracev1.ExportTraceServiceRequest) (*collectortracev1.ExportTraceServiceResponse, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for _, resourceSpans := range req.ResourceSpans {
+		for _, librarySpans := range resourceSpans.InstrumentationLibrarySpans {
+			c.spans = append(c.spans, librarySpans.Spans...)
+		}
+	}
+	return &collectortracev1.ExportTraceServiceResponse{}, nil
+}
+
+func (c *testCollector) getSpan(name string) *tracev1.Span {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for _, span := range c.spans {
+		if span.Name == name {
+			return span
+		}
+	}
+	return nil
+}
+
+func TestSetupTracingDisabled(t *testing.T) {
+	shutdown, err := SetupTracing(context.TODO(), "test", TracingOptions{})
+	if err != nil {
+		t.Fatalf("Failed to set up the tracing: %s", err)
+	}
+	if err := shutdown(context.TODO()); err != nil {
+		t.Fatalf("Failed to shut down the tracing: %s", err)
+	}
+	if _, err := SetupTracing(context.TODO(), "test", TracingOptions{Endpoint: "localhost:4317", SamplingRatio: 2}); err == nil {
+		t.Fatalf("Expected an error for a sampling ratio above 1")
+	}
+}
+
+func TestSetupTracingExport(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %s", err)
+	}
+	collector := &testCollector{}
+	server := grpc.NewServer()
+	collectortracev1.RegisterTraceServiceServer(server, collector)
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	defer server.Stop()
+
+	defer otel.SetTracerProvider(otel.GetTracerProvider())
+	shutdown, err := SetupTracing(context.TODO(), "test", TracingOptions{
+		Endpoint:      listener.Addr().String(),
+		Insecure:      true,
+		SamplingRatio: 1,
+	})
+	if err != nil {
+		t.Fatalf("Failed to set up the tracing: %s", err)
+	}
+
+	hub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer hub.Close()
+	hubClient := &http.Client{Transport: TraceTransport(http.DefaultTransport, TracingAttributeHub.String("hub-1"))}
+
+	// The requests made outside of a span are not traced
+	resp, err := hubClient.Get(hub.URL)
+	if err != nil {
+		t.Fatalf("Failed to send the request: %s", err)
+	}
+	resp.Body.Close()
+	ctx, span := Tracer().Start(context.TODO(), "reconcile")
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, hub.URL, nil)
+	resp, err = hubClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to send the request: %s", err)
+	}
+	// The span of the request ends when its body is closed
+	resp.Body.Close()
+	span.End()
+
+	if err := shutdown(context.TODO()); err != nil {
+		t.Fatalf("Failed to shut down the tracing: %s", err)
+	}
+
+	parent := collector.getSpan("reconcile")
+	if parent == nil {
+		t.Fatalf("Span reconcile not exported: %v", collector.spans)
+	}
+	request := collector.getSpan("GET")
+	if request == nil || string(request.ParentSpanId) != string(parent.SpanId) {
+		t.Fatalf("Span of the hub request not exported as child of the reconcile span: %v", collector.spans)
+	}
+	if len(collector.spans) != 2 {
+		t.Fatalf("Expected 2 spans, got %d", len(collector.spans))
+	}
+	found := false
+	for _, attribute := range request.Attributes {
+		if attribute.Key == string(TracingAttributeHub) && attribute.Value.GetStringValue() == "hub-1" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("Hub attribute not found: %v", request.Attributes)
+	}
+}
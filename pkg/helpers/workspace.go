@@ -2,7 +2,42 @@
 
 package helpers
 
+import (
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const (
+	WorkspaceProviderLabel      string = "toolchain.dev.openshift.com/provider"
+	WorkspaceProviderLabelValue string = "codeready-toolchain"
+
+	// WorkspaceHubConfigAnnotation selects the HubConfig of the hub of a workspace,
+	// changing it migrates the RegisteredClusters of the workspace to the new hub.
+	WorkspaceHubConfigAnnotation string = "singapore.open-cluster-management.io/hub-config-name"
+)
+
+var (
+	workspaceSelector = labels.SelectorFromSet(labels.Set{WorkspaceProviderLabel: WorkspaceProviderLabelValue})
+
+	// DefaultWorkspaceSelector selects the namespaces provided by the codeready-toolchain
+	DefaultWorkspaceSelector = workspaceSelector.String()
+)
+
 func ManagedClusterSetNameForWorkspace(workspaceName string) string {
 	// For now, workspaces are uniquely identified by their name. This may change.
 	return workspaceName
 }
+
+// SetWorkspaceSelector sets the label selector identifying the workspace namespaces
+func SetWorkspaceSelector(selector string) error {
+	s, err := labels.Parse(selector)
+	if err != nil {
+		return err
+	}
+	workspaceSelector = s
+	return nil
+}
+
+// IsWorkspace returns true if the namespace labels identify an AppStudio workspace
+func IsWorkspace(namespaceLabels map[string]string) bool {
+	return workspaceSelector.Matches(labels.Set(namespaceLabels))
+}
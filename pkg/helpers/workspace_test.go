@@ -13,3 +13,32 @@ func TestManagedClusterSetNameForWorkspace(t *testing.T) {
 		t.Fatalf(`ManagedClusterSet name is not as expected. Expected %s, actual %s`, workspaceName, name)
 	}
 }
+
+func TestIsWorkspace(t *testing.T) {
+	if !IsWorkspace(map[string]string{WorkspaceProviderLabel: WorkspaceProviderLabelValue}) {
+		t.Fatalf("Namespace not detected as workspace.")
+	}
+	if IsWorkspace(map[string]string{"foo": "bar"}) {
+		t.Fatalf("Namespace detected as workspace but expected not to be.")
+	}
+}
+
+func TestSetWorkspaceSelector(t *testing.T) {
+	defer func() {
+		if err := SetWorkspaceSelector(DefaultWorkspaceSelector); err != nil {
+			t.Fatalf("Failed to reset the workspace selector: %s", err)
+		}
+	}()
+	if err := SetWorkspaceSelector("tenant in (a,b)"); err != nil {
+		t.Fatalf("Failed to set the workspace selector: %s", err)
+	}
+	if !IsWorkspace(map[string]string{"tenant": "a"}) {
+		t.Fatalf("Namespace not detected as workspace.")
+	}
+	if IsWorkspace(map[string]string{WorkspaceProviderLabel: WorkspaceProviderLabelValue}) {
+		t.Fatalf("Namespace detected as workspace but expected not to be.")
+	}
+	if err := SetWorkspaceSelector("tenant in ("); err == nil {
+		t.Fatalf("Invalid selector accepted.")
+	}
+}
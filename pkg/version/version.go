@@ -0,0 +1,7 @@
+// Copyright Red Hat
+
+package version
+
+// Version is the version of the binary, it is set at build time with
+// -ldflags "-X github.com/stolostron/cluster-registration-operator/pkg/version.Version=<version>"
+var Version = "dev"
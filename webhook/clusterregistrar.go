@@ -0,0 +1,184 @@
+// Copyright Red Hat
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	singaporev1alpha1 "github.com/stolostron/cluster-registration-operator/api/singapore/v1alpha1"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+	"k8s.io/klog/v2"
+)
+
+// ClusterRegistrarAdmissionHook validates the ClusterRegistrars, only one ClusterRegistrar can exist per cluster.
+type ClusterRegistrarAdmissionHook struct {
+	ClusterRegistrarClient dynamic.NamespaceableResourceInterface
+	lock                   sync.RWMutex
+	initialized            bool
+}
+
+// ValidatingResource is called by generic-admission-server on startup to register the returned REST resource through which the
+// webhook is accessed by the kube apiserver.
+func (a *ClusterRegistrarAdmissionHook) ValidatingResource() (plural schema.GroupVersionResource, singular string) {
+	return schema.GroupVersionResource{
+			Group:    "admission." + GROUP_SUFFIX,
+			Version:  "v1",
+			Resource: "clusterregistrars",
+		},
+		"clusterregistrar"
+}
+
+// Validate is called by generic-admission-server when the registered REST resource above is called with an admission.k8s.io/v1 admission request.
+func (a *ClusterRegistrarAdmissionHook) Validate(admissionSpec *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	status := &admissionv1.AdmissionResponse{}
+	klog.V(4).Infof("ClusterRegistrar Validate %q operation for object %q, group: %s, resource: %s", admissionSpec.Operation, admissionSpec.Object, admissionSpec.Resource.Group, admissionSpec.Resource.Resource)
+
+	if !strings.HasSuffix(admissionSpec.Resource.Group, GROUP_SUFFIX) || admissionSpec.Resource.Resource != "clusterregistrars" {
+		status.Allowed = true
+		return status
+	}
+
+	if admissionSpec.Operation != admissionv1.Create && admissionSpec.Operation != admissionv1.Update {
+		status.Allowed = true
+		return status
+	}
+
+	clusterRegistrar := &singaporev1alpha1.ClusterRegistrar{}
+	if err := json.Unmarshal(admissionSpec.Object.Raw, clusterRegistrar); err != nil {
+		return badRequest(err)
+	}
+
+	klog.V(4).Infof("Validate webhook for ClusterRegistrar name: %s, namespace: %s", clusterRegistrar.Name, clusterRegistrar.Namespace)
+
+	var errs field.ErrorList
+	if admissionSpec.Operation == admissionv1.Create {
+		singletonErrs, err := a.validateSingleton(clusterRegistrar, field.NewPath("metadata", "name"))
+		if err != nil {
+			return internalError(err)
+		}
+		errs = append(errs, singletonErrs...)
+	}
+	errs = append(errs, validateRegistrationPolicy(&clusterRegistrar.Spec.RegistrationPolicy, field.NewPath("spec", "registrationPolicy"))...)
+	errs = append(errs, validateDeletionPolicy(&clusterRegistrar.Spec, field.NewPath("spec"))...)
+
+	if len(errs) != 0 {
+		statusErr := apierrors.NewInvalid(singaporev1alpha1.SchemeGroupVersion.WithKind("ClusterRegistrar").GroupKind(), clusterRegistrar.Name, errs)
+		status.Allowed = false
+		status.Result = &statusErr.ErrStatus
+		return status
+	}
+
+	status.Allowed = true
+	return status
+}
+
+// validateSingleton checks no other ClusterRegistrar exists in the cluster.
+func (a *ClusterRegistrarAdmissionHook) validateSingleton(clusterRegistrar *singaporev1alpha1.ClusterRegistrar, fldPath *field.Path) (field.ErrorList, error) {
+	clusterRegistrarList, err := a.ClusterRegistrarClient.List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, other := range clusterRegistrarList.Items {
+		if other.GetName() == clusterRegistrar.Name && other.GetNamespace() == clusterRegistrar.Namespace {
+			continue
+		}
+		return field.ErrorList{field.Forbidden(fldPath,
+			fmt.Sprintf("only one ClusterRegistrar is allowed per cluster, %s/%s already exists", other.GetNamespace(), other.GetName()))}, nil
+	}
+	return nil, nil
+}
+
+// validateDeletionPolicy checks the CRDs are not removed with the Retain deletion policy,
+// the removal of the CRDs would delete the RegisteredClusters it retains.
+func validateDeletionPolicy(spec *singaporev1alpha1.ClusterRegistrarSpec, fldPath *field.Path) field.ErrorList {
+	if spec.RemoveCRDs && spec.DeletionPolicy == singaporev1alpha1.DeletionPolicyRetain {
+		return field.ErrorList{field.Forbidden(fldPath.Child("removeCRDs"),
+			fmt.Sprintf("the CRDs can not be removed with the %s deletion policy, it would delete the RegisteredClusters", singaporev1alpha1.DeletionPolicyRetain))}
+	}
+	return nil
+}
+
+// validateRegistrationPolicy checks the allow-lists can be used to validate the RegisteredClusters.
+func validateRegistrationPolicy(policy *singaporev1alpha1.RegistrationPolicy, fldPath *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	for i, prefix := range policy.AllowedLabelPrefixes {
+		switch {
+		case len(prefix) == 0:
+			errs = append(errs, field.Invalid(fldPath.Child("allowedLabelPrefixes").Index(i), prefix, "an empty prefix would allow any label"))
+		case hasPrefix(prefix, reservedLabelPrefixes):
+			errs = append(errs, field.Forbidden(fldPath.Child("allowedLabelPrefixes").Index(i), "label prefix is managed by the operator"))
+		}
+	}
+
+	for i, addOn := range policy.AllowedAddOns {
+		if len(addOn) == 0 {
+			errs = append(errs, field.Invalid(fldPath.Child("allowedAddOns").Index(i), addOn, "must not be empty"))
+		}
+	}
+
+	for i, accessProfile := range policy.AllowedAccessProfiles {
+		if len(accessProfile) == 0 {
+			errs = append(errs, field.Invalid(fldPath.Child("allowedAccessProfiles").Index(i), accessProfile, "must not be empty"))
+		}
+	}
+
+	if len(policy.DefaultAccessProfile) != 0 && !contains(policy.DefaultAccessProfile, policy.AllowedAccessProfiles) {
+		errs = append(errs, field.NotSupported(fldPath.Child("defaultAccessProfile"), policy.DefaultAccessProfile, policy.AllowedAccessProfiles))
+	}
+
+	for i, name := range policy.AdoptableManagedClusters {
+		if len(name) == 0 {
+			errs = append(errs, field.Invalid(fldPath.Child("adoptableManagedClusters").Index(i), name, "must not be empty"))
+		}
+	}
+
+	for i, approver := range policy.Approvers {
+		if len(approver) == 0 {
+			errs = append(errs, field.Invalid(fldPath.Child("approvers").Index(i), approver, "must not be empty"))
+		}
+	}
+
+	for i, group := range policy.ApproverGroups {
+		if len(group) == 0 {
+			errs = append(errs, field.Invalid(fldPath.Child("approverGroups").Index(i), group, "must not be empty"))
+		}
+	}
+
+	if policy.ApprovalRequired && len(policy.Approvers) == 0 && len(policy.ApproverGroups) == 0 {
+		errs = append(errs, field.Required(fldPath.Child("approvers"), "an approver or an approver group is required to approve the RegisteredClusters"))
+	}
+	return errs
+}
+
+// Initialize is called by generic-admission-server on startup to setup initialization that webhook needs.
+func (a *ClusterRegistrarAdmissionHook) Initialize(kubeClientConfig *rest.Config, stopCh <-chan struct{}) error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	klog.V(0).Infof("Initialize admission webhook for ClusterRegistrar")
+
+	a.initialized = true
+
+	_, dynamicClient, err := newClients(kubeClientConfig)
+	if err != nil {
+		return err
+	}
+	a.ClusterRegistrarClient = dynamicClient.Resource(schema.GroupVersionResource{
+		Group:    GROUP_SUFFIX,
+		Version:  "v1alpha1",
+		Resource: "clusterregistrars",
+	})
+
+	return nil
+}
@@ -0,0 +1,145 @@
+// Copyright Red Hat
+
+package webhook
+
+import (
+	"encoding/json"
+	"testing"
+
+	singaporev1alpha1 "github.com/stolostron/cluster-registration-operator/api/singapore/v1alpha1"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func newClusterRegistrar(name string, policy singaporev1alpha1.RegistrationPolicy) *singaporev1alpha1.ClusterRegistrar {
+	return &singaporev1alpha1.ClusterRegistrar{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: singaporev1alpha1.SchemeGroupVersion.String(),
+			Kind:       "ClusterRegistrar",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: installationNamespace,
+		},
+		Spec: singaporev1alpha1.ClusterRegistrarSpec{
+			RegistrationPolicy: policy,
+		},
+	}
+}
+
+func newClusterRegistrarAdmissionHook(t *testing.T, existing ...*singaporev1alpha1.ClusterRegistrar) *ClusterRegistrarAdmissionHook {
+	objects := []runtime.Object{}
+	for _, clusterRegistrar := range existing {
+		clusterRegistrarU, err := runtime.DefaultUnstructuredConverter.ToUnstructured(clusterRegistrar)
+		if err != nil {
+			t.Fatalf("Failed to convert ClusterRegistrar: %s", err)
+		}
+		objects = append(objects, &unstructured.Unstructured{Object: clusterRegistrarU})
+	}
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{clusterRegistrarGVR: "ClusterRegistrarList"},
+		objects...)
+	return &ClusterRegistrarAdmissionHook{
+		ClusterRegistrarClient: dynamicClient.Resource(clusterRegistrarGVR),
+	}
+}
+
+func newClusterRegistrarAdmissionRequest(t *testing.T, operation admissionv1.Operation, clusterRegistrar *singaporev1alpha1.ClusterRegistrar) *admissionv1.AdmissionRequest {
+	b, err := json.Marshal(clusterRegistrar)
+	if err != nil {
+		t.Fatalf("Failed to marshal ClusterRegistrar: %s", err)
+	}
+	request := &admissionv1.AdmissionRequest{
+		Operation: operation,
+		Resource: metav1.GroupVersionResource{
+			Group:    GROUP_SUFFIX,
+			Version:  "v1alpha1",
+			Resource: "clusterregistrars",
+		},
+	}
+	request.Object.Raw = b
+	return request
+}
+
+func TestValidateClusterRegistrarCreate(t *testing.T) {
+	a := newClusterRegistrarAdmissionHook(t)
+	clusterRegistrar := newClusterRegistrar("cluster-reg", singaporev1alpha1.RegistrationPolicy{
+		AllowedLabelPrefixes:  []string{"env"},
+		AllowedAccessProfiles: []string{"view"},
+		DefaultAccessProfile:  "view",
+	})
+	response := a.Validate(newClusterRegistrarAdmissionRequest(t, admissionv1.Create, clusterRegistrar))
+	if !response.Allowed {
+		t.Fatalf("Request denied but expected to be allowed: %v", response.Result)
+	}
+}
+
+func TestValidateClusterRegistrarCreateSecond(t *testing.T) {
+	a := newClusterRegistrarAdmissionHook(t, newClusterRegistrar("cluster-reg", singaporev1alpha1.RegistrationPolicy{}))
+	clusterRegistrar := newClusterRegistrar("cluster-reg-2", singaporev1alpha1.RegistrationPolicy{})
+	response := a.Validate(newClusterRegistrarAdmissionRequest(t, admissionv1.Create, clusterRegistrar))
+	checkDenied(t, response, "metadata.name")
+}
+
+func TestValidateClusterRegistrarUpdate(t *testing.T) {
+	clusterRegistrar := newClusterRegistrar("cluster-reg", singaporev1alpha1.RegistrationPolicy{})
+	a := newClusterRegistrarAdmissionHook(t, clusterRegistrar)
+	response := a.Validate(newClusterRegistrarAdmissionRequest(t, admissionv1.Update, clusterRegistrar))
+	if !response.Allowed {
+		t.Fatalf("Request denied but expected to be allowed: %v", response.Result)
+	}
+}
+
+func TestValidateClusterRegistrarInvalidPolicy(t *testing.T) {
+	a := newClusterRegistrarAdmissionHook(t)
+	clusterRegistrar := newClusterRegistrar("cluster-reg", singaporev1alpha1.RegistrationPolicy{
+		AllowedLabelPrefixes:     []string{"", "cluster.open-cluster-management.io/"},
+		AllowedAddOns:            []string{""},
+		AllowedAccessProfiles:    []string{"view"},
+		DefaultAccessProfile:     "admin",
+		AdoptableManagedClusters: []string{""},
+	})
+	response := a.Validate(newClusterRegistrarAdmissionRequest(t, admissionv1.Update, clusterRegistrar))
+	checkDenied(t, response,
+		"spec.registrationPolicy.allowedLabelPrefixes[0]",
+		"spec.registrationPolicy.allowedLabelPrefixes[1]",
+		"spec.registrationPolicy.allowedAddOns[0]",
+		"spec.registrationPolicy.defaultAccessProfile",
+		"spec.registrationPolicy.adoptableManagedClusters[0]")
+}
+
+func TestValidateClusterRegistrarApprovalWithoutApprovers(t *testing.T) {
+	a := newClusterRegistrarAdmissionHook(t)
+	clusterRegistrar := newClusterRegistrar("cluster-reg", singaporev1alpha1.RegistrationPolicy{
+		ApprovalRequired: true,
+		ApproverGroups:   []string{""},
+	})
+	response := a.Validate(newClusterRegistrarAdmissionRequest(t, admissionv1.Create, clusterRegistrar))
+	checkDenied(t, response,
+		"spec.registrationPolicy.approverGroups[0]")
+
+	clusterRegistrar.Spec.RegistrationPolicy.ApproverGroups = nil
+	response = a.Validate(newClusterRegistrarAdmissionRequest(t, admissionv1.Create, clusterRegistrar))
+	checkDenied(t, response,
+		"spec.registrationPolicy.approvers")
+}
+
+func TestValidateClusterRegistrarRemoveCRDsRetain(t *testing.T) {
+	a := newClusterRegistrarAdmissionHook(t)
+	clusterRegistrar := newClusterRegistrar("cluster-reg", singaporev1alpha1.RegistrationPolicy{})
+	clusterRegistrar.Spec.RemoveCRDs = true
+	clusterRegistrar.Spec.DeletionPolicy = singaporev1alpha1.DeletionPolicyRetain
+	response := a.Validate(newClusterRegistrarAdmissionRequest(t, admissionv1.Create, clusterRegistrar))
+	checkDenied(t, response, "spec.removeCRDs")
+
+	clusterRegistrar.Spec.DeletionPolicy = singaporev1alpha1.DeletionPolicyDetach
+	response = a.Validate(newClusterRegistrarAdmissionRequest(t, admissionv1.Create, clusterRegistrar))
+	if !response.Allowed {
+		t.Fatalf("Request denied but expected to be allowed: %v", response.Result)
+	}
+}
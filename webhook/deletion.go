@@ -0,0 +1,200 @@
+// Copyright Red Hat
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	singaporev1alpha1 "github.com/stolostron/cluster-registration-operator/api/singapore/v1alpha1"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/klog/v2"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	clusterv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
+	workv1 "open-cluster-management.io/api/work/v1"
+)
+
+const (
+	// ProtectedAnnotation blocks the deletion of the RegisteredCluster when set to "true".
+	ProtectedAnnotation string = "registeredcluster.singapore.open-cluster-management.io/protected"
+	// ForceDeleteAnnotation allows the deletion of the RegisteredCluster when set to "true",
+	// even if it is protected or still used on the hub.
+	ForceDeleteAnnotation string = "registeredcluster.singapore.open-cluster-management.io/force-delete"
+
+	// The labels set by the manager on the ManagedCluster and the ManifestWorks it creates for a RegisteredCluster.
+	registeredClusterNameLabel      string = "registeredcluster.singapore.open-cluster-management.io/name"
+	registeredClusterNamespaceLabel string = "registeredcluster.singapore.open-cluster-management.io/namespace"
+
+	// The labels set by the addon framework and the import controller of the hub on the ManifestWorks they create.
+	addOnNameLabel       string = "open-cluster-management.io/addon-name"
+	klusterletWorksLabel string = "import.open-cluster-management.io/klusterlet-works"
+)
+
+var (
+	managedClusterGVR    = clusterv1.SchemeGroupVersion.WithResource("managedclusters")
+	manifestWorkGVR      = workv1.SchemeGroupVersion.WithResource("manifestworks")
+	placementDecisionGVR = clusterv1beta1.SchemeGroupVersion.WithResource("placementdecisions")
+)
+
+// ValidateRegisteredClusterDelete denies the deletion of a RegisteredCluster which is protected
+// or still used on the hub, unless the ForceDeleteAnnotation is set.
+func (a *RegisteredClusterAdmissionHook) ValidateRegisteredClusterDelete(admissionSpec *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	status := &admissionv1.AdmissionResponse{}
+
+	regCluster := &singaporev1alpha1.RegisteredCluster{}
+	if err := json.Unmarshal(admissionSpec.OldObject.Raw, regCluster); err != nil {
+		return badRequest(err)
+	}
+
+	klog.V(4).Infof("Validate delete of RegisteredCluster name: %s, namespace: %s", regCluster.Name, regCluster.Namespace)
+
+	if regCluster.Annotations[ForceDeleteAnnotation] == "true" {
+		klog.Infof("Force delete of RegisteredCluster name: %s, namespace: %s by %s", regCluster.Name, regCluster.Namespace, admissionSpec.UserInfo.Username)
+		status.Allowed = true
+		return status
+	}
+
+	// Don't block the deletion of the workspace
+	ns, err := a.KubeClient.CoreV1().Namespaces().Get(context.TODO(), regCluster.Namespace, metav1.GetOptions{})
+	switch {
+	case err != nil && !apierrors.IsNotFound(err):
+		return internalError(err)
+	case apierrors.IsNotFound(err) || ns.DeletionTimestamp != nil:
+		status.Allowed = true
+		return status
+	}
+
+	if regCluster.Annotations[ProtectedAnnotation] == "true" {
+		status.Allowed = false
+		status.Result = &apierrors.NewForbidden(singaporev1alpha1.Resource("registeredclusters"), regCluster.Name,
+			fmt.Errorf("the RegisteredCluster is protected by the %s annotation, remove it or set the %s annotation to \"true\"",
+				ProtectedAnnotation, ForceDeleteAnnotation)).ErrStatus
+		return status
+	}
+
+	references, err := a.getRegisteredClusterReferences(regCluster)
+	if err != nil {
+		return internalError(fmt.Errorf("unable to check the RegisteredCluster is not used on the hub, set the %s annotation to \"true\" to delete it anyway: %v",
+			ForceDeleteAnnotation, err))
+	}
+	if len(references) != 0 {
+		status.Allowed = false
+		status.Result = &apierrors.NewForbidden(singaporev1alpha1.Resource("registeredclusters"), regCluster.Name,
+			fmt.Errorf("the RegisteredCluster is still used by %s, set the %s annotation to \"true\" to delete it anyway",
+				strings.Join(references, ", "), ForceDeleteAnnotation)).ErrStatus
+		return status
+	}
+
+	status.Allowed = true
+	return status
+}
+
+// getRegisteredClusterReferences returns the ManifestWorks not created by the manager and the
+// PlacementDecisions selecting the ManagedCluster of the RegisteredCluster on its hub.
+func (a *RegisteredClusterAdmissionHook) getRegisteredClusterReferences(regCluster *singaporev1alpha1.RegisteredCluster) ([]string, error) {
+	// During a migration, the ManagedCluster is still used on the hub the cluster is migrated from
+	hubConfigName := regCluster.Status.HubConfigName
+	if len(hubConfigName) == 0 {
+		hubConfigName = regCluster.Spec.HubConfigName
+	}
+	if len(hubConfigName) == 0 {
+		var err error
+		hubConfigName, err = a.getDefaultHubConfigName(regCluster.Namespace)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	hubClient, err := a.HubDynamicClient(hubConfigName)
+	if err != nil {
+		return nil, err
+	}
+
+	managedClusterList, err := hubClient.Resource(managedClusterGVR).List(context.TODO(), metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(labels.Set{
+			registeredClusterNameLabel:      regCluster.Name,
+			registeredClusterNamespaceLabel: regCluster.Namespace,
+		}).String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(managedClusterList.Items) == 0 {
+		return nil, nil
+	}
+	managedClusterName := managedClusterList.Items[0].GetName()
+
+	var references []string
+	manifestWorkList, err := hubClient.Resource(manifestWorkGVR).Namespace(managedClusterName).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, manifestWork := range manifestWorkList.Items {
+		if isManagedManifestWork(&manifestWork) {
+			continue
+		}
+		references = append(references, fmt.Sprintf("ManifestWork %s/%s", manifestWork.GetNamespace(), manifestWork.GetName()))
+	}
+
+	placementDecisionList, err := hubClient.Resource(placementDecisionGVR).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, placementDecisionU := range placementDecisionList.Items {
+		placementDecision := &clusterv1beta1.PlacementDecision{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(placementDecisionU.Object, placementDecision); err != nil {
+			return nil, err
+		}
+		for _, decision := range placementDecision.Status.Decisions {
+			if decision.ClusterName == managedClusterName {
+				references = append(references, fmt.Sprintf("PlacementDecision %s/%s", placementDecision.Namespace, placementDecision.Name))
+				break
+			}
+		}
+	}
+	return references, nil
+}
+
+// isManagedManifestWork returns true when the ManifestWork is created by the manager, by the addon framework
+// or by the import controller of the hub, rather than by a user of the cluster.
+func isManagedManifestWork(manifestWork *unstructured.Unstructured) bool {
+	labels := manifestWork.GetLabels()
+	for _, label := range []string{registeredClusterNameLabel, addOnNameLabel, klusterletWorksLabel} {
+		if _, ok := labels[label]; ok {
+			return true
+		}
+	}
+	// The ManifestWorks of the addons are owned by their ManagedClusterAddOn
+	return len(manifestWork.GetOwnerReferences()) != 0
+}
+
+// newHubDynamicClient returns a client on the hub configured by the HubConfig.
+func (a *RegisteredClusterAdmissionHook) newHubDynamicClient(hubConfigName string) (dynamic.Interface, error) {
+	hubConfigU, err := a.HubConfigClient.Namespace(a.Namespace).Get(context.TODO(), hubConfigName, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	hubConfig := &singaporev1alpha1.HubConfig{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(hubConfigU.Object, hubConfig); err != nil {
+		return nil, err
+	}
+	kubeConfig, err := getHubKubeConfig(a.KubeClient, hubConfig.Namespace, hubConfig.Spec.KubeConfigSecretRef.Name)
+	if err != nil {
+		return nil, err
+	}
+	hubKubeconfig, err := clientcmd.RESTConfigFromKubeConfig(kubeConfig)
+	if err != nil {
+		return nil, err
+	}
+	return dynamic.NewForConfig(hubKubeconfig)
+}
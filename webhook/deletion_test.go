@@ -0,0 +1,182 @@
+// Copyright Red Hat
+
+package webhook
+
+import (
+	"net/http"
+	"testing"
+
+	singaporev1alpha1 "github.com/stolostron/cluster-registration-operator/api/singapore/v1alpha1"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	clusterv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
+)
+
+func newHubObject(t *testing.T, obj runtime.Object) *unstructured.Unstructured {
+	u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		t.Fatalf("Failed to convert %T: %s", obj, err)
+	}
+	return &unstructured.Unstructured{Object: u}
+}
+
+func newManagedCluster(name string, regCluster *singaporev1alpha1.RegisteredCluster) *unstructured.Unstructured {
+	managedCluster := &unstructured.Unstructured{}
+	managedCluster.SetAPIVersion(managedClusterGVR.GroupVersion().String())
+	managedCluster.SetKind("ManagedCluster")
+	managedCluster.SetName(name)
+	managedCluster.SetLabels(map[string]string{
+		registeredClusterNameLabel:      regCluster.Name,
+		registeredClusterNamespaceLabel: regCluster.Namespace,
+	})
+	return managedCluster
+}
+
+func newManifestWork(name, namespace string, labels map[string]string) *unstructured.Unstructured {
+	manifestWork := &unstructured.Unstructured{}
+	manifestWork.SetAPIVersion(manifestWorkGVR.GroupVersion().String())
+	manifestWork.SetKind("ManifestWork")
+	manifestWork.SetName(name)
+	manifestWork.SetNamespace(namespace)
+	manifestWork.SetLabels(labels)
+	return manifestWork
+}
+
+func newDeleteAdmissionHook(t *testing.T, hubObjects ...runtime.Object) *RegisteredClusterAdmissionHook {
+	a := newAdmissionHook(t, singaporev1alpha1.RegistrationPolicy{})
+	hubClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{
+			managedClusterGVR:    "ManagedClusterList",
+			manifestWorkGVR:      "ManifestWorkList",
+			placementDecisionGVR: "PlacementDecisionList",
+		},
+		hubObjects...)
+	a.HubDynamicClient = func(hubConfigName string) (dynamic.Interface, error) {
+		return hubClient, nil
+	}
+	return a
+}
+
+func newDeleteAdmissionRequest(t *testing.T, regCluster *singaporev1alpha1.RegisteredCluster) *admissionv1.AdmissionRequest {
+	request := newAdmissionRequest(t, admissionv1.Delete, regCluster, regCluster)
+	request.Object.Raw = nil
+	return request
+}
+
+func checkDeleteDenied(t *testing.T, response *admissionv1.AdmissionResponse, code int32) {
+	if response.Allowed {
+		t.Fatalf("Request allowed but expected to be denied.")
+	}
+	if response.Result.Code != code {
+		t.Fatalf(`Response code not as expected. Expected %d, actual %d: %s`, code, response.Result.Code, response.Result.Message)
+	}
+}
+
+func TestValidateRegisteredClusterDeleteNotUsed(t *testing.T) {
+	regCluster := newRegisteredCluster("cluster1", workspaceName, singaporev1alpha1.RegisteredClusterSpec{})
+	a := newDeleteAdmissionHook(t,
+		newManagedCluster("registered-cluster-abcde", regCluster),
+		newManifestWork("appstudio", "registered-cluster-abcde", map[string]string{
+			registeredClusterNameLabel:      regCluster.Name,
+			registeredClusterNamespaceLabel: regCluster.Namespace,
+		}),
+	)
+	response := a.Validate(newDeleteAdmissionRequest(t, regCluster))
+	if !response.Allowed {
+		t.Fatalf("Request denied but expected to be allowed: %v", response.Result)
+	}
+}
+
+func TestValidateRegisteredClusterDeleteSystemManifestWorks(t *testing.T) {
+	regCluster := newRegisteredCluster("cluster1", workspaceName, singaporev1alpha1.RegisteredClusterSpec{})
+	addOnWork := newManifestWork("addon-managed-serviceaccount-deploy", "registered-cluster-abcde", map[string]string{
+		addOnNameLabel: "managed-serviceaccount",
+	})
+	addOnWork.SetOwnerReferences([]metav1.OwnerReference{{
+		APIVersion: "addon.open-cluster-management.io/v1alpha1",
+		Kind:       "ManagedClusterAddOn",
+		Name:       "managed-serviceaccount",
+		UID:        "8e5b0c3a-4f1e-4f6b-9d53-4a3c0a1f2b7e",
+	}})
+	ownedWork := newManifestWork("addon-work-manager-deploy", "registered-cluster-abcde", nil)
+	ownedWork.SetOwnerReferences(addOnWork.GetOwnerReferences())
+	a := newDeleteAdmissionHook(t,
+		newManagedCluster("registered-cluster-abcde", regCluster),
+		addOnWork,
+		ownedWork,
+		newManifestWork("registered-cluster-abcde-klusterlet", "registered-cluster-abcde", map[string]string{klusterletWorksLabel: "true"}),
+		newManifestWork("registered-cluster-abcde-klusterlet-crds", "registered-cluster-abcde", map[string]string{klusterletWorksLabel: "true"}),
+	)
+	response := a.Validate(newDeleteAdmissionRequest(t, regCluster))
+	if !response.Allowed {
+		t.Fatalf("Request denied but expected to be allowed: %v", response.Result)
+	}
+}
+
+func TestValidateRegisteredClusterDeleteNotImported(t *testing.T) {
+	regCluster := newRegisteredCluster("cluster1", workspaceName, singaporev1alpha1.RegisteredClusterSpec{})
+	a := newDeleteAdmissionHook(t)
+	response := a.Validate(newDeleteAdmissionRequest(t, regCluster))
+	if !response.Allowed {
+		t.Fatalf("Request denied but expected to be allowed: %v", response.Result)
+	}
+}
+
+func TestValidateRegisteredClusterDeleteUsedByManifestWork(t *testing.T) {
+	regCluster := newRegisteredCluster("cluster1", workspaceName, singaporev1alpha1.RegisteredClusterSpec{})
+	a := newDeleteAdmissionHook(t,
+		newManagedCluster("registered-cluster-abcde", regCluster),
+		newManifestWork("application", "registered-cluster-abcde", nil),
+	)
+	response := a.Validate(newDeleteAdmissionRequest(t, regCluster))
+	checkDeleteDenied(t, response, http.StatusForbidden)
+
+	regCluster.Annotations = map[string]string{ForceDeleteAnnotation: "true"}
+	response = a.Validate(newDeleteAdmissionRequest(t, regCluster))
+	if !response.Allowed {
+		t.Fatalf("Request denied but expected to be allowed: %v", response.Result)
+	}
+}
+
+func TestValidateRegisteredClusterDeleteSelectedByPlacement(t *testing.T) {
+	regCluster := newRegisteredCluster("cluster1", workspaceName, singaporev1alpha1.RegisteredClusterSpec{})
+	placementDecision := &clusterv1beta1.PlacementDecision{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: clusterv1beta1.SchemeGroupVersion.String(),
+			Kind:       "PlacementDecision",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "placement-decision-1",
+			Namespace: "applications",
+		},
+		Status: clusterv1beta1.PlacementDecisionStatus{
+			Decisions: []clusterv1beta1.ClusterDecision{{ClusterName: "registered-cluster-abcde"}},
+		},
+	}
+	a := newDeleteAdmissionHook(t,
+		newManagedCluster("registered-cluster-abcde", regCluster),
+		newHubObject(t, placementDecision),
+	)
+	response := a.Validate(newDeleteAdmissionRequest(t, regCluster))
+	checkDeleteDenied(t, response, http.StatusForbidden)
+}
+
+func TestValidateRegisteredClusterDeleteProtected(t *testing.T) {
+	regCluster := newRegisteredCluster("cluster1", workspaceName, singaporev1alpha1.RegisteredClusterSpec{})
+	regCluster.Annotations = map[string]string{ProtectedAnnotation: "true"}
+	a := newDeleteAdmissionHook(t)
+	response := a.Validate(newDeleteAdmissionRequest(t, regCluster))
+	checkDeleteDenied(t, response, http.StatusForbidden)
+
+	regCluster.Annotations[ForceDeleteAnnotation] = "true"
+	response = a.Validate(newDeleteAdmissionRequest(t, regCluster))
+	if !response.Allowed {
+		t.Fatalf("Request denied but expected to be allowed: %v", response.Result)
+	}
+}
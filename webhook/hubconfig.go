@@ -0,0 +1,206 @@
+// Copyright Red Hat
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	singaporev1alpha1 "github.com/stolostron/cluster-registration-operator/api/singapore/v1alpha1"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/klog/v2"
+)
+
+// HubConfigKubeConfigKey is the key of the HubConfig secret holding the kubeconfig of the hub.
+const HubConfigKubeConfigKey = "kubeconfig"
+
+var errMissingKubeConfig = errors.New("secret has no " + HubConfigKubeConfigKey + " key")
+
+// HubConfigAdmissionHook validates the HubConfigs, so the manager doesn't fail at startup on a HubConfig it can't use.
+type HubConfigAdmissionHook struct {
+	HubConfigClient dynamic.NamespaceableResourceInterface
+	KubeClient      kubernetes.Interface
+	// Namespace is the installation namespace, the only one the manager reads the HubConfigs from
+	Namespace   string
+	lock        sync.RWMutex
+	initialized bool
+}
+
+// ValidatingResource is called by generic-admission-server on startup to register the returned REST resource through which the
+// webhook is accessed by the kube apiserver.
+func (a *HubConfigAdmissionHook) ValidatingResource() (plural schema.GroupVersionResource, singular string) {
+	return schema.GroupVersionResource{
+			Group:    "admission." + GROUP_SUFFIX,
+			Version:  "v1",
+			Resource: "hubconfigs",
+		},
+		"hubconfig"
+}
+
+// Validate is called by generic-admission-server when the registered REST resource above is called with an admission.k8s.io/v1 admission request.
+func (a *HubConfigAdmissionHook) Validate(admissionSpec *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	status := &admissionv1.AdmissionResponse{}
+	klog.V(4).Infof("HubConfig Validate %q operation for object %q, group: %s, resource: %s", admissionSpec.Operation, admissionSpec.Object, admissionSpec.Resource.Group, admissionSpec.Resource.Resource)
+
+	if !strings.HasSuffix(admissionSpec.Resource.Group, GROUP_SUFFIX) || admissionSpec.Resource.Resource != "hubconfigs" {
+		status.Allowed = true
+		return status
+	}
+
+	if admissionSpec.Operation != admissionv1.Create && admissionSpec.Operation != admissionv1.Update {
+		status.Allowed = true
+		return status
+	}
+
+	hubConfig := &singaporev1alpha1.HubConfig{}
+	if err := json.Unmarshal(admissionSpec.Object.Raw, hubConfig); err != nil {
+		return badRequest(err)
+	}
+
+	klog.V(4).Infof("Validate webhook for HubConfig name: %s, namespace: %s", hubConfig.Name, hubConfig.Namespace)
+
+	// The secret is only validated when its reference changes, so the HubConfig can still be updated
+	// while its secret is being rotated or was deleted
+	if admissionSpec.Operation == admissionv1.Update {
+		oldHubConfig := &singaporev1alpha1.HubConfig{}
+		if err := json.Unmarshal(admissionSpec.OldObject.Raw, oldHubConfig); err != nil {
+			return badRequest(err)
+		}
+		if oldHubConfig.Spec.KubeConfigSecretRef == hubConfig.Spec.KubeConfigSecretRef {
+			status.Allowed = true
+			return status
+		}
+	}
+
+	errs, err := a.validateHubConfig(hubConfig)
+	if err != nil {
+		return internalError(err)
+	}
+
+	if len(errs) != 0 {
+		statusErr := apierrors.NewInvalid(singaporev1alpha1.SchemeGroupVersion.WithKind("HubConfig").GroupKind(), hubConfig.Name, errs)
+		status.Allowed = false
+		status.Result = &statusErr.ErrStatus
+		return status
+	}
+
+	// The prerequisites of the hub are not checked during the admission, an unreachable hub would time the request out.
+	// The installer reports them in the HubConfig conditions.
+	status.Allowed = true
+	return status
+}
+
+// validateHubConfig checks the HubConfig is in the installation namespace, references a secret holding
+// a valid kubeconfig and targets a hub not already configured by another HubConfig.
+func (a *HubConfigAdmissionHook) validateHubConfig(hubConfig *singaporev1alpha1.HubConfig) (field.ErrorList, error) {
+	var errs field.ErrorList
+	if len(a.Namespace) != 0 && hubConfig.Namespace != a.Namespace {
+		errs = append(errs, field.Forbidden(field.NewPath("metadata", "namespace"),
+			fmt.Sprintf("HubConfigs must be created in the installation namespace %s", a.Namespace)))
+		return errs, nil
+	}
+
+	fldPath := field.NewPath("spec", "kubeConfigSecretRef", "name")
+	secretName := hubConfig.Spec.KubeConfigSecretRef.Name
+	if len(secretName) == 0 {
+		errs = append(errs, field.Required(fldPath, "the secret holding the kubeconfig of the hub must be set"))
+		return errs, nil
+	}
+
+	hubKubeConfig, err := getHubKubeConfig(a.KubeClient, hubConfig.Namespace, secretName)
+	switch {
+	case apierrors.IsNotFound(err):
+		errs = append(errs, field.Invalid(fldPath, secretName, "secret not found"))
+		return errs, nil
+	case err == errMissingKubeConfig:
+		errs = append(errs, field.Invalid(fldPath, secretName, fmt.Sprintf("secret has no %s key", HubConfigKubeConfigKey)))
+		return errs, nil
+	case err != nil:
+		return nil, err
+	}
+	hubKubeconfig, err := clientcmd.RESTConfigFromKubeConfig(hubKubeConfig)
+	if err != nil {
+		errs = append(errs, field.Invalid(fldPath, secretName, fmt.Sprintf("invalid %s in secret: %s", HubConfigKubeConfigKey, err)))
+		return errs, nil
+	}
+
+	hubConfigList, err := a.HubConfigClient.Namespace(hubConfig.Namespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, otherU := range hubConfigList.Items {
+		if otherU.GetName() == hubConfig.Name {
+			continue
+		}
+		other := &singaporev1alpha1.HubConfig{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(otherU.Object, other); err != nil {
+			return nil, err
+		}
+		otherKubeConfig, err := getHubKubeConfig(a.KubeClient, other.Namespace, other.Spec.KubeConfigSecretRef.Name)
+		if err != nil {
+			// The other HubConfig is unusable, it can't be configuring the same hub.
+			continue
+		}
+		otherKubeconfig, err := clientcmd.RESTConfigFromKubeConfig(otherKubeConfig)
+		if err != nil {
+			continue
+		}
+		if otherKubeconfig.Host == hubKubeconfig.Host {
+			errs = append(errs, field.Duplicate(fldPath,
+				fmt.Sprintf("hub %s is already configured by HubConfig %s", hubKubeconfig.Host, other.Name)))
+		}
+	}
+	return errs, nil
+}
+
+// getHubKubeConfig returns the kubeconfig of the hub stored in the secret.
+func getHubKubeConfig(kubeClient kubernetes.Interface, namespace, secretName string) ([]byte, error) {
+	secret, err := kubeClient.CoreV1().Secrets(namespace).Get(context.TODO(), secretName, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	kubeConfig, ok := secret.Data[HubConfigKubeConfigKey]
+	if !ok {
+		return nil, errMissingKubeConfig
+	}
+	return kubeConfig, nil
+}
+
+// Initialize is called by generic-admission-server on startup to setup initialization that webhook needs.
+func (a *HubConfigAdmissionHook) Initialize(kubeClientConfig *rest.Config, stopCh <-chan struct{}) error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	klog.V(0).Infof("Initialize admission webhook for HubConfig")
+
+	a.initialized = true
+
+	kubeClient, dynamicClient, err := newClients(kubeClientConfig)
+	if err != nil {
+		return err
+	}
+	a.KubeClient = kubeClient
+	a.HubConfigClient = dynamicClient.Resource(schema.GroupVersionResource{
+		Group:    GROUP_SUFFIX,
+		Version:  "v1alpha1",
+		Resource: "hubconfigs",
+	})
+	a.Namespace = os.Getenv("POD_NAMESPACE")
+
+	return nil
+}
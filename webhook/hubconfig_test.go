@@ -0,0 +1,189 @@
+// Copyright Red Hat
+
+package webhook
+
+import (
+	"encoding/json"
+	"testing"
+
+	singaporev1alpha1 "github.com/stolostron/cluster-registration-operator/api/singapore/v1alpha1"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+const installationNamespace = "cluster-reg-config"
+
+func newKubeConfig(t *testing.T, server string) []byte {
+	config := clientcmdapi.NewConfig()
+	config.Clusters["hub"] = &clientcmdapi.Cluster{Server: server}
+	config.AuthInfos["hub"] = &clientcmdapi.AuthInfo{Token: "token"}
+	config.Contexts["hub"] = &clientcmdapi.Context{Cluster: "hub", AuthInfo: "hub"}
+	config.CurrentContext = "hub"
+	b, err := clientcmd.Write(*config)
+	if err != nil {
+		t.Fatalf("Failed to write kubeconfig: %s", err)
+	}
+	return b
+}
+
+func newHubConfig(name, namespace, secretName string) *singaporev1alpha1.HubConfig {
+	return &singaporev1alpha1.HubConfig{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: singaporev1alpha1.SchemeGroupVersion.String(),
+			Kind:       "HubConfig",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: singaporev1alpha1.HubConfigSpec{
+			KubeConfigSecretRef: corev1.LocalObjectReference{Name: secretName},
+		},
+	}
+}
+
+func newHubConfigAdmissionHook(t *testing.T) *HubConfigAdmissionHook {
+	kubeClient := kubefake.NewSimpleClientset(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "hub-1-kubeconfig", Namespace: installationNamespace},
+			Data:       map[string][]byte{HubConfigKubeConfigKey: newKubeConfig(t, "https://hub-1:6443")},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "hub-2-kubeconfig", Namespace: installationNamespace},
+			Data:       map[string][]byte{HubConfigKubeConfigKey: newKubeConfig(t, "https://hub-2:6443")},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "no-kubeconfig", Namespace: installationNamespace},
+			Data:       map[string][]byte{"token": []byte("token")},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "invalid-kubeconfig", Namespace: installationNamespace},
+			Data:       map[string][]byte{HubConfigKubeConfigKey: []byte("not a kubeconfig")},
+		},
+	)
+
+	hubConfigU, err := runtime.DefaultUnstructuredConverter.ToUnstructured(newHubConfig("hub-1", installationNamespace, "hub-1-kubeconfig"))
+	if err != nil {
+		t.Fatalf("Failed to convert HubConfig: %s", err)
+	}
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{hubConfigGVR: "HubConfigList"},
+		&unstructured.Unstructured{Object: hubConfigU})
+
+	return &HubConfigAdmissionHook{
+		KubeClient:      kubeClient,
+		HubConfigClient: dynamicClient.Resource(hubConfigGVR),
+		Namespace:       installationNamespace,
+	}
+}
+
+func newHubConfigAdmissionRequest(t *testing.T, operation admissionv1.Operation, hubConfig *singaporev1alpha1.HubConfig) *admissionv1.AdmissionRequest {
+	b, err := json.Marshal(hubConfig)
+	if err != nil {
+		t.Fatalf("Failed to marshal HubConfig: %s", err)
+	}
+	request := &admissionv1.AdmissionRequest{
+		Operation: operation,
+		Resource: metav1.GroupVersionResource{
+			Group:    GROUP_SUFFIX,
+			Version:  "v1alpha1",
+			Resource: "hubconfigs",
+		},
+	}
+	request.Object.Raw = b
+	return request
+}
+
+func TestValidateHubConfigCreate(t *testing.T) {
+	a := newHubConfigAdmissionHook(t)
+	response := a.Validate(newHubConfigAdmissionRequest(t, admissionv1.Create, newHubConfig("hub-2", installationNamespace, "hub-2-kubeconfig")))
+	if !response.Allowed {
+		t.Fatalf("Request denied but expected to be allowed: %v", response.Result)
+	}
+}
+
+func newHubConfigUpdateRequest(t *testing.T, hubConfig, oldHubConfig *singaporev1alpha1.HubConfig) *admissionv1.AdmissionRequest {
+	request := newHubConfigAdmissionRequest(t, admissionv1.Update, hubConfig)
+	b, err := json.Marshal(oldHubConfig)
+	if err != nil {
+		t.Fatalf("Failed to marshal HubConfig: %s", err)
+	}
+	request.OldObject.Raw = b
+	return request
+}
+
+func TestValidateHubConfigUpdate(t *testing.T) {
+	a := newHubConfigAdmissionHook(t)
+	response := a.Validate(newHubConfigUpdateRequest(t, newHubConfig("hub-1", installationNamespace, "hub-1-kubeconfig"),
+		newHubConfig("hub-1", installationNamespace, "hub-1-kubeconfig")))
+	if !response.Allowed {
+		t.Fatalf("Request denied but expected to be allowed: %v", response.Result)
+	}
+
+	// The secret is not read again when its reference is not changed
+	hubConfig := newHubConfig("hub-1", installationNamespace, "not-found")
+	hubConfig.Labels = map[string]string{"env": "prod"}
+	response = a.Validate(newHubConfigUpdateRequest(t, hubConfig, newHubConfig("hub-1", installationNamespace, "not-found")))
+	if !response.Allowed {
+		t.Fatalf("Request denied but expected to be allowed: %v", response.Result)
+	}
+
+	response = a.Validate(newHubConfigUpdateRequest(t, newHubConfig("hub-1", installationNamespace, "not-found"),
+		newHubConfig("hub-1", installationNamespace, "hub-1-kubeconfig")))
+	checkDenied(t, response, "spec.kubeConfigSecretRef.name")
+}
+
+func TestValidateHubConfigCreateInvalid(t *testing.T) {
+	cases := []struct {
+		name      string
+		hubConfig *singaporev1alpha1.HubConfig
+		field     string
+	}{
+		{
+			name:      "other namespace",
+			hubConfig: newHubConfig("hub-2", "other-namespace", "hub-2-kubeconfig"),
+			field:     "metadata.namespace",
+		},
+		{
+			name:      "no secret",
+			hubConfig: newHubConfig("hub-2", installationNamespace, ""),
+			field:     "spec.kubeConfigSecretRef.name",
+		},
+		{
+			name:      "secret not found",
+			hubConfig: newHubConfig("hub-2", installationNamespace, "not-found"),
+			field:     "spec.kubeConfigSecretRef.name",
+		},
+		{
+			name:      "no kubeconfig in secret",
+			hubConfig: newHubConfig("hub-2", installationNamespace, "no-kubeconfig"),
+			field:     "spec.kubeConfigSecretRef.name",
+		},
+		{
+			name:      "invalid kubeconfig",
+			hubConfig: newHubConfig("hub-2", installationNamespace, "invalid-kubeconfig"),
+			field:     "spec.kubeConfigSecretRef.name",
+		},
+		{
+			name:      "hub already configured",
+			hubConfig: newHubConfig("hub-2", installationNamespace, "hub-1-kubeconfig"),
+			field:     "spec.kubeConfigSecretRef.name",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			a := newHubConfigAdmissionHook(t)
+			response := a.Validate(newHubConfigAdmissionRequest(t, admissionv1.Create, c.hubConfig))
+			checkDenied(t, response, c.field)
+		})
+	}
+}
@@ -0,0 +1,226 @@
+// Copyright Red Hat
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	singaporev1alpha1 "github.com/stolostron/cluster-registration-operator/api/singapore/v1alpha1"
+	"github.com/stolostron/cluster-registration-operator/pkg/helpers"
+	"gomodules.xyz/jsonpatch/v2"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// CreatedByAnnotation records the user who created the RegisteredCluster.
+	CreatedByAnnotation string = "registeredcluster.singapore.open-cluster-management.io/created-by"
+	// HubConfigNameLabel is the name of the HubConfig of the hub the RegisteredCluster is registered on.
+	HubConfigNameLabel string = "registeredcluster.singapore.open-cluster-management.io/hubconfig"
+	// ManagedClusterSetLabel is the ManagedClusterSet the RegisteredCluster is added to.
+	ManagedClusterSetLabel string = "cluster.open-cluster-management.io/clusterset"
+)
+
+// MutatingResource is called by generic-admission-server on startup to register the returned REST resource through which the
+// mutating webhook is accessed by the kube apiserver.
+func (a *RegisteredClusterAdmissionHook) MutatingResource() (plural schema.GroupVersionResource, singular string) {
+	return schema.GroupVersionResource{
+			Group:    "admission." + GROUP_SUFFIX,
+			Version:  "v1",
+			Resource: "registeredclustermutators",
+		},
+		"registeredclustermutator"
+}
+
+// Admit is called by generic-admission-server when the registered REST resource above is called with an admission.k8s.io/v1 admission request.
+func (a *RegisteredClusterAdmissionHook) Admit(admissionSpec *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	status := &admissionv1.AdmissionResponse{}
+	klog.V(4).Infof("RegisteredCluster Admit %q operation for object %q, group: %s, resource: %s", admissionSpec.Operation, admissionSpec.Object, admissionSpec.Resource.Group, admissionSpec.Resource.Resource)
+
+	if !strings.HasSuffix(admissionSpec.Resource.Group, GROUP_SUFFIX) {
+		status.Allowed = true
+		return status
+	}
+
+	switch admissionSpec.Resource.Resource {
+	case "registeredclusters":
+		return a.MutateRegisteredCluster(admissionSpec)
+	}
+	status.Allowed = true
+	return status
+}
+
+// MutateRegisteredCluster sets the defaults on the RegisteredClusters being created
+// and updates the hub label of those being migrated
+func (a *RegisteredClusterAdmissionHook) MutateRegisteredCluster(admissionSpec *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	status := &admissionv1.AdmissionResponse{}
+
+	if admissionSpec.Operation != admissionv1.Create && admissionSpec.Operation != admissionv1.Update {
+		status.Allowed = true
+		return status
+	}
+
+	regCluster := &singaporev1alpha1.RegisteredCluster{}
+	if err := json.Unmarshal(admissionSpec.Object.Raw, regCluster); err != nil {
+		return badRequest(err)
+	}
+
+	klog.V(4).Infof("Mutate webhook for RegisteredCluster name: %s, namespace: %s", regCluster.Name, regCluster.Namespace)
+
+	if admissionSpec.Operation == admissionv1.Update {
+		oldRegCluster := &singaporev1alpha1.RegisteredCluster{}
+		if err := json.Unmarshal(admissionSpec.OldObject.Raw, oldRegCluster); err != nil {
+			return badRequest(err)
+		}
+		mutated := regCluster.DeepCopy()
+		setHubConfigNameLabel(mutated)
+		setMigrationSource(mutated, oldRegCluster)
+		setApprover(mutated, oldRegCluster, admissionSpec.UserInfo.Username)
+		return patchResponse(regCluster, mutated)
+	}
+
+	policy, err := a.getRegistrationPolicy()
+	if err != nil {
+		return internalError(err)
+	}
+
+	defaultHubConfigName, err := a.getDefaultHubConfigName(regCluster.Namespace)
+	if err != nil {
+		return internalError(err)
+	}
+
+	mutated := regCluster.DeepCopy()
+	setRegisteredClusterDefaults(mutated, defaultHubConfigName, policy)
+	if mutated.Annotations == nil {
+		mutated.Annotations = map[string]string{}
+	}
+	mutated.Annotations[CreatedByAnnotation] = admissionSpec.UserInfo.Username
+	delete(mutated.Annotations, singaporev1alpha1.MigrationSourceHubAnnotation)
+	// The policy at the creation decides if the RegisteredCluster must be approved,
+	// the adopted clusters already joined the hub and are allowed by the adoptableManagedClusters of the policy
+	if policy.ApprovalRequired && len(mutated.Spec.ManagedClusterName) == 0 {
+		mutated.Annotations[singaporev1alpha1.ApprovalRequiredAnnotation] = "true"
+	} else {
+		delete(mutated.Annotations, singaporev1alpha1.ApprovalRequiredAnnotation)
+	}
+	setApprover(mutated, nil, admissionSpec.UserInfo.Username)
+
+	return patchResponse(regCluster, mutated)
+}
+
+// setMigrationSource records the hub the RegisteredCluster is migrated from when its hub changes, the validating
+// webhook denies changing the hub again until the operator removes the annotation.
+func setMigrationSource(regCluster, oldRegCluster *singaporev1alpha1.RegisteredCluster) {
+	if regCluster.Spec.HubConfigName == oldRegCluster.Spec.HubConfigName || len(oldRegCluster.Spec.HubConfigName) == 0 {
+		return
+	}
+	if regCluster.Annotations == nil {
+		regCluster.Annotations = map[string]string{}
+	}
+	if source, ok := oldRegCluster.Annotations[singaporev1alpha1.MigrationSourceHubAnnotation]; ok {
+		regCluster.Annotations[singaporev1alpha1.MigrationSourceHubAnnotation] = source
+		return
+	}
+	regCluster.Annotations[singaporev1alpha1.MigrationSourceHubAnnotation] = oldRegCluster.Spec.HubConfigName
+}
+
+// setApprover records the user approving the RegisteredCluster, the validating webhook checks the user is an approver.
+func setApprover(regCluster, oldRegCluster *singaporev1alpha1.RegisteredCluster, username string) {
+	if regCluster.Annotations[singaporev1alpha1.ApprovedAnnotation] != "true" {
+		delete(regCluster.Annotations, singaporev1alpha1.ApprovedByAnnotation)
+		return
+	}
+	if oldRegCluster == nil || oldRegCluster.Annotations[singaporev1alpha1.ApprovedAnnotation] != "true" {
+		regCluster.Annotations[singaporev1alpha1.ApprovedByAnnotation] = username
+	}
+}
+
+// patchResponse returns the response allowing the request with the patch of the mutations.
+func patchResponse(regCluster, mutated *singaporev1alpha1.RegisteredCluster) *admissionv1.AdmissionResponse {
+	status := &admissionv1.AdmissionResponse{}
+	patch, err := createPatch(regCluster, mutated)
+	if err != nil {
+		return internalError(err)
+	}
+
+	status.Allowed = true
+	if len(patch) != 0 {
+		patchType := admissionv1.PatchTypeJSONPatch
+		status.PatchType = &patchType
+		status.Patch = patch
+	}
+	return status
+}
+
+// setRegisteredClusterDefaults sets the hub, the ManagedClusterSet, the access profile
+// and the standard labels of the RegisteredCluster.
+func setRegisteredClusterDefaults(regCluster *singaporev1alpha1.RegisteredCluster, defaultHubConfigName string, policy *singaporev1alpha1.RegistrationPolicy) {
+	if len(regCluster.Spec.HubConfigName) == 0 {
+		regCluster.Spec.HubConfigName = defaultHubConfigName
+	}
+	if len(regCluster.Spec.ManagedClusterSet) == 0 {
+		regCluster.Spec.ManagedClusterSet = helpers.ManagedClusterSetNameForWorkspace(regCluster.Namespace)
+	}
+	if len(regCluster.Spec.AccessProfile) == 0 {
+		regCluster.Spec.AccessProfile = policy.DefaultAccessProfile
+	}
+
+	setHubConfigNameLabel(regCluster)
+	regCluster.Labels[ManagedClusterSetLabel] = regCluster.Spec.ManagedClusterSet
+}
+
+// setHubConfigNameLabel sets the label of the hub of the RegisteredCluster.
+func setHubConfigNameLabel(regCluster *singaporev1alpha1.RegisteredCluster) {
+	if regCluster.Labels == nil {
+		regCluster.Labels = map[string]string{}
+	}
+	if len(regCluster.Spec.HubConfigName) != 0 {
+		regCluster.Labels[HubConfigNameLabel] = regCluster.Spec.HubConfigName
+	}
+}
+
+// getDefaultHubConfigName returns the name of the HubConfig used when none is set on the RegisteredCluster.
+// Like the manager, the hub selected by the annotation of the workspace or the first HubConfig of the installation namespace is used.
+func (a *RegisteredClusterAdmissionHook) getDefaultHubConfigName(workspace string) (string, error) {
+	ns, err := a.KubeClient.CoreV1().Namespaces().Get(context.TODO(), workspace, metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+	if hubConfigName := ns.Annotations[helpers.WorkspaceHubConfigAnnotation]; len(hubConfigName) != 0 {
+		return hubConfigName, nil
+	}
+	hubConfigList, err := a.HubConfigClient.Namespace(a.Namespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return "", err
+	}
+	if len(hubConfigList.Items) == 0 {
+		return "", fmt.Errorf("no HubConfig found")
+	}
+	return hubConfigList.Items[0].GetName(), nil
+}
+
+// createPatch returns the JSON patch to apply on the original object to get the mutated one.
+func createPatch(original, mutated interface{}) ([]byte, error) {
+	originalJSON, err := json.Marshal(original)
+	if err != nil {
+		return nil, err
+	}
+	mutatedJSON, err := json.Marshal(mutated)
+	if err != nil {
+		return nil, err
+	}
+	operations, err := jsonpatch.CreatePatch(originalJSON, mutatedJSON)
+	if err != nil {
+		return nil, err
+	}
+	if len(operations) == 0 {
+		return nil, nil
+	}
+	return json.Marshal(operations)
+}
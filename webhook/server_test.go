@@ -0,0 +1,117 @@
+// Copyright Red Hat
+
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/openshift/generic-admission-server/pkg/apiserver"
+	singaporev1alpha1 "github.com/stolostron/cluster-registration-operator/api/singapore/v1alpha1"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	genericapiserver "k8s.io/apiserver/pkg/server"
+	"k8s.io/client-go/rest"
+)
+
+// newAdmissionServerHandler returns the HTTP handler of the admission server serving the hooks of the webhook.
+func newAdmissionServerHandler(t *testing.T) http.Handler {
+	hook := newAdmissionHook(t, singaporev1alpha1.RegistrationPolicy{})
+	config := &apiserver.Config{
+		GenericConfig: genericapiserver.NewRecommendedConfig(apiserver.Codecs),
+		ExtraConfig: apiserver.ExtraConfig{AdmissionHooks: []apiserver.AdmissionHook{
+			hook, &RegisteredClusterAdmissionHookV1Beta1{Hook: hook},
+		}},
+		RestConfig: &rest.Config{},
+	}
+	config.GenericConfig.LoopbackClientConfig = &rest.Config{}
+	config.GenericConfig.ExternalAddress = "127.0.0.1:443"
+	server, err := config.Complete().New()
+	if err != nil {
+		t.Fatalf("Failed to create the admission server: %s", err)
+	}
+	return server.GenericAPIServer.Handler
+}
+
+// postAdmissionReview sends the serialized AdmissionReview to the path and decodes the returned review in response.
+func postAdmissionReview(t *testing.T, handler http.Handler, path string, review, response interface{}) {
+	b, err := json.Marshal(review)
+	if err != nil {
+		t.Fatalf("Failed to marshal AdmissionReview: %s", err)
+	}
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(b))
+	req.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusCreated && recorder.Code != http.StatusOK {
+		t.Fatalf("Request to %s failed with %d: %s", path, recorder.Code, recorder.Body.String())
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), response); err != nil {
+		t.Fatalf("Failed to unmarshal AdmissionReview: %s: %s", err, recorder.Body.String())
+	}
+}
+
+func TestAdmissionServerV1(t *testing.T) {
+	handler := newAdmissionServerHandler(t)
+	request := newAdmissionRequest(t, admissionv1.Create, newRegisteredCluster("cluster1", workspaceName, singaporev1alpha1.RegisteredClusterSpec{}), nil)
+	request.UID = "uid-v1"
+	review := &admissionv1.AdmissionReview{
+		TypeMeta: metav1.TypeMeta{APIVersion: admissionv1.SchemeGroupVersion.String(), Kind: "AdmissionReview"},
+		Request:  request,
+	}
+
+	response := &admissionv1.AdmissionReview{}
+	postAdmissionReview(t, handler, "/apis/admission."+GROUP_SUFFIX+"/v1/registeredclustermutators", review, response)
+	if response.APIVersion != admissionv1.SchemeGroupVersion.String() || response.Response == nil {
+		t.Fatalf("AdmissionReview not as expected: %v", response)
+	}
+	if response.Response.UID != request.UID || !response.Response.Allowed || len(response.Response.Patch) == 0 {
+		t.Fatalf("AdmissionResponse not as expected: %v", response.Response)
+	}
+
+	response = &admissionv1.AdmissionReview{}
+	postAdmissionReview(t, handler, "/apis/admission."+GROUP_SUFFIX+"/v1/registeredclusters", review, response)
+	if response.Response == nil || response.Response.UID != request.UID || !response.Response.Allowed {
+		t.Fatalf("AdmissionResponse not as expected: %v", response.Response)
+	}
+}
+
+func TestAdmissionServerV1beta1(t *testing.T) {
+	handler := newAdmissionServerHandler(t)
+	request := newV1beta1AdmissionRequest(t, admissionv1beta1.Create, newRegisteredCluster("cluster1", workspaceName, singaporev1alpha1.RegisteredClusterSpec{}), nil)
+	request.UID = "uid-v1beta1"
+	review := &admissionv1beta1.AdmissionReview{
+		TypeMeta: metav1.TypeMeta{APIVersion: admissionv1beta1.SchemeGroupVersion.String(), Kind: "AdmissionReview"},
+		Request:  request,
+	}
+
+	response := &admissionv1beta1.AdmissionReview{}
+	postAdmissionReview(t, handler, "/apis/admission."+GROUP_SUFFIX+"/v1alpha1/registeredclustermutators", review, response)
+	if response.APIVersion != admissionv1beta1.SchemeGroupVersion.String() || response.Response == nil {
+		t.Fatalf("AdmissionReview not as expected: %v", response)
+	}
+	if response.Response.UID != request.UID || !response.Response.Allowed || len(response.Response.Patch) == 0 {
+		t.Fatalf("AdmissionResponse not as expected: %v", response.Response)
+	}
+
+	response = &admissionv1beta1.AdmissionReview{}
+	postAdmissionReview(t, handler, "/apis/admission."+GROUP_SUFFIX+"/v1alpha1/registeredclusters", review, response)
+	if response.Response == nil || response.Response.UID != request.UID || !response.Response.Allowed {
+		t.Fatalf("AdmissionResponse not as expected: %v", response.Response)
+	}
+
+	// The v1beta1 AdmissionReviews of invalid RegisteredClusters are denied with the cause of the v1 hook
+	request.Object.Raw = newV1beta1AdmissionRequest(t, admissionv1beta1.Create, newRegisteredCluster("Cluster_1", workspaceName, singaporev1alpha1.RegisteredClusterSpec{}), nil).Object.Raw
+	response = &admissionv1beta1.AdmissionReview{}
+	postAdmissionReview(t, handler, "/apis/admission."+GROUP_SUFFIX+"/v1alpha1/registeredclusters", review, response)
+	if response.Response == nil || response.Response.Allowed || response.Response.Result == nil ||
+		response.Response.Result.Details == nil || len(response.Response.Result.Details.Causes) != 1 ||
+		response.Response.Result.Details.Causes[0].Field != "metadata.name" {
+		t.Fatalf("AdmissionResponse not as expected: %v", response.Response)
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright Red Hat
+
+package webhook
+
+import (
+	admissionv1 "k8s.io/api/admission/v1"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/rest"
+)
+
+// RegisteredClusterAdmissionHookV1Beta1 serves the admission.k8s.io/v1beta1 AdmissionReviews
+// on the v1alpha1 resources used by the webhook configurations created by previous releases.
+// The requests are converted to admission.k8s.io/v1 and handled by the RegisteredClusterAdmissionHook.
+type RegisteredClusterAdmissionHookV1Beta1 struct {
+	Hook *RegisteredClusterAdmissionHook
+}
+
+// ValidatingResource is called by generic-admission-server on startup to register the returned REST resource through which the
+// v1beta1 validating webhook is accessed by the kube apiserver.
+func (a *RegisteredClusterAdmissionHookV1Beta1) ValidatingResource() (plural schema.GroupVersionResource, singular string) {
+	return schema.GroupVersionResource{
+			Group:    "admission." + GROUP_SUFFIX,
+			Version:  "v1alpha1",
+			Resource: "registeredclusters",
+		},
+		"registeredcluster"
+}
+
+// MutatingResource is called by generic-admission-server on startup to register the returned REST resource through which the
+// v1beta1 mutating webhook is accessed by the kube apiserver.
+func (a *RegisteredClusterAdmissionHookV1Beta1) MutatingResource() (plural schema.GroupVersionResource, singular string) {
+	return schema.GroupVersionResource{
+			Group:    "admission." + GROUP_SUFFIX,
+			Version:  "v1alpha1",
+			Resource: "registeredclustermutators",
+		},
+		"registeredclustermutator"
+}
+
+// Validate is called by generic-admission-server when the v1beta1 validating resource is called with an admission request.
+func (a *RegisteredClusterAdmissionHookV1Beta1) Validate(admissionSpec *admissionv1beta1.AdmissionRequest) *admissionv1beta1.AdmissionResponse {
+	return toV1beta1AdmissionResponse(admissionSpec, a.Hook.Validate(toV1AdmissionRequest(admissionSpec)))
+}
+
+// Admit is called by generic-admission-server when the v1beta1 mutating resource is called with an admission request.
+func (a *RegisteredClusterAdmissionHookV1Beta1) Admit(admissionSpec *admissionv1beta1.AdmissionRequest) *admissionv1beta1.AdmissionResponse {
+	return toV1beta1AdmissionResponse(admissionSpec, a.Hook.Admit(toV1AdmissionRequest(admissionSpec)))
+}
+
+// Initialize does nothing as the clients are set up by the Initialize of the wrapped hook,
+// which is registered on the admission server as well.
+func (a *RegisteredClusterAdmissionHookV1Beta1) Initialize(kubeClientConfig *rest.Config, stopCh <-chan struct{}) error {
+	return nil
+}
+
+// toV1AdmissionRequest converts a v1beta1 AdmissionRequest, both versions have the same fields.
+func toV1AdmissionRequest(in *admissionv1beta1.AdmissionRequest) *admissionv1.AdmissionRequest {
+	return &admissionv1.AdmissionRequest{
+		UID:                in.UID,
+		Kind:               in.Kind,
+		Resource:           in.Resource,
+		SubResource:        in.SubResource,
+		RequestKind:        in.RequestKind,
+		RequestResource:    in.RequestResource,
+		RequestSubResource: in.RequestSubResource,
+		Name:               in.Name,
+		Namespace:          in.Namespace,
+		Operation:          admissionv1.Operation(in.Operation),
+		UserInfo:           in.UserInfo,
+		Object:             in.Object,
+		OldObject:          in.OldObject,
+		DryRun:             in.DryRun,
+		Options:            in.Options,
+	}
+}
+
+// toV1beta1AdmissionResponse converts a v1 AdmissionResponse, both versions have the same fields.
+// The UID of the request is echoed as generic-admission-server only sets it on the v1 AdmissionReviews.
+func toV1beta1AdmissionResponse(request *admissionv1beta1.AdmissionRequest, in *admissionv1.AdmissionResponse) *admissionv1beta1.AdmissionResponse {
+	out := &admissionv1beta1.AdmissionResponse{
+		UID:              request.UID,
+		Allowed:          in.Allowed,
+		Result:           in.Result,
+		Patch:            in.Patch,
+		AuditAnnotations: in.AuditAnnotations,
+		Warnings:         in.Warnings,
+	}
+	if in.PatchType != nil {
+		patchType := admissionv1beta1.PatchType(*in.PatchType)
+		out.PatchType = &patchType
+	}
+	return out
+}
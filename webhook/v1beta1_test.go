@@ -0,0 +1,75 @@
+// Copyright Red Hat
+
+package webhook
+
+import (
+	"net/http"
+	"testing"
+
+	singaporev1alpha1 "github.com/stolostron/cluster-registration-operator/api/singapore/v1alpha1"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+)
+
+func newV1beta1AdmissionRequest(t *testing.T, operation admissionv1beta1.Operation, regCluster, oldRegCluster *singaporev1alpha1.RegisteredCluster) *admissionv1beta1.AdmissionRequest {
+	request := newAdmissionRequest(t, admissionv1.Operation(operation), regCluster, oldRegCluster)
+	return &admissionv1beta1.AdmissionRequest{
+		UID:       "uid",
+		Operation: operation,
+		Resource:  request.Resource,
+		Object:    request.Object,
+		OldObject: request.OldObject,
+	}
+}
+
+func TestV1beta1ValidateRegisteredCluster(t *testing.T) {
+	a := &RegisteredClusterAdmissionHookV1Beta1{Hook: newAdmissionHook(t, singaporev1alpha1.RegistrationPolicy{})}
+
+	regCluster := newRegisteredCluster("cluster1", workspaceName, singaporev1alpha1.RegisteredClusterSpec{})
+	response := a.Validate(newV1beta1AdmissionRequest(t, admissionv1beta1.Create, regCluster, nil))
+	if !response.Allowed {
+		t.Fatalf("Request denied but expected to be allowed: %v", response.Result)
+	}
+
+	regCluster = newRegisteredCluster("Cluster_1", workspaceName, singaporev1alpha1.RegisteredClusterSpec{})
+	response = a.Validate(newV1beta1AdmissionRequest(t, admissionv1beta1.Create, regCluster, nil))
+	if response.Allowed {
+		t.Fatalf("Request allowed but expected to be denied.")
+	}
+	if response.Result.Code != http.StatusUnprocessableEntity {
+		t.Fatalf(`Response code not as expected. Expected %d, actual %d`, http.StatusUnprocessableEntity, response.Result.Code)
+	}
+}
+
+func TestV1beta1MutateRegisteredCluster(t *testing.T) {
+	a := &RegisteredClusterAdmissionHookV1Beta1{Hook: newAdmissionHook(t, singaporev1alpha1.RegistrationPolicy{})}
+
+	regCluster := newRegisteredCluster("cluster1", workspaceName, singaporev1alpha1.RegisteredClusterSpec{})
+	response := a.Admit(newV1beta1AdmissionRequest(t, admissionv1beta1.Create, regCluster, nil))
+	if !response.Allowed {
+		t.Fatalf("Request denied but expected to be allowed: %v", response.Result)
+	}
+	if response.PatchType == nil || *response.PatchType != admissionv1beta1.PatchTypeJSONPatch {
+		t.Fatalf("JSON patch expected.")
+	}
+
+	mutated := applyPatch(t, regCluster, response.Patch)
+	if mutated.Spec.HubConfigName != "hub-1" {
+		t.Fatalf(`HubConfigName not as expected. Expected %s, actual %s`, "hub-1", mutated.Spec.HubConfigName)
+	}
+}
+
+func TestV1beta1Resources(t *testing.T) {
+	a := &RegisteredClusterAdmissionHookV1Beta1{Hook: &RegisteredClusterAdmissionHook{}}
+	v1beta1Validating, _ := a.ValidatingResource()
+	v1beta1Mutating, _ := a.MutatingResource()
+	v1Validating, _ := a.Hook.ValidatingResource()
+	v1Mutating, _ := a.Hook.MutatingResource()
+	if v1beta1Validating == v1Validating || v1beta1Mutating == v1Mutating {
+		t.Fatalf("The v1beta1 and v1 hooks must be served on different resources.")
+	}
+	if v1beta1Validating.Version != "v1alpha1" || v1Validating.Version != "v1" {
+		t.Fatalf(`Versions not as expected: %s, %s`, v1beta1Validating.Version, v1Validating.Version)
+	}
+}
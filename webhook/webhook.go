@@ -3,16 +3,27 @@
 package webhook
 
 import (
+	"context"
 	"encoding/json"
-	"net/http"
+	"fmt"
+	"os"
 	"strings"
 	"sync"
 
 	singaporev1alpha1 "github.com/stolostron/cluster-registration-operator/api/singapore/v1alpha1"
+	"github.com/stolostron/cluster-registration-operator/pkg/helpers"
 
-	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apivalidation "k8s.io/apimachinery/pkg/api/validation"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
@@ -21,11 +32,29 @@ import (
 
 const (
 	GROUP_SUFFIX = "singapore.open-cluster-management.io"
+
+	// registeredClusterNameMaxLength leaves room for the suffixes added to the RegisteredCluster name
+	// when naming the import configmap and the kubeconfig secret.
+	registeredClusterNameMaxLength = 50
 )
 
+// reservedLabelPrefixes are the label prefixes managed by the operator or the hub, they can't be set by the user.
+var reservedLabelPrefixes = []string{
+	"registeredcluster." + GROUP_SUFFIX + "/",
+	"cluster.open-cluster-management.io/",
+}
+
+// RegisteredClusterAdmissionHook validates and mutates the RegisteredClusters, it serves the admission.k8s.io/v1 AdmissionReviews.
+// The v1beta1 AdmissionReviews are served by the RegisteredClusterAdmissionHookV1Beta1.
 type RegisteredClusterAdmissionHook struct {
-	Client      dynamic.ResourceInterface
-	KubeClient  kubernetes.Interface
+	Client                 dynamic.ResourceInterface
+	ClusterRegistrarClient dynamic.NamespaceableResourceInterface
+	HubConfigClient        dynamic.NamespaceableResourceInterface
+	KubeClient             kubernetes.Interface
+	// HubDynamicClient returns a client on the hub configured by the HubConfig with the given name
+	HubDynamicClient func(hubConfigName string) (dynamic.Interface, error)
+	// Namespace is the installation namespace holding the HubConfigs
+	Namespace   string
 	lock        sync.RWMutex
 	initialized bool
 }
@@ -35,15 +64,15 @@ type RegisteredClusterAdmissionHook struct {
 func (a *RegisteredClusterAdmissionHook) ValidatingResource() (plural schema.GroupVersionResource, singular string) {
 	return schema.GroupVersionResource{
 			Group:    "admission." + GROUP_SUFFIX,
-			Version:  "v1alpha1",
+			Version:  "v1",
 			Resource: "registeredclusters",
 		},
 		"registeredcluster"
 }
 
-// Validate is called by generic-admission-server when the registered REST resource above is called with an admission request.
-func (a *RegisteredClusterAdmissionHook) Validate(admissionSpec *admissionv1beta1.AdmissionRequest) *admissionv1beta1.AdmissionResponse {
-	status := &admissionv1beta1.AdmissionResponse{}
+// Validate is called by generic-admission-server when the registered REST resource above is called with an admission.k8s.io/v1 admission request.
+func (a *RegisteredClusterAdmissionHook) Validate(admissionSpec *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	status := &admissionv1.AdmissionResponse{}
 	klog.V(4).Infof("RegisteredCluster Validate %q operation for object %q, group: %s, resource: %s", admissionSpec.Operation, admissionSpec.Object, admissionSpec.Resource.Group, admissionSpec.Resource.Resource)
 
 	// only validate the request for authrealm
@@ -54,6 +83,9 @@ func (a *RegisteredClusterAdmissionHook) Validate(admissionSpec *admissionv1beta
 
 	switch admissionSpec.Resource.Resource {
 	case "registeredclusters":
+		if admissionSpec.Operation == admissionv1.Delete {
+			return a.ValidateRegisteredClusterDelete(admissionSpec)
+		}
 		return a.ValidateRegisteredCluster(admissionSpec)
 
 	}
@@ -61,42 +93,380 @@ func (a *RegisteredClusterAdmissionHook) Validate(admissionSpec *admissionv1beta
 	return status
 }
 
-func (a *RegisteredClusterAdmissionHook) ValidateRegisteredCluster(admissionSpec *admissionv1beta1.AdmissionRequest) *admissionv1beta1.AdmissionResponse {
-	status := &admissionv1beta1.AdmissionResponse{}
+func (a *RegisteredClusterAdmissionHook) ValidateRegisteredCluster(admissionSpec *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	status := &admissionv1.AdmissionResponse{}
 
 	regCluster := &singaporev1alpha1.RegisteredCluster{}
 
 	err := json.Unmarshal(admissionSpec.Object.Raw, regCluster)
 	if err != nil {
-		status.Allowed = false
-		status.Result = &metav1.Status{
-			Status: metav1.StatusFailure, Code: http.StatusBadRequest, Reason: metav1.StatusReasonBadRequest,
-			Message: err.Error(),
-		}
-		return status
+		return badRequest(err)
 	}
 
 	klog.V(4).Infof("Validate webhook for RegisteredCluster name: %s, namespace: %s", regCluster.Name, regCluster.Namespace)
+
+	policy, err := a.getRegistrationPolicy()
+	if err != nil {
+		return internalError(err)
+	}
+
+	var errs field.ErrorList
 	switch admissionSpec.Operation {
-	case admissionv1beta1.Create:
+	case admissionv1.Create:
 		klog.V(4).Info("Validate RegisteredCluster create ")
 
-		if len(regCluster.Name) > 50 {
-			status.Allowed = false
-			status.Result = &metav1.Status{
-				Status: metav1.StatusFailure, Code: http.StatusForbidden, Reason: metav1.StatusReasonForbidden,
-				Message: "RegisteredCluster name is too long (max 50 characters)",
+		errs = append(errs, validateRegisteredClusterName(regCluster.Name, field.NewPath("metadata", "name"))...)
+
+		namespaceErrs, err := a.validateWorkspace(regCluster.Namespace, field.NewPath("metadata", "namespace"))
+		if err != nil {
+			return internalError(err)
+		}
+		errs = append(errs, namespaceErrs...)
+		hubErrs, err := a.validateHubConfigName(regCluster.Spec.HubConfigName, field.NewPath("spec", "hubConfigName"))
+		if err != nil {
+			return internalError(err)
+		}
+		errs = append(errs, hubErrs...)
+		errs = append(errs, validateManagedClusterSet(regCluster, field.NewPath("spec", "managedClusterSet"))...)
+		specErrs := validateRegisteredClusterSpec(&regCluster.Spec, nil, policy, field.NewPath("spec"))
+		errs = append(errs, specErrs...)
+		if len(regCluster.Spec.ManagedClusterName) != 0 && len(specErrs) == 0 {
+			adoptionErrs, err := a.validateAdoptedManagedCluster(regCluster, field.NewPath("spec", "managedClusterName"))
+			if err != nil {
+				return internalError(err)
 			}
-			return status
+			errs = append(errs, adoptionErrs...)
+		}
+		errs = append(errs, validateApproval(regCluster, nil, policy, admissionSpec.UserInfo)...)
+		accessErrs, err := a.validateAutoImportSecretAccess(regCluster, nil, admissionSpec.UserInfo, field.NewPath("spec", "autoImportSecretRef", "name"))
+		if err != nil {
+			return internalError(err)
 		}
+		errs = append(errs, accessErrs...)
+	case admissionv1.Update:
+		klog.V(4).Info("Validate RegisteredCluster update ")
 
-		status.Allowed = true
+		oldRegCluster := &singaporev1alpha1.RegisteredCluster{}
+		if err := json.Unmarshal(admissionSpec.OldObject.Raw, oldRegCluster); err != nil {
+			return badRequest(err)
+		}
+
+		errs = append(errs, validateRegisteredClusterUpdate(regCluster, oldRegCluster)...)
+		if regCluster.Spec.HubConfigName != oldRegCluster.Spec.HubConfigName {
+			hubErrs, err := a.validateHubConfigName(regCluster.Spec.HubConfigName, field.NewPath("spec", "hubConfigName"))
+			if err != nil {
+				return internalError(err)
+			}
+			errs = append(errs, hubErrs...)
+		}
+		errs = append(errs, validateRegisteredClusterSpec(&regCluster.Spec, &oldRegCluster.Spec, policy, field.NewPath("spec"))...)
+		errs = append(errs, validateApproval(regCluster, oldRegCluster, policy, admissionSpec.UserInfo)...)
+		accessErrs, err := a.validateAutoImportSecretAccess(regCluster, oldRegCluster, admissionSpec.UserInfo, field.NewPath("spec", "autoImportSecretRef", "name"))
+		if err != nil {
+			return internalError(err)
+		}
+		errs = append(errs, accessErrs...)
+	}
+
+	if len(errs) != 0 {
+		statusErr := apierrors.NewInvalid(singaporev1alpha1.SchemeGroupVersion.WithKind("RegisteredCluster").GroupKind(), regCluster.Name, errs)
+		status.Allowed = false
+		status.Result = &statusErr.ErrStatus
 		return status
 	}
+
 	status.Allowed = true
 	return status
 }
 
+// internalError returns the response denying the request because it couldn't be validated.
+func internalError(err error) *admissionv1.AdmissionResponse {
+	return &admissionv1.AdmissionResponse{
+		Allowed: false,
+		Result:  &apierrors.NewInternalError(err).ErrStatus,
+	}
+}
+
+// badRequest returns the response denying the request because its objects couldn't be decoded.
+func badRequest(err error) *admissionv1.AdmissionResponse {
+	return &admissionv1.AdmissionResponse{
+		Allowed: false,
+		Result:  &apierrors.NewBadRequest(err.Error()).ErrStatus,
+	}
+}
+
+// validateRegisteredClusterName checks the name can be used to name the resources created for the RegisteredCluster.
+func validateRegisteredClusterName(name string, fldPath *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	if len(name) > registeredClusterNameMaxLength {
+		errs = append(errs, field.TooLong(fldPath, name, registeredClusterNameMaxLength))
+	}
+	for _, msg := range validation.IsDNS1123Label(name) {
+		errs = append(errs, field.Invalid(fldPath, name, msg))
+	}
+	return errs
+}
+
+// validateWorkspace checks the RegisteredCluster is created in a workspace namespace.
+func (a *RegisteredClusterAdmissionHook) validateWorkspace(namespace string, fldPath *field.Path) (field.ErrorList, error) {
+	ns, err := a.KubeClient.CoreV1().Namespaces().Get(context.TODO(), namespace, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if !helpers.IsWorkspace(ns.Labels) {
+		return field.ErrorList{field.Forbidden(fldPath, fmt.Sprintf("namespace %s is not a workspace", namespace))}, nil
+	}
+	return nil, nil
+}
+
+// validateRegisteredClusterSpec checks the spec against the registration policy of the ClusterRegistrar. On update, only the
+// values changed from the old spec are checked, so the existing RegisteredClusters can still be updated once the policy is tightened.
+func validateRegisteredClusterSpec(spec, oldSpec *singaporev1alpha1.RegisteredClusterSpec, policy *singaporev1alpha1.RegistrationPolicy, fldPath *field.Path) field.ErrorList {
+	if oldSpec == nil {
+		oldSpec = &singaporev1alpha1.RegisteredClusterSpec{}
+	}
+	changedLabels := map[string]string{}
+	for k, v := range spec.Labels {
+		if old, ok := oldSpec.Labels[k]; !ok || old != v {
+			changedLabels[k] = v
+		}
+	}
+	errs := metav1validation.ValidateLabels(changedLabels, fldPath.Child("labels"))
+	for k := range changedLabels {
+		if hasPrefix(k, reservedLabelPrefixes) {
+			errs = append(errs, field.Forbidden(fldPath.Child("labels").Key(k), "label is managed by the operator"))
+			continue
+		}
+		if !hasPrefix(k, policy.AllowedLabelPrefixes) {
+			errs = append(errs, field.Forbidden(fldPath.Child("labels").Key(k), "label is not allowed by the registration policy"))
+		}
+	}
+
+	for i, addOn := range spec.AddOns {
+		if !contains(addOn, oldSpec.AddOns) && !contains(addOn, policy.AllowedAddOns) {
+			errs = append(errs, field.NotSupported(fldPath.Child("addOns").Index(i), addOn, policy.AllowedAddOns))
+		}
+	}
+
+	if len(spec.AccessProfile) != 0 && spec.AccessProfile != oldSpec.AccessProfile && !contains(spec.AccessProfile, policy.AllowedAccessProfiles) {
+		errs = append(errs, field.NotSupported(fldPath.Child("accessProfile"), spec.AccessProfile, policy.AllowedAccessProfiles))
+	}
+
+	if len(spec.ManagedClusterName) != 0 && spec.ManagedClusterName != oldSpec.ManagedClusterName && !contains(spec.ManagedClusterName, policy.AdoptableManagedClusters) {
+		errs = append(errs, field.NotSupported(fldPath.Child("managedClusterName"), spec.ManagedClusterName, policy.AdoptableManagedClusters))
+	}
+
+	if spec.AutoImportSecretRef != nil && len(spec.AutoImportSecretRef.Name) == 0 {
+		errs = append(errs, field.Required(fldPath.Child("autoImportSecretRef", "name"), "the name of the Secret holding the credentials of the cluster is required"))
+	}
+	return errs
+}
+
+// validateAdoptedManagedCluster checks the ManagedCluster to adopt exists on the hub, is not registered
+// by another RegisteredCluster and doesn't belong to another ManagedClusterSet.
+func (a *RegisteredClusterAdmissionHook) validateAdoptedManagedCluster(regCluster *singaporev1alpha1.RegisteredCluster, fldPath *field.Path) (field.ErrorList, error) {
+	hubConfigName := regCluster.Spec.HubConfigName
+	if len(hubConfigName) == 0 {
+		var err error
+		hubConfigName, err = a.getDefaultHubConfigName(regCluster.Namespace)
+		if err != nil {
+			return nil, err
+		}
+	}
+	hubClient, err := a.HubDynamicClient(hubConfigName)
+	if err != nil {
+		return nil, err
+	}
+	managedCluster, err := hubClient.Resource(managedClusterGVR).Get(context.TODO(), regCluster.Spec.ManagedClusterName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return field.ErrorList{field.NotFound(fldPath, regCluster.Spec.ManagedClusterName)}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if name, ok := managedCluster.GetLabels()[registeredClusterNameLabel]; ok {
+		return field.ErrorList{field.Forbidden(fldPath, fmt.Sprintf("ManagedCluster %s is already registered by the RegisteredCluster %s/%s",
+			managedCluster.GetName(), managedCluster.GetLabels()[registeredClusterNamespaceLabel], name))}, nil
+	}
+	// The mutating webhook sets the ManagedClusterSet of the workspace by default
+	if set, ok := managedCluster.GetLabels()[ManagedClusterSetLabel]; ok && set != regCluster.Spec.ManagedClusterSet {
+		return field.ErrorList{field.Forbidden(fldPath, fmt.Sprintf("ManagedCluster %s belongs to the ManagedClusterSet %s", managedCluster.GetName(), set))}, nil
+	}
+	return nil, nil
+}
+
+// validateManagedClusterSet checks the cluster is not added to the ManagedClusterSet of another workspace.
+func validateManagedClusterSet(regCluster *singaporev1alpha1.RegisteredCluster, fldPath *field.Path) field.ErrorList {
+	mcsName := helpers.ManagedClusterSetNameForWorkspace(regCluster.Namespace)
+	if len(regCluster.Spec.ManagedClusterSet) != 0 && regCluster.Spec.ManagedClusterSet != mcsName {
+		return field.ErrorList{field.Forbidden(fldPath, fmt.Sprintf("must be the ManagedClusterSet of the workspace: %s", mcsName))}
+	}
+	return nil
+}
+
+// validateRegisteredClusterUpdate checks the immutable fields of the spec and the creator annotation are not changed.
+// The hub can be changed to migrate the cluster, but not while the migration source annotation set by the
+// mutating webhook is present. The annotation can only be removed once the cluster is registered on the hub of its spec.
+func validateRegisteredClusterUpdate(regCluster, oldRegCluster *singaporev1alpha1.RegisteredCluster) field.ErrorList {
+	fldPath := field.NewPath("spec")
+	sourceFldPath := field.NewPath("metadata", "annotations").Key(singaporev1alpha1.MigrationSourceHubAnnotation)
+	var errs field.ErrorList
+	source := regCluster.Annotations[singaporev1alpha1.MigrationSourceHubAnnotation]
+	oldSource, migrating := oldRegCluster.Annotations[singaporev1alpha1.MigrationSourceHubAnnotation]
+	switch {
+	case regCluster.Spec.HubConfigName != oldRegCluster.Spec.HubConfigName && len(regCluster.Spec.HubConfigName) == 0:
+		errs = append(errs, field.Required(fldPath.Child("hubConfigName"), "the hub can not be unset"))
+	case regCluster.Spec.HubConfigName != oldRegCluster.Spec.HubConfigName && migrating:
+		errs = append(errs, field.Forbidden(fldPath.Child("hubConfigName"),
+			fmt.Sprintf("the cluster is being migrated from the hub %s to the hub %s", oldSource, oldRegCluster.Spec.HubConfigName)))
+	case regCluster.Spec.HubConfigName == oldRegCluster.Spec.HubConfigName && source != oldSource:
+		if len(source) != 0 || !isRegisteredOnSpecHub(oldRegCluster) {
+			errs = append(errs, field.Forbidden(sourceFldPath, "the annotation is removed by the operator once the migration is completed"))
+		}
+	}
+	errs = append(errs, apivalidation.ValidateImmutableField(regCluster.Spec.ManagedClusterSet, oldRegCluster.Spec.ManagedClusterSet, fldPath.Child("managedClusterSet"))...)
+	errs = append(errs, apivalidation.ValidateImmutableField(regCluster.Spec.ManagedClusterName, oldRegCluster.Spec.ManagedClusterName, fldPath.Child("managedClusterName"))...)
+	errs = append(errs, apivalidation.ValidateImmutableField(regCluster.Annotations[CreatedByAnnotation], oldRegCluster.Annotations[CreatedByAnnotation],
+		field.NewPath("metadata", "annotations").Key(CreatedByAnnotation))...)
+	return errs
+}
+
+// isRegisteredOnSpecHub returns true when the cluster is not being migrated, the operator has either registered it
+// on the hub of its spec or not registered it yet.
+func isRegisteredOnSpecHub(regCluster *singaporev1alpha1.RegisteredCluster) bool {
+	return regCluster.Status.Migration == nil &&
+		(len(regCluster.Status.HubConfigName) == 0 || regCluster.Status.HubConfigName == regCluster.Spec.HubConfigName)
+}
+
+// validateHubConfigName checks the HubConfig of the hub exists in the installation namespace.
+func (a *RegisteredClusterAdmissionHook) validateHubConfigName(hubConfigName string, fldPath *field.Path) (field.ErrorList, error) {
+	if len(hubConfigName) == 0 {
+		return nil, nil
+	}
+	_, err := a.HubConfigClient.Namespace(a.Namespace).Get(context.TODO(), hubConfigName, metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		return field.ErrorList{field.NotFound(fldPath, hubConfigName)}, nil
+	case err != nil:
+		return nil, err
+	}
+	return nil, nil
+}
+
+// validateApproval checks the RegisteredCluster is approved by an approver of the registration policy,
+// and that the approval requirement and the approver recorded by the mutating webhook are not changed.
+func validateApproval(regCluster, oldRegCluster *singaporev1alpha1.RegisteredCluster, policy *singaporev1alpha1.RegistrationPolicy, userInfo authenticationv1.UserInfo) field.ErrorList {
+	fldPath := field.NewPath("metadata", "annotations")
+	var errs field.ErrorList
+	approved := regCluster.Annotations[singaporev1alpha1.ApprovedAnnotation]
+	oldApproved := ""
+	if oldRegCluster != nil {
+		oldApproved = oldRegCluster.Annotations[singaporev1alpha1.ApprovedAnnotation]
+		errs = append(errs, apivalidation.ValidateImmutableField(regCluster.Annotations[singaporev1alpha1.ApprovalRequiredAnnotation],
+			oldRegCluster.Annotations[singaporev1alpha1.ApprovalRequiredAnnotation], fldPath.Key(singaporev1alpha1.ApprovalRequiredAnnotation))...)
+		if approved == oldApproved {
+			errs = append(errs, apivalidation.ValidateImmutableField(regCluster.Annotations[singaporev1alpha1.ApprovedByAnnotation],
+				oldRegCluster.Annotations[singaporev1alpha1.ApprovedByAnnotation], fldPath.Key(singaporev1alpha1.ApprovedByAnnotation))...)
+		}
+	}
+	if approved != oldApproved && !isApprover(userInfo, policy) {
+		errs = append(errs, field.Forbidden(fldPath.Key(singaporev1alpha1.ApprovedAnnotation),
+			fmt.Sprintf("user %s is not an approver of the registration policy", userInfo.Username)))
+	}
+	return errs
+}
+
+// validateAutoImportSecretAccess checks the user can read and delete the Secret of the autoImportSecretRef,
+// as the operator copies it to the hub and deletes it on behalf of the user.
+func (a *RegisteredClusterAdmissionHook) validateAutoImportSecretAccess(regCluster, oldRegCluster *singaporev1alpha1.RegisteredCluster, userInfo authenticationv1.UserInfo, fldPath *field.Path) (field.ErrorList, error) {
+	ref := regCluster.Spec.AutoImportSecretRef
+	if ref == nil || len(ref.Name) == 0 {
+		return nil, nil
+	}
+	if oldRegCluster != nil && oldRegCluster.Spec.AutoImportSecretRef != nil && oldRegCluster.Spec.AutoImportSecretRef.Name == ref.Name {
+		return nil, nil
+	}
+
+	extra := map[string]authorizationv1.ExtraValue{}
+	for k, v := range userInfo.Extra {
+		extra[k] = authorizationv1.ExtraValue(v)
+	}
+	for _, verb := range []string{"get", "delete"} {
+		review, err := a.KubeClient.AuthorizationV1().SubjectAccessReviews().Create(context.TODO(), &authorizationv1.SubjectAccessReview{
+			Spec: authorizationv1.SubjectAccessReviewSpec{
+				User:   userInfo.Username,
+				UID:    userInfo.UID,
+				Groups: userInfo.Groups,
+				Extra:  extra,
+				ResourceAttributes: &authorizationv1.ResourceAttributes{
+					Namespace: regCluster.Namespace,
+					Verb:      verb,
+					Resource:  "secrets",
+					Name:      ref.Name,
+				},
+			},
+		}, metav1.CreateOptions{})
+		if err != nil {
+			return nil, err
+		}
+		if !review.Status.Allowed {
+			return field.ErrorList{field.Forbidden(fldPath, fmt.Sprintf("user %s is not allowed to %s the secret %s", userInfo.Username, verb, ref.Name))}, nil
+		}
+	}
+	return nil, nil
+}
+
+// isApprover returns true when the user or one of its groups is an approver of the registration policy.
+func isApprover(userInfo authenticationv1.UserInfo, policy *singaporev1alpha1.RegistrationPolicy) bool {
+	if contains(userInfo.Username, policy.Approvers) {
+		return true
+	}
+	for _, group := range userInfo.Groups {
+		if contains(group, policy.ApproverGroups) {
+			return true
+		}
+	}
+	return false
+}
+
+// getRegistrationPolicy returns the registration policy of the active ClusterRegistrar, the oldest one,
+// an empty policy is returned if no ClusterRegistrar exists.
+func (a *RegisteredClusterAdmissionHook) getRegistrationPolicy() (*singaporev1alpha1.RegistrationPolicy, error) {
+	clusterRegistrarList, err := a.ClusterRegistrarClient.List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	clusterRegistrars := make([]singaporev1alpha1.ClusterRegistrar, len(clusterRegistrarList.Items))
+	for i := range clusterRegistrarList.Items {
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(clusterRegistrarList.Items[i].Object, &clusterRegistrars[i]); err != nil {
+			return nil, err
+		}
+	}
+	clusterRegistrar := helpers.GetActiveClusterRegistrar(clusterRegistrars)
+	if clusterRegistrar == nil {
+		return &singaporev1alpha1.RegistrationPolicy{}, nil
+	}
+	return &clusterRegistrar.Spec.RegistrationPolicy, nil
+}
+
+func hasPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(s string, list []string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
+
 // Initialize is called by generic-admission-server on startup to setup initialization that webhook needs.
 func (a *RegisteredClusterAdmissionHook) Initialize(kubeClientConfig *rest.Config, stopCh <-chan struct{}) error {
 	a.lock.Lock()
@@ -106,6 +476,35 @@ func (a *RegisteredClusterAdmissionHook) Initialize(kubeClientConfig *rest.Confi
 
 	a.initialized = true
 
+	kubeClient, dynamicClient, err := newClients(kubeClientConfig)
+	if err != nil {
+		return err
+	}
+	a.KubeClient = kubeClient
+
+	a.Client = dynamicClient.Resource(schema.GroupVersionResource{
+		Group:    GROUP_SUFFIX,
+		Version:  "v1alpha1",
+		Resource: "registeredclusters",
+	})
+	a.ClusterRegistrarClient = dynamicClient.Resource(schema.GroupVersionResource{
+		Group:    GROUP_SUFFIX,
+		Version:  "v1alpha1",
+		Resource: "clusterregistrars",
+	})
+	a.HubConfigClient = dynamicClient.Resource(schema.GroupVersionResource{
+		Group:    GROUP_SUFFIX,
+		Version:  "v1alpha1",
+		Resource: "hubconfigs",
+	})
+	a.HubDynamicClient = a.newHubDynamicClient
+	a.Namespace = os.Getenv("POD_NAMESPACE")
+
+	return nil
+}
+
+// newClients returns the clients used by the admission hooks to read the singapore resources.
+func newClients(kubeClientConfig *rest.Config) (kubernetes.Interface, dynamic.Interface, error) {
 	shallowClientConfigCopy := *kubeClientConfig
 	shallowClientConfigCopy.GroupVersion = &schema.GroupVersion{
 		Group:   GROUP_SUFFIX,
@@ -114,19 +513,12 @@ func (a *RegisteredClusterAdmissionHook) Initialize(kubeClientConfig *rest.Confi
 	shallowClientConfigCopy.APIPath = "/apis"
 	kubeClient, err := kubernetes.NewForConfig(&shallowClientConfigCopy)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
-	a.KubeClient = kubeClient
 
 	dynamicClient, err := dynamic.NewForConfig(&shallowClientConfigCopy)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
-	a.Client = dynamicClient.Resource(schema.GroupVersionResource{
-		Group:    GROUP_SUFFIX,
-		Version:  "v1alpha1",
-		Resource: "registeredclusters",
-	})
-
-	return nil
+	return kubeClient, dynamicClient, nil
 }
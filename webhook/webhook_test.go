@@ -0,0 +1,613 @@
+// Copyright Red Hat
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	singaporev1alpha1 "github.com/stolostron/cluster-registration-operator/api/singapore/v1alpha1"
+	"github.com/stolostron/cluster-registration-operator/pkg/helpers"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+const (
+	workspaceName = "workspace"
+)
+
+var clusterRegistrarGVR = schema.GroupVersionResource{
+	Group:    GROUP_SUFFIX,
+	Version:  "v1alpha1",
+	Resource: "clusterregistrars",
+}
+
+var hubConfigGVR = schema.GroupVersionResource{
+	Group:    GROUP_SUFFIX,
+	Version:  "v1alpha1",
+	Resource: "hubconfigs",
+}
+
+func newAdmissionHook(t *testing.T, policy singaporev1alpha1.RegistrationPolicy) *RegisteredClusterAdmissionHook {
+	kubeClient := kubefake.NewSimpleClientset(
+		&corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   workspaceName,
+				Labels: map[string]string{helpers.WorkspaceProviderLabel: helpers.WorkspaceProviderLabelValue},
+			},
+		},
+		&corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "not-a-workspace",
+			},
+		},
+	)
+
+	clusterRegistrar := &singaporev1alpha1.ClusterRegistrar{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: singaporev1alpha1.SchemeGroupVersion.String(),
+			Kind:       "ClusterRegistrar",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "cluster-registrar",
+			Namespace: "cluster-reg-config",
+		},
+		Spec: singaporev1alpha1.ClusterRegistrarSpec{
+			RegistrationPolicy: policy,
+		},
+	}
+	clusterRegistrarU, err := runtime.DefaultUnstructuredConverter.ToUnstructured(clusterRegistrar)
+	if err != nil {
+		t.Fatalf("Failed to convert ClusterRegistrar: %s", err)
+	}
+	var hubConfigs []runtime.Object
+	for _, name := range []string{"hub-1", "hub-2"} {
+		hubConfig := &unstructured.Unstructured{}
+		hubConfig.SetAPIVersion(singaporev1alpha1.SchemeGroupVersion.String())
+		hubConfig.SetKind("HubConfig")
+		hubConfig.SetName(name)
+		hubConfig.SetNamespace("cluster-reg-config")
+		hubConfigs = append(hubConfigs, hubConfig)
+	}
+
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{
+			clusterRegistrarGVR: "ClusterRegistrarList",
+			hubConfigGVR:        "HubConfigList",
+		},
+		append(hubConfigs, &unstructured.Unstructured{Object: clusterRegistrarU})...)
+
+	return &RegisteredClusterAdmissionHook{
+		KubeClient:             kubeClient,
+		ClusterRegistrarClient: dynamicClient.Resource(clusterRegistrarGVR),
+		HubConfigClient:        dynamicClient.Resource(hubConfigGVR),
+		Namespace:              "cluster-reg-config",
+	}
+}
+
+func newAdmissionRequest(t *testing.T, operation admissionv1.Operation, regCluster, oldRegCluster *singaporev1alpha1.RegisteredCluster) *admissionv1.AdmissionRequest {
+	request := &admissionv1.AdmissionRequest{
+		Operation: operation,
+		Resource: metav1.GroupVersionResource{
+			Group:    GROUP_SUFFIX,
+			Version:  "v1alpha1",
+			Resource: "registeredclusters",
+		},
+	}
+	b, err := json.Marshal(regCluster)
+	if err != nil {
+		t.Fatalf("Failed to marshal RegisteredCluster: %s", err)
+	}
+	request.Object.Raw = b
+	if oldRegCluster != nil {
+		b, err := json.Marshal(oldRegCluster)
+		if err != nil {
+			t.Fatalf("Failed to marshal RegisteredCluster: %s", err)
+		}
+		request.OldObject.Raw = b
+	}
+	return request
+}
+
+func newRegisteredCluster(name, namespace string, spec singaporev1alpha1.RegisteredClusterSpec) *singaporev1alpha1.RegisteredCluster {
+	return &singaporev1alpha1.RegisteredCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: spec,
+	}
+}
+
+func checkDenied(t *testing.T, response *admissionv1.AdmissionResponse, fields ...string) {
+	if response.Allowed {
+		t.Fatalf("Request allowed but expected to be denied.")
+	}
+	if response.Result.Code != http.StatusUnprocessableEntity {
+		t.Fatalf(`Response code not as expected. Expected %d, actual %d`, http.StatusUnprocessableEntity, response.Result.Code)
+	}
+	if len(response.Result.Details.Causes) != len(fields) {
+		t.Fatalf(`Number of causes not as expected. Expected %d, actual %d: %v`, len(fields), len(response.Result.Details.Causes), response.Result.Details.Causes)
+	}
+	for i, cause := range response.Result.Details.Causes {
+		if cause.Field != fields[i] {
+			t.Fatalf(`Cause field not as expected. Expected %s, actual %s`, fields[i], cause.Field)
+		}
+	}
+}
+
+func TestValidateRegisteredClusterCreate(t *testing.T) {
+	a := newAdmissionHook(t, singaporev1alpha1.RegistrationPolicy{
+		AllowedLabelPrefixes:  []string{"env"},
+		AllowedAddOns:         []string{"application-manager"},
+		AllowedAccessProfiles: []string{"view"},
+	})
+	regCluster := newRegisteredCluster("cluster1", workspaceName, singaporev1alpha1.RegisteredClusterSpec{
+		Labels:        map[string]string{"env": "dev"},
+		AddOns:        []string{"application-manager"},
+		AccessProfile: "view",
+	})
+	response := a.ValidateRegisteredCluster(newAdmissionRequest(t, admissionv1.Create, regCluster, nil))
+	if !response.Allowed {
+		t.Fatalf("Request denied but expected to be allowed: %v", response.Result)
+	}
+}
+
+func TestValidateRegisteredClusterCreateInvalidName(t *testing.T) {
+	a := newAdmissionHook(t, singaporev1alpha1.RegistrationPolicy{})
+	regCluster := newRegisteredCluster("Cluster_1", workspaceName, singaporev1alpha1.RegisteredClusterSpec{})
+	response := a.ValidateRegisteredCluster(newAdmissionRequest(t, admissionv1.Create, regCluster, nil))
+	checkDenied(t, response, "metadata.name")
+
+	regCluster = newRegisteredCluster("cluster-with-a-very-long-name-exceeding-fifty-chars", workspaceName, singaporev1alpha1.RegisteredClusterSpec{})
+	response = a.ValidateRegisteredCluster(newAdmissionRequest(t, admissionv1.Create, regCluster, nil))
+	checkDenied(t, response, "metadata.name")
+}
+
+func TestValidateRegisteredClusterCreateNotInWorkspace(t *testing.T) {
+	a := newAdmissionHook(t, singaporev1alpha1.RegistrationPolicy{})
+	regCluster := newRegisteredCluster("cluster1", "not-a-workspace", singaporev1alpha1.RegisteredClusterSpec{})
+	response := a.ValidateRegisteredCluster(newAdmissionRequest(t, admissionv1.Create, regCluster, nil))
+	checkDenied(t, response, "metadata.namespace")
+}
+
+func TestValidateRegisteredClusterCreateNotAllowed(t *testing.T) {
+	a := newAdmissionHook(t, singaporev1alpha1.RegistrationPolicy{
+		AllowedLabelPrefixes: []string{"env"},
+	})
+	regCluster := newRegisteredCluster("cluster1", workspaceName, singaporev1alpha1.RegisteredClusterSpec{
+		Labels: map[string]string{
+			"team": "a",
+			"cluster.open-cluster-management.io/clusterset": "other-workspace",
+			"env":                    "prod",
+			"registeredcluster-none": "ok",
+		},
+		AddOns:        []string{"application-manager"},
+		AccessProfile: "cluster-admin",
+	})
+	response := a.ValidateRegisteredCluster(newAdmissionRequest(t, admissionv1.Create, regCluster, nil))
+	if response.Allowed {
+		t.Fatalf("Request allowed but expected to be denied.")
+	}
+	// labels are validated in map order, only check the number of causes
+	if len(response.Result.Details.Causes) != 5 {
+		t.Fatalf(`Number of causes not as expected. Expected %d, actual %d: %v`, 5, len(response.Result.Details.Causes), response.Result.Details.Causes)
+	}
+}
+
+func TestValidateRegisteredClusterUpdateHub(t *testing.T) {
+	a := newAdmissionHook(t, singaporev1alpha1.RegistrationPolicy{})
+	oldRegCluster := newRegisteredCluster("cluster1", workspaceName, singaporev1alpha1.RegisteredClusterSpec{
+		HubConfigName: "hub-1",
+	})
+	regCluster := newRegisteredCluster("cluster1", workspaceName, singaporev1alpha1.RegisteredClusterSpec{
+		HubConfigName: "hub-2",
+	})
+	response := a.ValidateRegisteredCluster(newAdmissionRequest(t, admissionv1.Update, regCluster, oldRegCluster))
+	if !response.Allowed {
+		t.Fatalf("Request denied but expected to be allowed: %v", response.Result)
+	}
+
+	regCluster.Spec.HubConfigName = ""
+	response = a.ValidateRegisteredCluster(newAdmissionRequest(t, admissionv1.Update, regCluster, oldRegCluster))
+	checkDenied(t, response, "spec.hubConfigName")
+
+	// The target HubConfig must exist
+	regCluster.Spec.HubConfigName = "not-found"
+	response = a.ValidateRegisteredCluster(newAdmissionRequest(t, admissionv1.Update, regCluster, oldRegCluster))
+	checkDenied(t, response, "spec.hubConfigName")
+
+	// The hub can not be changed again while the cluster is migrated, even before the operator updated the status
+	oldRegCluster.Spec.HubConfigName = "hub-2"
+	oldRegCluster.Status.HubConfigName = "hub-1"
+	oldRegCluster.Annotations = map[string]string{singaporev1alpha1.MigrationSourceHubAnnotation: "hub-1"}
+	regCluster = oldRegCluster.DeepCopy()
+	regCluster.Spec.HubConfigName = "hub-1"
+	response = a.ValidateRegisteredCluster(newAdmissionRequest(t, admissionv1.Update, regCluster, oldRegCluster))
+	checkDenied(t, response, "spec.hubConfigName")
+
+	// The migration source can not be removed or changed until the cluster is registered on the hub of its spec
+	sourceField := "metadata.annotations[" + singaporev1alpha1.MigrationSourceHubAnnotation + "]"
+	regCluster = oldRegCluster.DeepCopy()
+	delete(regCluster.Annotations, singaporev1alpha1.MigrationSourceHubAnnotation)
+	response = a.ValidateRegisteredCluster(newAdmissionRequest(t, admissionv1.Update, regCluster, oldRegCluster))
+	checkDenied(t, response, sourceField)
+
+	oldRegCluster.Status.HubConfigName = "hub-2"
+	response = a.ValidateRegisteredCluster(newAdmissionRequest(t, admissionv1.Update, regCluster, oldRegCluster))
+	if !response.Allowed {
+		t.Fatalf("Request denied but expected to be allowed: %v", response.Result)
+	}
+
+	regCluster.Annotations[singaporev1alpha1.MigrationSourceHubAnnotation] = "hub-3"
+	response = a.ValidateRegisteredCluster(newAdmissionRequest(t, admissionv1.Update, regCluster, oldRegCluster))
+	checkDenied(t, response, sourceField)
+}
+
+func TestValidateRegisteredClusterCreateHubNotFound(t *testing.T) {
+	a := newAdmissionHook(t, singaporev1alpha1.RegistrationPolicy{})
+	regCluster := newRegisteredCluster("cluster1", workspaceName, singaporev1alpha1.RegisteredClusterSpec{
+		HubConfigName:     "not-found",
+		ManagedClusterSet: helpers.ManagedClusterSetNameForWorkspace(workspaceName),
+	})
+	response := a.ValidateRegisteredCluster(newAdmissionRequest(t, admissionv1.Create, regCluster, nil))
+	checkDenied(t, response, "spec.hubConfigName")
+}
+
+func TestValidateRegisteredClusterAdoption(t *testing.T) {
+	a := newAdmissionHook(t, singaporev1alpha1.RegistrationPolicy{
+		AdoptableManagedClusters: []string{"local-cluster", "registered", "missing", "other-set"},
+	})
+	localCluster := newManagedCluster("local-cluster", &singaporev1alpha1.RegisteredCluster{})
+	localCluster.SetLabels(map[string]string{ManagedClusterSetLabel: helpers.ManagedClusterSetNameForWorkspace(workspaceName)})
+	otherSetCluster := newManagedCluster("other-set", &singaporev1alpha1.RegisteredCluster{})
+	otherSetCluster.SetLabels(map[string]string{ManagedClusterSetLabel: "other-workspace"})
+	hubClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{managedClusterGVR: "ManagedClusterList"},
+		localCluster, otherSetCluster, newManagedCluster("registered", newRegisteredCluster("other", workspaceName, singaporev1alpha1.RegisteredClusterSpec{})))
+	a.HubDynamicClient = func(hubConfigName string) (dynamic.Interface, error) {
+		return hubClient, nil
+	}
+
+	regCluster := newRegisteredCluster("cluster1", workspaceName, singaporev1alpha1.RegisteredClusterSpec{
+		HubConfigName:      "hub-1",
+		ManagedClusterSet:  helpers.ManagedClusterSetNameForWorkspace(workspaceName),
+		ManagedClusterName: "local-cluster",
+	})
+	response := a.ValidateRegisteredCluster(newAdmissionRequest(t, admissionv1.Create, regCluster, nil))
+	if !response.Allowed {
+		t.Fatalf("Request denied but expected to be allowed: %v", response.Result)
+	}
+
+	for _, name := range []string{"not-adoptable", "registered", "missing", "other-set"} {
+		regCluster.Spec.ManagedClusterName = name
+		response = a.ValidateRegisteredCluster(newAdmissionRequest(t, admissionv1.Create, regCluster, nil))
+		checkDenied(t, response, "spec.managedClusterName")
+	}
+
+	oldRegCluster := regCluster.DeepCopy()
+	oldRegCluster.Spec.ManagedClusterName = "local-cluster"
+	regCluster.Spec.ManagedClusterName = ""
+	response = a.ValidateRegisteredCluster(newAdmissionRequest(t, admissionv1.Update, regCluster, oldRegCluster))
+	checkDenied(t, response, "spec.managedClusterName")
+}
+
+func TestValidateRegisteredClusterAutoImportSecret(t *testing.T) {
+	a := newAdmissionHook(t, singaporev1alpha1.RegistrationPolicy{})
+	a.KubeClient.(*kubefake.Clientset).PrependReactor("create", "subjectaccessreviews", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		review := action.(clienttesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview)
+		review.Status.Allowed = review.Spec.ResourceAttributes.Name == "cluster1-credentials"
+		return true, review, nil
+	})
+	regCluster := newRegisteredCluster("cluster1", workspaceName, singaporev1alpha1.RegisteredClusterSpec{
+		AutoImportSecretRef: &corev1.LocalObjectReference{Name: "cluster1-credentials"},
+	})
+	response := a.ValidateRegisteredCluster(newAdmissionRequest(t, admissionv1.Create, regCluster, nil))
+	if !response.Allowed {
+		t.Fatalf("Request denied but expected to be allowed: %v", response.Result)
+	}
+
+	regCluster.Spec.AutoImportSecretRef.Name = ""
+	response = a.ValidateRegisteredCluster(newAdmissionRequest(t, admissionv1.Create, regCluster, nil))
+	checkDenied(t, response, "spec.autoImportSecretRef.name")
+
+	// The user must be allowed to read and delete the Secret
+	regCluster.Spec.AutoImportSecretRef.Name = "other-cluster-secret"
+	response = a.ValidateRegisteredCluster(newAdmissionRequest(t, admissionv1.Create, regCluster, nil))
+	checkDenied(t, response, "spec.autoImportSecretRef.name")
+
+	oldRegCluster := regCluster.DeepCopy()
+	oldRegCluster.Spec.AutoImportSecretRef.Name = "cluster1-credentials"
+	response = a.ValidateRegisteredCluster(newAdmissionRequest(t, admissionv1.Update, regCluster, oldRegCluster))
+	checkDenied(t, response, "spec.autoImportSecretRef.name")
+}
+
+func TestValidateRegisteredClusterCreateOtherManagedClusterSet(t *testing.T) {
+	a := newAdmissionHook(t, singaporev1alpha1.RegistrationPolicy{})
+	regCluster := newRegisteredCluster("cluster1", workspaceName, singaporev1alpha1.RegisteredClusterSpec{
+		ManagedClusterSet: "other-workspace",
+	})
+	response := a.ValidateRegisteredCluster(newAdmissionRequest(t, admissionv1.Create, regCluster, nil))
+	checkDenied(t, response, "spec.managedClusterSet")
+}
+
+func TestValidateRegisteredClusterUpdatePolicyTightened(t *testing.T) {
+	// The policy no longer allows the values of the existing RegisteredCluster
+	a := newAdmissionHook(t, singaporev1alpha1.RegistrationPolicy{
+		AllowedLabelPrefixes:  []string{"team"},
+		AllowedAddOns:         []string{"search-collector"},
+		AllowedAccessProfiles: []string{"view"},
+	})
+	oldRegCluster := newRegisteredCluster("cluster1", workspaceName, singaporev1alpha1.RegisteredClusterSpec{
+		HubConfigName: "hub-1",
+		Labels:        map[string]string{"env": "prod"},
+		AddOns:        []string{"application-manager"},
+		AccessProfile: "edit",
+	})
+	regCluster := oldRegCluster.DeepCopy()
+	regCluster.Annotations = map[string]string{"approved": "true"}
+	response := a.ValidateRegisteredCluster(newAdmissionRequest(t, admissionv1.Update, regCluster, oldRegCluster))
+	if !response.Allowed {
+		t.Fatalf("Request with unchanged spec denied but expected to be allowed: %v", response.Result)
+	}
+
+	regCluster.Spec.Labels["team"] = "a"
+	regCluster.Spec.AddOns = append(regCluster.Spec.AddOns, "search-collector")
+	response = a.ValidateRegisteredCluster(newAdmissionRequest(t, admissionv1.Update, regCluster, oldRegCluster))
+	if !response.Allowed {
+		t.Fatalf("Request adding allowed values denied but expected to be allowed: %v", response.Result)
+	}
+
+	// The changed values are checked against the current policy
+	regCluster.Spec.Labels["env"] = "dev"
+	regCluster.Spec.AddOns = append(regCluster.Spec.AddOns, "policy-controller")
+	regCluster.Spec.AccessProfile = "admin"
+	response = a.ValidateRegisteredCluster(newAdmissionRequest(t, admissionv1.Update, regCluster, oldRegCluster))
+	checkDenied(t, response, "spec.labels[env]", "spec.addOns[2]", "spec.accessProfile")
+}
+
+func TestGetRegistrationPolicyActiveClusterRegistrar(t *testing.T) {
+	a := newAdmissionHook(t, singaporev1alpha1.RegistrationPolicy{AllowedLabelPrefixes: []string{"env"}})
+	duplicate := &singaporev1alpha1.ClusterRegistrar{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: singaporev1alpha1.SchemeGroupVersion.String(),
+			Kind:       "ClusterRegistrar",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "a-duplicate",
+			Namespace:         "a-namespace",
+			CreationTimestamp: metav1.Now(),
+		},
+		Spec: singaporev1alpha1.ClusterRegistrarSpec{
+			RegistrationPolicy: singaporev1alpha1.RegistrationPolicy{AllowedLabelPrefixes: []string{"team"}},
+		},
+	}
+	duplicateU, err := runtime.DefaultUnstructuredConverter.ToUnstructured(duplicate)
+	if err != nil {
+		t.Fatalf("Failed to convert ClusterRegistrar: %s", err)
+	}
+	if _, err := a.ClusterRegistrarClient.Namespace(duplicate.Namespace).Create(context.TODO(), &unstructured.Unstructured{Object: duplicateU}, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("Failed to create ClusterRegistrar: %s", err)
+	}
+
+	// The policy of the oldest ClusterRegistrar is enforced
+	policy, err := a.getRegistrationPolicy()
+	if err != nil {
+		t.Fatalf("Failed to get the registration policy: %s", err)
+	}
+	if len(policy.AllowedLabelPrefixes) != 1 || policy.AllowedLabelPrefixes[0] != "env" {
+		t.Fatalf("Registration policy not as expected: %v", policy)
+	}
+}
+
+func TestValidateRegisteredClusterUpdateCreatedBy(t *testing.T) {
+	a := newAdmissionHook(t, singaporev1alpha1.RegistrationPolicy{})
+	oldRegCluster := newRegisteredCluster("cluster1", workspaceName, singaporev1alpha1.RegisteredClusterSpec{})
+	oldRegCluster.Annotations = map[string]string{CreatedByAnnotation: "janedoe"}
+	regCluster := oldRegCluster.DeepCopy()
+	regCluster.Annotations[CreatedByAnnotation] = "johndoe"
+	response := a.ValidateRegisteredCluster(newAdmissionRequest(t, admissionv1.Update, regCluster, oldRegCluster))
+	checkDenied(t, response, "metadata.annotations["+CreatedByAnnotation+"]")
+}
+
+func TestMutateRegisteredClusterCreate(t *testing.T) {
+	a := newAdmissionHook(t, singaporev1alpha1.RegistrationPolicy{
+		DefaultAccessProfile: "view",
+	})
+	regCluster := newRegisteredCluster("cluster1", workspaceName, singaporev1alpha1.RegisteredClusterSpec{})
+	request := newAdmissionRequest(t, admissionv1.Create, regCluster, nil)
+	request.UserInfo.Username = "janedoe"
+	response := a.MutateRegisteredCluster(request)
+	if !response.Allowed {
+		t.Fatalf("Request denied but expected to be allowed: %v", response.Result)
+	}
+	if response.PatchType == nil || *response.PatchType != admissionv1.PatchTypeJSONPatch {
+		t.Fatalf("JSON patch expected.")
+	}
+
+	mutated := applyPatch(t, regCluster, response.Patch)
+	if mutated.Spec.HubConfigName != "hub-1" {
+		t.Fatalf(`HubConfigName not as expected. Expected %s, actual %s`, "hub-1", mutated.Spec.HubConfigName)
+	}
+	if mutated.Spec.ManagedClusterSet != workspaceName {
+		t.Fatalf(`ManagedClusterSet not as expected. Expected %s, actual %s`, workspaceName, mutated.Spec.ManagedClusterSet)
+	}
+	if mutated.Spec.AccessProfile != "view" {
+		t.Fatalf(`AccessProfile not as expected. Expected %s, actual %s`, "view", mutated.Spec.AccessProfile)
+	}
+	if mutated.Labels[HubConfigNameLabel] != "hub-1" || mutated.Labels[ManagedClusterSetLabel] != workspaceName {
+		t.Fatalf(`Labels not as expected: %v`, mutated.Labels)
+	}
+	if mutated.Annotations[CreatedByAnnotation] != "janedoe" {
+		t.Fatalf(`Creator not as expected. Expected %s, actual %s`, "janedoe", mutated.Annotations[CreatedByAnnotation])
+	}
+}
+
+func TestMutateRegisteredClusterCreateKeepsValues(t *testing.T) {
+	a := newAdmissionHook(t, singaporev1alpha1.RegistrationPolicy{
+		DefaultAccessProfile: "view",
+	})
+	regCluster := newRegisteredCluster("cluster1", workspaceName, singaporev1alpha1.RegisteredClusterSpec{
+		HubConfigName: "hub-2",
+		AccessProfile: "edit",
+	})
+	response := a.MutateRegisteredCluster(newAdmissionRequest(t, admissionv1.Create, regCluster, nil))
+	mutated := applyPatch(t, regCluster, response.Patch)
+	if mutated.Spec.HubConfigName != "hub-2" {
+		t.Fatalf(`HubConfigName not as expected. Expected %s, actual %s`, "hub-2", mutated.Spec.HubConfigName)
+	}
+	if mutated.Spec.AccessProfile != "edit" {
+		t.Fatalf(`AccessProfile not as expected. Expected %s, actual %s`, "edit", mutated.Spec.AccessProfile)
+	}
+}
+
+func TestMutateRegisteredClusterCreateWorkspaceHub(t *testing.T) {
+	a := newAdmissionHook(t, singaporev1alpha1.RegistrationPolicy{})
+	ns, err := a.KubeClient.CoreV1().Namespaces().Get(context.TODO(), workspaceName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get the workspace: %s", err)
+	}
+	ns.Annotations = map[string]string{helpers.WorkspaceHubConfigAnnotation: "hub-2"}
+	if _, err := a.KubeClient.CoreV1().Namespaces().Update(context.TODO(), ns, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("Failed to update the workspace: %s", err)
+	}
+
+	regCluster := newRegisteredCluster("cluster1", workspaceName, singaporev1alpha1.RegisteredClusterSpec{})
+	response := a.MutateRegisteredCluster(newAdmissionRequest(t, admissionv1.Create, regCluster, nil))
+	mutated := applyPatch(t, regCluster, response.Patch)
+	if mutated.Spec.HubConfigName != "hub-2" || mutated.Labels[HubConfigNameLabel] != "hub-2" {
+		t.Fatalf(`HubConfigName not as expected. Expected %s, actual %s`, "hub-2", mutated.Spec.HubConfigName)
+	}
+}
+
+func TestMutateRegisteredClusterUpdateHub(t *testing.T) {
+	a := newAdmissionHook(t, singaporev1alpha1.RegistrationPolicy{
+		DefaultAccessProfile: "view",
+	})
+	oldRegCluster := newRegisteredCluster("cluster1", workspaceName, singaporev1alpha1.RegisteredClusterSpec{
+		HubConfigName: "hub-1",
+	})
+	oldRegCluster.Labels = map[string]string{HubConfigNameLabel: "hub-1"}
+	regCluster := oldRegCluster.DeepCopy()
+	regCluster.Spec.HubConfigName = "hub-2"
+	response := a.MutateRegisteredCluster(newAdmissionRequest(t, admissionv1.Update, regCluster, oldRegCluster))
+	if !response.Allowed {
+		t.Fatalf("Request denied but expected to be allowed: %v", response.Result)
+	}
+	mutated := applyPatch(t, regCluster, response.Patch)
+	if mutated.Labels[HubConfigNameLabel] != "hub-2" {
+		t.Fatalf(`Labels not as expected: %v`, mutated.Labels)
+	}
+	if mutated.Annotations[singaporev1alpha1.MigrationSourceHubAnnotation] != "hub-1" {
+		t.Fatalf(`Migration source not as expected: %v`, mutated.Annotations)
+	}
+	// The defaults are only set on creation
+	if len(mutated.Spec.AccessProfile) != 0 || len(mutated.Annotations[CreatedByAnnotation]) != 0 {
+		t.Fatalf(`RegisteredCluster not expected to be defaulted: %v`, mutated)
+	}
+}
+
+func applyPatch(t *testing.T, regCluster *singaporev1alpha1.RegisteredCluster, patch []byte) *singaporev1alpha1.RegisteredCluster {
+	original, err := json.Marshal(regCluster)
+	if err != nil {
+		t.Fatalf("Failed to marshal RegisteredCluster: %s", err)
+	}
+	p, err := jsonpatch.DecodePatch(patch)
+	if err != nil {
+		t.Fatalf("Failed to decode patch: %s", err)
+	}
+	b, err := p.Apply(original)
+	if err != nil {
+		t.Fatalf("Failed to apply patch: %s", err)
+	}
+	mutated := &singaporev1alpha1.RegisteredCluster{}
+	if err := json.Unmarshal(b, mutated); err != nil {
+		t.Fatalf("Failed to unmarshal RegisteredCluster: %s", err)
+	}
+	return mutated
+}
+
+func TestValidateRegisteredClusterApproval(t *testing.T) {
+	a := newAdmissionHook(t, singaporev1alpha1.RegistrationPolicy{
+		ApprovalRequired: true,
+		Approvers:        []string{"admin"},
+		ApproverGroups:   []string{"cluster-approvers"},
+	})
+	oldRegCluster := newRegisteredCluster("cluster1", workspaceName, singaporev1alpha1.RegisteredClusterSpec{})
+	oldRegCluster.Annotations = map[string]string{singaporev1alpha1.ApprovalRequiredAnnotation: "true"}
+	regCluster := oldRegCluster.DeepCopy()
+	regCluster.Annotations[singaporev1alpha1.ApprovedAnnotation] = "true"
+	regCluster.Annotations[singaporev1alpha1.ApprovedByAnnotation] = "janedoe"
+
+	// Only the approvers of the registration policy can approve
+	request := newAdmissionRequest(t, admissionv1.Update, regCluster, oldRegCluster)
+	request.UserInfo.Username = "janedoe"
+	checkDenied(t, a.ValidateRegisteredCluster(request), "metadata.annotations["+singaporev1alpha1.ApprovedAnnotation+"]")
+
+	request.UserInfo.Username = "admin"
+	if response := a.ValidateRegisteredCluster(request); !response.Allowed {
+		t.Fatalf("Request denied but expected to be allowed: %v", response.Result)
+	}
+	request.UserInfo.Username = "johndoe"
+	request.UserInfo.Groups = []string{"system:authenticated", "cluster-approvers"}
+	if response := a.ValidateRegisteredCluster(request); !response.Allowed {
+		t.Fatalf("Request denied but expected to be allowed: %v", response.Result)
+	}
+
+	// The approval requirement and the approver can't be changed
+	oldRegCluster = regCluster.DeepCopy()
+	delete(regCluster.Annotations, singaporev1alpha1.ApprovalRequiredAnnotation)
+	regCluster.Annotations[singaporev1alpha1.ApprovedByAnnotation] = "admin"
+	request = newAdmissionRequest(t, admissionv1.Update, regCluster, oldRegCluster)
+	request.UserInfo.Username = "janedoe"
+	checkDenied(t, a.ValidateRegisteredCluster(request),
+		"metadata.annotations["+singaporev1alpha1.ApprovalRequiredAnnotation+"]",
+		"metadata.annotations["+singaporev1alpha1.ApprovedByAnnotation+"]")
+}
+
+func TestMutateRegisteredClusterApproval(t *testing.T) {
+	a := newAdmissionHook(t, singaporev1alpha1.RegistrationPolicy{
+		ApprovalRequired: true,
+		Approvers:        []string{"admin"},
+	})
+	regCluster := newRegisteredCluster("cluster1", workspaceName, singaporev1alpha1.RegisteredClusterSpec{})
+	regCluster.Annotations = map[string]string{singaporev1alpha1.ApprovedByAnnotation: "admin"}
+	request := newAdmissionRequest(t, admissionv1.Create, regCluster, nil)
+	request.UserInfo.Username = "janedoe"
+	created := applyPatch(t, regCluster, a.MutateRegisteredCluster(request).Patch)
+	if created.Annotations[singaporev1alpha1.ApprovalRequiredAnnotation] != "true" {
+		t.Fatalf(`Approval not required as expected: %v`, created.Annotations)
+	}
+	if _, ok := created.Annotations[singaporev1alpha1.ApprovedByAnnotation]; ok {
+		t.Fatalf(`Approver not expected before the approval: %v`, created.Annotations)
+	}
+
+	// The adopted clusters already joined the hub
+	adopted := newRegisteredCluster("cluster2", workspaceName, singaporev1alpha1.RegisteredClusterSpec{ManagedClusterName: "local-cluster"})
+	adopted = applyPatch(t, adopted, a.MutateRegisteredCluster(newAdmissionRequest(t, admissionv1.Create, adopted, nil)).Patch)
+	if _, ok := adopted.Annotations[singaporev1alpha1.ApprovalRequiredAnnotation]; ok {
+		t.Fatalf(`Approval not expected for an adopted cluster: %v`, adopted.Annotations)
+	}
+
+	approved := created.DeepCopy()
+	approved.Annotations[singaporev1alpha1.ApprovedAnnotation] = "true"
+	request = newAdmissionRequest(t, admissionv1.Update, approved, created)
+	request.UserInfo.Username = "admin"
+	mutated := applyPatch(t, approved, a.MutateRegisteredCluster(request).Patch)
+	if mutated.Annotations[singaporev1alpha1.ApprovedByAnnotation] != "admin" {
+		t.Fatalf(`Approver not as expected. Expected %s, actual %s`, "admin", mutated.Annotations[singaporev1alpha1.ApprovedByAnnotation])
+	}
+}